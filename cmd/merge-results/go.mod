@@ -0,0 +1,3 @@
+module merge-results
+
+go 1.20
@@ -0,0 +1,176 @@
+// Command merge-results combines the JSON result documents of several
+// benchmark runs into one document, for publishing as a single artifact:
+//
+//	merge-results [-output FILE] result.json...
+//
+// Each input is stored whole under its benchmark name: the document's
+// "name" (from the config's top-level name) when set, otherwise the file
+// name without its extension. Inputs whose schema_version differs from the
+// others are merged anyway, with a warning on stderr.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Merged is the combined document.
+type Merged struct {
+	MergedAt   float64              `json:"merged_at"`
+	Benchmarks map[string]Benchmark `json:"benchmarks"`
+	Summary    Summary              `json:"summary"`
+}
+
+// Benchmark is one input document. Tests and Failures are read from its
+// summary (see countTests); Results is the document unchanged.
+type Benchmark struct {
+	Source        string          `json:"source"`
+	SchemaVersion string          `json:"schema_version,omitempty"`
+	Tests         int             `json:"tests"`
+	Failures      int             `json:"failures"`
+	Results       json.RawMessage `json:"results"`
+}
+
+type Summary struct {
+	TotalBenchmarks int `json:"total_benchmarks"`
+	TotalTests      int `json:"total_tests"`
+	TotalFailures   int `json:"total_failures"`
+	// SchemaVersions lists the distinct schema versions of the inputs;
+	// more than one means their fields may not line up.
+	SchemaVersions []string `json:"schema_versions"`
+}
+
+// resultDocument holds the fields of a benchmark result that merging reads.
+type resultDocument struct {
+	SchemaVersion string                 `json:"schema_version"`
+	Name          string                 `json:"name"`
+	Summary       map[string]interface{} `json:"summary"`
+}
+
+// countTests totals a benchmark summary's successful_* and failed_* counts.
+// The benchmarks count different things (tests, requests, resolutions,
+// targets), but each reports a successful/failed pair of them.
+func countTests(summary map[string]interface{}) (tests, failures int) {
+	for key, value := range summary {
+		count, ok := value.(float64)
+		if !ok {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(key, "failed_"):
+			if _, paired := summary["successful_"+strings.TrimPrefix(key, "failed_")]; paired {
+				failures += int(count)
+				tests += int(count)
+			}
+		case strings.HasPrefix(key, "successful_"):
+			if _, paired := summary["failed_"+strings.TrimPrefix(key, "successful_")]; paired {
+				tests += int(count)
+			}
+		}
+	}
+	return tests, failures
+}
+
+// benchmarkName returns the name a result document is merged under.
+func benchmarkName(path string, document resultDocument) string {
+	if document.Name != "" {
+		return document.Name
+	}
+	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+}
+
+func merge(paths []string) (*Merged, error) {
+	merged := &Merged{
+		MergedAt:   float64(time.Now().UnixNano()) / 1e9,
+		Benchmarks: make(map[string]Benchmark, len(paths)),
+	}
+	versions := make(map[string][]string) // schema version -> benchmark names
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read results file '%s': %v", path, err)
+		}
+		var document resultDocument
+		if err := json.Unmarshal(data, &document); err != nil {
+			return nil, fmt.Errorf("'%s' is not a JSON results document: %v", path, err)
+		}
+
+		name := benchmarkName(path, document)
+		if previous, ok := merged.Benchmarks[name]; ok {
+			return nil, fmt.Errorf("'%s' and '%s' are both named '%s'", previous.Source, path, name)
+		}
+		if document.SchemaVersion == "" {
+			fmt.Fprintf(os.Stderr, "Warning: '%s' has no schema_version\n", path)
+		}
+
+		tests, failures := countTests(document.Summary)
+		merged.Benchmarks[name] = Benchmark{
+			Source:        path,
+			SchemaVersion: document.SchemaVersion,
+			Tests:         tests,
+			Failures:      failures,
+			Results:       json.RawMessage(data),
+		}
+		merged.Summary.TotalTests += tests
+		merged.Summary.TotalFailures += failures
+		versions[document.SchemaVersion] = append(versions[document.SchemaVersion], name)
+	}
+
+	merged.Summary.TotalBenchmarks = len(merged.Benchmarks)
+	for version := range versions {
+		if version != "" {
+			merged.Summary.SchemaVersions = append(merged.Summary.SchemaVersions, version)
+		}
+	}
+	sort.Strings(merged.Summary.SchemaVersions)
+	if len(merged.Summary.SchemaVersions) > 1 {
+		fmt.Fprintf(os.Stderr, "Warning: inputs use different schema versions:\n")
+		for _, version := range merged.Summary.SchemaVersions {
+			names := versions[version]
+			sort.Strings(names)
+			fmt.Fprintf(os.Stderr, "  %s: %s\n", version, strings.Join(names, ", "))
+		}
+	}
+	return merged, nil
+}
+
+func main() {
+	var output string
+	flag.StringVar(&output, "output", "", "write the merged document to this file instead of stdout")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: merge-results [flags] result.json...\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	merged, err := merge(flag.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	data, err := json.MarshalIndent(merged, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	data = append(data, '\n')
+	if output == "" {
+		os.Stdout.Write(data)
+	} else if err := os.WriteFile(output, data, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to write merged results to '%s': %v\n", output, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// captureStderr returns what fn writes to os.Stderr.
+func captureStderr(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderr := os.Stderr
+	os.Stderr = w
+	defer func() { os.Stderr = stderr }()
+
+	done := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- string(data)
+	}()
+	fn()
+	w.Close()
+	return <-done
+}
+
+func TestMergeFixtures(t *testing.T) {
+	gzipPath := filepath.Join("testdata", "gzip_compression.json")
+	httpPath := filepath.Join("testdata", "http_request.json")
+
+	var merged *Merged
+	var err error
+	warnings := captureStderr(t, func() {
+		merged, err = merge([]string{gzipPath, httpPath})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// gzip: 5 successful + 1 failed test. http: 18 + 2 requests; the
+	// unpaired failed_redirects and total_requests are not counted.
+	want := Summary{TotalBenchmarks: 2, TotalTests: 26, TotalFailures: 3, SchemaVersions: []string{"1.1", "1.2"}}
+	if !reflect.DeepEqual(merged.Summary, want) {
+		t.Errorf("Summary = %+v, want %+v", merged.Summary, want)
+	}
+
+	gzip, ok := merged.Benchmarks["gzip-nightly"]
+	if !ok {
+		t.Fatalf("gzip results not merged under their config name; got %v", merged.Benchmarks)
+	}
+	if gzip.Tests != 6 || gzip.Failures != 1 || gzip.SchemaVersion != "1.2" || gzip.Source != gzipPath {
+		t.Errorf("gzip entry = %+v", gzip)
+	}
+	http, ok := merged.Benchmarks["http_request"]
+	if !ok {
+		t.Fatalf("unnamed results not merged under their file name; got %v", merged.Benchmarks)
+	}
+	if http.Tests != 20 || http.Failures != 2 || http.SchemaVersion != "1.1" {
+		t.Errorf("http entry = %+v", http)
+	}
+
+	original, err := os.ReadFile(httpPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(http.Results) != string(original) {
+		t.Errorf("http results were not kept unchanged")
+	}
+	if _, err := json.Marshal(merged); err != nil {
+		t.Errorf("merged document does not marshal: %v", err)
+	}
+
+	if !strings.Contains(warnings, "different schema versions") ||
+		!strings.Contains(warnings, "1.1: http_request") || !strings.Contains(warnings, "1.2: gzip-nightly") {
+		t.Errorf("schema mismatch warning = %q", warnings)
+	}
+}
+
+func TestMergeSameSchemaDoesNotWarn(t *testing.T) {
+	gzipPath := filepath.Join("testdata", "gzip_compression.json")
+	copyPath := filepath.Join(t.TempDir(), "other.json")
+	data, err := os.ReadFile(gzipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = []byte(strings.Replace(string(data), `"name": "gzip-nightly",`, "", 1))
+	if err := os.WriteFile(copyPath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var merged *Merged
+	warnings := captureStderr(t, func() {
+		merged, err = merge([]string{gzipPath, copyPath})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if warnings != "" {
+		t.Errorf("unexpected warnings: %q", warnings)
+	}
+	if merged.Summary.TotalTests != 12 || merged.Summary.TotalFailures != 2 || len(merged.Summary.SchemaVersions) != 1 {
+		t.Errorf("Summary = %+v", merged.Summary)
+	}
+}
+
+func TestMergeDuplicateName(t *testing.T) {
+	gzipPath := filepath.Join("testdata", "gzip_compression.json")
+	_, err := merge([]string{gzipPath, gzipPath})
+	if err == nil || !strings.Contains(err.Error(), "are both named 'gzip-nightly'") {
+		t.Errorf("duplicate name error = %v", err)
+	}
+}
+
+func TestCountTests(t *testing.T) {
+	tests := []struct {
+		name            string
+		summary         map[string]interface{}
+		tests, failures int
+	}{
+		{"empty", nil, 0, 0},
+		{"tests", map[string]interface{}{"successful_tests": 4.0, "failed_tests": 1.0}, 5, 1},
+		{"two pairs", map[string]interface{}{"successful_targets": 2.0, "failed_targets": 0.0, "successful_packets": 9.0, "failed_packets": 1.0}, 12, 1},
+		{"unpaired ignored", map[string]interface{}{"successful_tests": 3.0, "failed_lookups": 2.0}, 0, 0},
+		{"non-numeric ignored", map[string]interface{}{"successful_tests": "3", "failed_tests": 1.0}, 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tests, failures := countTests(tt.summary)
+			if tests != tt.tests || failures != tt.failures {
+				t.Errorf("countTests(%v) = %d, %d, want %d, %d", tt.summary, tests, failures, tt.tests, tt.failures)
+			}
+		})
+	}
+}
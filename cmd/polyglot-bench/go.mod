@@ -0,0 +1,3 @@
+module polyglot-bench
+
+go 1.20
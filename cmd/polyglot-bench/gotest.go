@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// runGoTests runs go test on the shared packages and on each selected Go
+// benchmark that has _test.go files next to its source, and reports whether
+// all of them passed. A benchmark's tests are copied into the same throwaway
+// module the benchmark is built in, so they see the package under the same
+// import paths as the build; the source keeps the _benchmark.go rename, which
+// lets ping_test.go sit beside test files of its own.
+func runGoTests(ctx context.Context, manifest *Manifest, benchmarks []Benchmark, workDir string) bool {
+	passed := true
+	report := func(name string, err error) {
+		if err != nil {
+			passed = false
+			fmt.Fprintf(os.Stderr, "FAIL %s: %v\n", name, err)
+			return
+		}
+		fmt.Fprintf(os.Stderr, "ok   %s\n", name)
+	}
+
+	report("shared packages", testSharedPackages(ctx, manifest.SharedPackages, workDir))
+
+	for _, b := range benchmarks {
+		if b.Source == "" {
+			continue
+		}
+		testFiles, err := benchmarkTestFiles(b.Source)
+		if err != nil {
+			report(b.Name, err)
+			continue
+		}
+		if len(testFiles) == 0 {
+			continue
+		}
+		report(b.Name, testGoBenchmark(ctx, b, testFiles, manifest.SharedPackages, workDir))
+	}
+	return passed
+}
+
+// benchmarkTestFiles lists the _test.go files in source's directory other
+// than source itself.
+func benchmarkTestFiles(source string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(filepath.Dir(source), "*_test.go"))
+	if err != nil {
+		return nil, err
+	}
+	var testFiles []string
+	for _, match := range matches {
+		if filepath.Base(match) != filepath.Base(source) {
+			testFiles = append(testFiles, match)
+		}
+	}
+	return testFiles, nil
+}
+
+func testGoBenchmark(ctx context.Context, b Benchmark, testFiles []string, sharedPackages, workDir string) error {
+	moduleDir, err := goBenchmarkModule(b, sharedPackages, workDir)
+	if err != nil {
+		return err
+	}
+	for _, testFile := range testFiles {
+		if err := copyFile(testFile, filepath.Join(moduleDir, filepath.Base(testFile))); err != nil {
+			return err
+		}
+	}
+	if _, err := goCommand(ctx, moduleDir, "mod", "tidy"); err != nil {
+		return err
+	}
+	_, err = goCommand(ctx, moduleDir, "test", "-count=1", ".")
+	return err
+}
+
+// testSharedPackages copies the shared packages that have tests into a
+// benchmark_test module and tests them together.
+func testSharedPackages(ctx context.Context, sharedPackages, workDir string) error {
+	entries, err := os.ReadDir(sharedPackages)
+	if err != nil {
+		return err
+	}
+	moduleDir, err := os.MkdirTemp(workDir, "internal-")
+	if err != nil {
+		return err
+	}
+
+	var tested []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		pkgDir := filepath.Join(sharedPackages, entry.Name())
+		if tests, _ := filepath.Glob(filepath.Join(pkgDir, "*_test.go")); len(tests) == 0 {
+			continue
+		}
+		if err := copyDir(pkgDir, filepath.Join(moduleDir, "internal", entry.Name())); err != nil {
+			return err
+		}
+		tested = append(tested, entry.Name())
+	}
+	if len(tested) == 0 {
+		return nil
+	}
+	sort.Strings(tested)
+
+	goMod := "module benchmark_test\n\ngo 1.19\n"
+	if err := os.WriteFile(filepath.Join(moduleDir, "go.mod"), []byte(goMod), 0644); err != nil {
+		return err
+	}
+	if _, err := goCommand(ctx, moduleDir, "mod", "tidy"); err != nil {
+		return err
+	}
+	if _, err := goCommand(ctx, moduleDir, "test", "-count=1", "./..."); err != nil {
+		return fmt.Errorf("%s: %v", strings.Join(tested, ", "), err)
+	}
+	return nil
+}
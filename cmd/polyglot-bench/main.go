@@ -0,0 +1,187 @@
+// Command polyglot-bench runs the Go benchmarks (or any program following the
+// same config-in, JSON-out convention) listed in a manifest and writes one
+// combined JSON report:
+//
+//	polyglot-bench [-only a,b] [-tags t,u] [-concurrency N] [-output FILE] [manifest]
+//	polyglot-bench -test [-only a,b] [-tags t,u] [manifest]
+//
+// The manifest defaults to bench.manifest.json in the current directory.
+// -tags keeps the benchmarks whose config lists any of the tags in
+// parameters.tags; combined with -only, a benchmark must match both.
+// Benchmarks run one at a time unless -concurrency is raised; concurrent runs
+// compete for CPU and memory, so their timings are not comparable with
+// sequential ones.
+//
+// -test runs go test instead: on the shared packages, and on each selected
+// Go benchmark together with the _test.go files beside its source.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Report is the combined output of a suite run.
+type Report struct {
+	StartTime          float64           `json:"start_time"`
+	Manifest           string            `json:"manifest"`
+	Concurrency        int               `json:"concurrency"`
+	Benchmarks         []BenchmarkResult `json:"benchmarks"`
+	Summary            Summary           `json:"summary"`
+	EndTime            float64           `json:"end_time"`
+	TotalExecutionTime float64           `json:"total_execution_time"`
+}
+
+type Summary struct {
+	TotalBenchmarks      int      `json:"total_benchmarks"`
+	SuccessfulBenchmarks int      `json:"successful_benchmarks"`
+	FailedBenchmarks     int      `json:"failed_benchmarks"`
+	Failed               []string `json:"failed,omitempty"`
+}
+
+func runSuite(ctx context.Context, manifest *Manifest, benchmarks []Benchmark, concurrency int, workDir string) []BenchmarkResult {
+	results := make([]BenchmarkResult, len(benchmarks))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	done := 0
+
+	for i, b := range benchmarks {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, b Benchmark) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			fmt.Fprintf(os.Stderr, "Running %s...\n", b.Name)
+			results[i] = runBenchmark(ctx, b, manifest.SharedPackages, workDir)
+
+			mu.Lock()
+			done++
+			status := "ok"
+			if !results[i].Success {
+				status = "FAILED: " + *results[i].Error
+			}
+			fmt.Fprintf(os.Stderr, "[%d/%d] %s %s (%.1fs)\n", done, len(benchmarks), b.Name, status,
+				(results[i].BuildTimeMs+results[i].RunTimeMs)/1000)
+			mu.Unlock()
+		}(i, b)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// splitList splits a comma-separated flag value, dropping empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func main() {
+	var only, tags, output string
+	var concurrency int
+	var test bool
+	flag.StringVar(&only, "only", "", "comma-separated benchmark names to run (default: all)")
+	flag.StringVar(&tags, "tags", "", "comma-separated config tags; run only benchmarks tagged with any of them")
+	flag.IntVar(&concurrency, "concurrency", 1, "number of benchmarks run at the same time")
+	flag.StringVar(&output, "output", "", "write the report to this file instead of stdout")
+	flag.BoolVar(&test, "test", false, "run the go tests of the shared packages and the selected benchmarks instead of the benchmarks")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: polyglot-bench [flags] [manifest]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	manifestPath := "bench.manifest.json"
+	if flag.NArg() > 0 {
+		manifestPath = flag.Arg(0)
+	}
+	if concurrency < 1 {
+		fmt.Fprintf(os.Stderr, "Error: -concurrency must be at least 1\n")
+		os.Exit(2)
+	}
+
+	manifest, err := loadManifest(manifestPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	benchmarks, err := selectBenchmarks(manifest.Benchmarks, splitList(only))
+	if err == nil {
+		benchmarks, err = selectByTags(benchmarks, splitList(tags))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+
+	workDir, err := os.MkdirTemp("", "polyglot-bench-")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer os.RemoveAll(workDir)
+
+	if test {
+		if !runGoTests(context.Background(), manifest, benchmarks, workDir) {
+			os.RemoveAll(workDir)
+			os.Exit(1)
+		}
+		return
+	}
+
+	startTime := float64(time.Now().UnixNano()) / 1e9
+	results := runSuite(context.Background(), manifest, benchmarks, concurrency, workDir)
+	endTime := float64(time.Now().UnixNano()) / 1e9
+
+	summary := Summary{TotalBenchmarks: len(results)}
+	for _, result := range results {
+		if result.Success {
+			summary.SuccessfulBenchmarks++
+		} else {
+			summary.FailedBenchmarks++
+			summary.Failed = append(summary.Failed, result.Name)
+		}
+	}
+
+	report := Report{
+		StartTime:          startTime,
+		Manifest:           manifestPath,
+		Concurrency:        concurrency,
+		Benchmarks:         results,
+		Summary:            summary,
+		EndTime:            endTime,
+		TotalExecutionTime: endTime - startTime,
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	data = append(data, '\n')
+	if output == "" {
+		os.Stdout.Write(data)
+	} else if err := os.WriteFile(output, data, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to write report to '%s': %v\n", output, err)
+		os.Exit(1)
+	}
+
+	if summary.FailedBenchmarks > 0 {
+		os.RemoveAll(workDir)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestHelperProcess stands in for a command benchmark: when run by
+// TestRunSuite it prints the config path it was given as its results.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("POLYGLOT_BENCH_HELPER") != "1" {
+		return
+	}
+	data, _ := json.Marshal(map[string]string{"config": os.Args[len(os.Args)-1]})
+	os.Stdout.Write(data)
+	os.Exit(0)
+}
+
+func absPath(t *testing.T, path string) string {
+	t.Helper()
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return abs
+}
+
+func writeManifest(t *testing.T, manifest string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	if err := os.WriteFile(path, []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadManifestResolvesPaths(t *testing.T) {
+	manifest, err := loadManifest(filepath.Join("testdata", "suite.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := absPath(t, filepath.Join("..", "..", "tests", "internal")); manifest.SharedPackages != want {
+		t.Errorf("SharedPackages = %q, want %q", manifest.SharedPackages, want)
+	}
+	sample, helper := manifest.Benchmarks[0], manifest.Benchmarks[1]
+	if want := absPath(t, filepath.Join("testdata", "sample", "sample.go")); sample.Source != want {
+		t.Errorf("sample Source = %q, want %q", sample.Source, want)
+	}
+	if want := absPath(t, filepath.Join("testdata", "sample.json")); sample.Config != want {
+		t.Errorf("sample Config = %q, want %q", sample.Config, want)
+	}
+	if want := absPath(t, filepath.Join("testdata", "helper")); helper.Command[0] != want {
+		t.Errorf("helper Command[0] = %q, want %q", helper.Command[0], want)
+	}
+}
+
+func TestLoadManifestDefaults(t *testing.T) {
+	path := writeManifest(t, `{"benchmarks": [{"name": "py", "command": ["python", "bench.py"], "config": "/abs/config.json"}]}`)
+	manifest, err := loadManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := filepath.Dir(path)
+	if want := filepath.Join(dir, "tests", "internal"); manifest.SharedPackages != want {
+		t.Errorf("SharedPackages = %q, want %q", manifest.SharedPackages, want)
+	}
+	b := manifest.Benchmarks[0]
+	if b.Command[0] != "python" || b.Command[1] != "bench.py" {
+		t.Errorf("Command = %q, want bare names left for PATH lookup", b.Command)
+	}
+	if b.Config != "/abs/config.json" {
+		t.Errorf("Config = %q, want the absolute path unchanged", b.Config)
+	}
+}
+
+func TestLoadManifestErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		want     string
+	}{
+		{"no benchmarks", `{"benchmarks": []}`, "lists no benchmarks"},
+		{"no name", `{"benchmarks": [{"source": "a.go", "config": "a.json"}]}`, "has no name"},
+		{"duplicate", `{"benchmarks": [{"name": "a", "source": "a.go", "config": "a.json"}, {"name": "a", "source": "b.go", "config": "b.json"}]}`, "listed more than once"},
+		{"source and command", `{"benchmarks": [{"name": "a", "source": "a.go", "command": ["a"], "config": "a.json"}]}`, "exactly one of source and command"},
+		{"neither", `{"benchmarks": [{"name": "a", "config": "a.json"}]}`, "exactly one of source and command"},
+		{"no config", `{"benchmarks": [{"name": "a", "source": "a.go"}]}`, "has no config"},
+		{"invalid JSON", `{"benchmarks": [`, "invalid JSON"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadManifest(writeManifest(t, tt.manifest))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("loadManifest error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectBenchmarks(t *testing.T) {
+	benchmarks := []Benchmark{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+
+	selected, err := selectBenchmarks(benchmarks, []string{"c", "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(selected) != 2 || selected[0].Name != "a" || selected[1].Name != "c" {
+		t.Errorf("selected %v, want a and c in manifest order", selected)
+	}
+
+	all, err := selectBenchmarks(benchmarks, nil)
+	if err != nil || len(all) != 3 {
+		t.Errorf("no names selected %v, %v; want all three", all, err)
+	}
+
+	_, err = selectBenchmarks(benchmarks, []string{"a", "missing", "other"})
+	if err == nil || err.Error() != "unknown benchmark(s): missing, other" {
+		t.Errorf("unknown names error = %v", err)
+	}
+}
+
+func TestRunSuite(t *testing.T) {
+	manifest, err := loadManifest(filepath.Join("testdata", "suite.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest.Benchmarks[1].Command = []string{os.Args[0], "-test.run=TestHelperProcess", "--"}
+	t.Setenv("POLYGLOT_BENCH_HELPER", "1")
+
+	results := runSuite(context.Background(), manifest, manifest.Benchmarks, 2, t.TempDir())
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+	for _, result := range results {
+		if !result.Success {
+			t.Fatalf("%s failed: %s", result.Name, *result.Error)
+		}
+	}
+
+	sample := results[0]
+	if sample.Name != "sample" || sample.BuildTimeMs <= 0 {
+		t.Errorf("sample result = %+v, want a built source benchmark", sample)
+	}
+	var sampleOutput struct {
+		Name       string   `json:"name"`
+		Tags       []string `json:"tags"`
+		Iterations int      `json:"iterations"`
+	}
+	if err := json.Unmarshal(sample.Results, &sampleOutput); err != nil {
+		t.Fatal(err)
+	}
+	if sampleOutput.Name != "sample" || sampleOutput.Iterations != 3 || strings.Join(sampleOutput.Tags, ",") != "smoke,nightly" {
+		t.Errorf("sample results = %+v", sampleOutput)
+	}
+
+	helper := results[1]
+	var helperOutput struct {
+		Config string `json:"config"`
+	}
+	if err := json.Unmarshal(helper.Results, &helperOutput); err != nil {
+		t.Fatal(err)
+	}
+	if helperOutput.Config != manifest.Benchmarks[1].Config {
+		t.Errorf("helper got config %q, want %q", helperOutput.Config, manifest.Benchmarks[1].Config)
+	}
+	if helper.BuildTimeMs != 0 {
+		t.Errorf("helper BuildTimeMs = %v, want 0 for a command", helper.BuildTimeMs)
+	}
+}
+
+func TestSelectByTags(t *testing.T) {
+	manifest, err := loadManifest(filepath.Join("testdata", "suite.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// sample.json is tagged smoke and nightly, helper.json only nightly.
+	tests := []struct {
+		tags []string
+		want []string
+	}{
+		{nil, []string{"sample", "helper"}},
+		{[]string{"nightly"}, []string{"sample", "helper"}},
+		{[]string{"smoke"}, []string{"sample"}},
+		{[]string{"weekly", "smoke"}, []string{"sample"}},
+	}
+	for _, tt := range tests {
+		selected, err := selectByTags(manifest.Benchmarks, tt.tags)
+		if err != nil {
+			t.Errorf("selectByTags(%q): %v", tt.tags, err)
+			continue
+		}
+		var names []string
+		for _, b := range selected {
+			names = append(names, b.Name)
+		}
+		if strings.Join(names, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("selectByTags(%q) = %q, want %q", tt.tags, names, tt.want)
+		}
+	}
+
+	_, err = selectByTags(manifest.Benchmarks, []string{"weekly", "release"})
+	if err == nil || err.Error() != "no benchmark config is tagged weekly or release" {
+		t.Errorf("unmatched tags error = %v", err)
+	}
+
+	missing := []Benchmark{{Name: "gone", Config: filepath.Join(t.TempDir(), "missing.json")}}
+	if _, err := selectByTags(missing, []string{"smoke"}); err == nil || !strings.HasPrefix(err.Error(), "benchmark 'gone': cannot read config") {
+		t.Errorf("missing config error = %v", err)
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Manifest lists the benchmarks a suite run executes. Relative paths are
+// resolved against the directory holding the manifest file.
+type Manifest struct {
+	// SharedPackages is the directory copied into every Go build as
+	// benchmark_test/internal (tests/internal by default).
+	SharedPackages string      `json:"shared_packages,omitempty"`
+	Benchmarks     []Benchmark `json:"benchmarks"`
+}
+
+// Benchmark is one manifest entry. Source names a Go benchmark that is built
+// the same way the Python GoRunner builds it; Command runs any other
+// executable. Either way the config path is passed as the last argument and
+// the program's stdout is collected as its results.
+type Benchmark struct {
+	Name           string   `json:"name"`
+	Source         string   `json:"source,omitempty"`
+	Command        []string `json:"command,omitempty"`
+	Config         string   `json:"config"`
+	TimeoutSeconds int      `json:"timeout_seconds,omitempty"`
+}
+
+const defaultSharedPackages = "tests/internal"
+
+func loadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read manifest '%s': %v", path, err)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid JSON in manifest '%s': %v", path, err)
+	}
+	if len(manifest.Benchmarks) == 0 {
+		return nil, fmt.Errorf("manifest '%s' lists no benchmarks", path)
+	}
+
+	base, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	if manifest.SharedPackages == "" {
+		manifest.SharedPackages = defaultSharedPackages
+	}
+	manifest.SharedPackages = resolvePath(base, manifest.SharedPackages)
+
+	seen := make(map[string]bool)
+	for i := range manifest.Benchmarks {
+		b := &manifest.Benchmarks[i]
+		if b.Name == "" {
+			return nil, fmt.Errorf("benchmark #%d in manifest has no name", i+1)
+		}
+		if seen[b.Name] {
+			return nil, fmt.Errorf("benchmark '%s' is listed more than once", b.Name)
+		}
+		seen[b.Name] = true
+
+		if (b.Source == "") == (len(b.Command) == 0) {
+			return nil, fmt.Errorf("benchmark '%s' must set exactly one of source and command", b.Name)
+		}
+		if b.Config == "" {
+			return nil, fmt.Errorf("benchmark '%s' has no config", b.Name)
+		}
+
+		b.Config = resolvePath(base, b.Config)
+		if b.Source != "" {
+			b.Source = resolvePath(base, b.Source)
+		}
+		// Only path-like commands are made relative to the manifest; bare
+		// names such as "python" are looked up in PATH.
+		if len(b.Command) > 0 && strings.ContainsRune(b.Command[0], filepath.Separator) {
+			b.Command[0] = resolvePath(base, b.Command[0])
+		}
+	}
+
+	return &manifest, nil
+}
+
+func resolvePath(base, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(base, filepath.FromSlash(path))
+}
+
+// selectBenchmarks returns the benchmarks named in names, in manifest order,
+// or all of them when names is empty.
+func selectBenchmarks(benchmarks []Benchmark, names []string) ([]Benchmark, error) {
+	if len(names) == 0 {
+		return benchmarks, nil
+	}
+
+	wanted := make(map[string]bool)
+	for _, name := range names {
+		wanted[name] = true
+	}
+
+	selected := make([]Benchmark, 0, len(names))
+	for _, b := range benchmarks {
+		if wanted[b.Name] {
+			selected = append(selected, b)
+			delete(wanted, b.Name)
+		}
+	}
+	if len(wanted) > 0 {
+		unknown := make([]string, 0, len(wanted))
+		for _, name := range names {
+			if wanted[name] {
+				unknown = append(unknown, name)
+			}
+		}
+		return nil, fmt.Errorf("unknown benchmark(s): %s", strings.Join(unknown, ", "))
+	}
+	return selected, nil
+}
+
+// configTags returns the parameters.tags list of the config file at path,
+// or nil when it has none.
+func configTags(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read config '%s': %v", path, err)
+	}
+	var config struct {
+		Parameters struct {
+			Tags []string `json:"tags"`
+		} `json:"parameters"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid JSON in config '%s': %v", path, err)
+	}
+	return config.Parameters.Tags, nil
+}
+
+// selectByTags returns the benchmarks whose config carries at least one of
+// tags, in manifest order, or all of them when tags is empty.
+func selectByTags(benchmarks []Benchmark, tags []string) ([]Benchmark, error) {
+	if len(tags) == 0 {
+		return benchmarks, nil
+	}
+
+	wanted := make(map[string]bool)
+	for _, tag := range tags {
+		wanted[tag] = true
+	}
+
+	var selected []Benchmark
+	for _, b := range benchmarks {
+		configured, err := configTags(b.Config)
+		if err != nil {
+			return nil, fmt.Errorf("benchmark '%s': %v", b.Name, err)
+		}
+		for _, tag := range configured {
+			if wanted[tag] {
+				selected = append(selected, b)
+				break
+			}
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no benchmark config is tagged %s", strings.Join(tags, " or "))
+	}
+	return selected, nil
+}
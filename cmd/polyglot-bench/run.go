@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BenchmarkResult is one benchmark's entry in the combined report. Results
+// holds the benchmark's own JSON output unchanged; benchmarks that print a
+// plain-text report (the algorithm tests) have it stored in Output instead.
+type BenchmarkResult struct {
+	Name        string          `json:"name"`
+	Config      string          `json:"config"`
+	Success     bool            `json:"success"`
+	BuildTimeMs float64         `json:"build_time_ms"`
+	RunTimeMs   float64         `json:"run_time_ms"`
+	Error       *string         `json:"error,omitempty"`
+	Results     json.RawMessage `json:"results,omitempty"`
+	Output      string          `json:"output,omitempty"`
+}
+
+// stderrTailLines is how much of a failed benchmark's stderr is kept in its
+// error message.
+const stderrTailLines = 10
+
+// runBenchmark builds b if needed and runs it once with its config.
+func runBenchmark(ctx context.Context, b Benchmark, sharedPackages, workDir string) BenchmarkResult {
+	result := BenchmarkResult{Name: b.Name, Config: b.Config}
+	fail := func(err error) BenchmarkResult {
+		msg := err.Error()
+		result.Error = &msg
+		return result
+	}
+
+	command := b.Command
+	if b.Source != "" {
+		start := time.Now()
+		binary, err := buildGoBenchmark(ctx, b, sharedPackages, workDir)
+		result.BuildTimeMs = float64(time.Since(start).Nanoseconds()) / 1e6
+		if err != nil {
+			return fail(fmt.Errorf("build failed: %v", err))
+		}
+		command = []string{binary}
+	}
+
+	if b.TimeoutSeconds > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(b.TimeoutSeconds)*time.Second)
+		defer cancel()
+	}
+
+	args := append(append([]string{}, command[1:]...), b.Config)
+	cmd := exec.CommandContext(ctx, command[0], args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// Don't wait on children that outlive a killed benchmark while holding
+	// its output pipes open.
+	cmd.WaitDelay = time.Second
+
+	start := time.Now()
+	err := cmd.Run()
+	result.RunTimeMs = float64(time.Since(start).Nanoseconds()) / 1e6
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fail(fmt.Errorf("timed out after %ds", b.TimeoutSeconds))
+	}
+	if err != nil {
+		return fail(fmt.Errorf("%v%s", err, tail(&stderr, stderrTailLines)))
+	}
+
+	output := bytes.TrimSpace(stdout.Bytes())
+	if json.Valid(output) {
+		result.Results = json.RawMessage(output)
+	} else {
+		result.Output = string(output)
+	}
+	result.Success = true
+	return result
+}
+
+// buildGoBenchmark compiles b.Source into workDir. Like the Python GoRunner
+// it builds the file in a throwaway benchmark_test module with the shared
+// packages copied in; requirements come from the go.mod next to the source,
+// if there is one.
+func buildGoBenchmark(ctx context.Context, b Benchmark, sharedPackages, workDir string) (string, error) {
+	moduleDir, err := goBenchmarkModule(b, sharedPackages, workDir)
+	if err != nil {
+		return "", err
+	}
+
+	binary := filepath.Join(workDir, b.Name)
+	if runtime.GOOS == "windows" {
+		binary += ".exe"
+	}
+	for _, args := range [][]string{{"mod", "tidy"}, {"build", "-o", binary, "."}} {
+		if _, err := goCommand(ctx, moduleDir, args...); err != nil {
+			return "", err
+		}
+	}
+	return binary, nil
+}
+
+// goBenchmarkModule creates the throwaway module b.Source is built in and
+// returns its directory.
+func goBenchmarkModule(b Benchmark, sharedPackages, workDir string) (string, error) {
+	moduleDir, err := os.MkdirTemp(workDir, b.Name+"-")
+	if err != nil {
+		return "", err
+	}
+
+	name := strings.Replace(filepath.Base(b.Source), "_test.go", "_benchmark.go", 1)
+	if err := copyFile(b.Source, filepath.Join(moduleDir, name)); err != nil {
+		return "", err
+	}
+	if err := copySharedPackages(b.Source, sharedPackages, moduleDir); err != nil {
+		return "", fmt.Errorf("cannot copy shared packages: %v", err)
+	}
+
+	goMod := "module benchmark_test\n\ngo 1.19\n"
+	if requires, err := goModRequires(filepath.Join(filepath.Dir(b.Source), "go.mod")); err != nil {
+		return "", err
+	} else if requires != "" {
+		goMod += "\n" + requires
+	}
+	if err := os.WriteFile(filepath.Join(moduleDir, "go.mod"), []byte(goMod), 0644); err != nil {
+		return "", err
+	}
+	return moduleDir, nil
+}
+
+// goCommand runs the go tool in dir and returns its combined output. A
+// failure is returned with the output attached.
+func goCommand(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return output, fmt.Errorf("go %s: %v\n%s", strings.Join(args, " "), err, bytes.TrimSpace(output))
+	}
+	return output, nil
+}
+
+// sharedImportPrefix is the import path of the shared packages directory
+// inside the benchmark_test module.
+const sharedImportPrefix = "benchmark_test/internal/"
+
+// copySharedPackages copies the shared packages source imports into
+// moduleDir/internal. The shared packages do not import each other, and
+// leaving the others out keeps their requirements (golang.org/x/sys for
+// fadvise) away from go mod tidy in builds that do not use them.
+func copySharedPackages(source, sharedPackages, moduleDir string) error {
+	file, err := parser.ParseFile(token.NewFileSet(), source, nil, parser.ImportsOnly)
+	if err != nil {
+		return err
+	}
+	for _, spec := range file.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			return err
+		}
+		if !strings.HasPrefix(path, sharedImportPrefix) {
+			continue
+		}
+		pkg := strings.TrimPrefix(path, sharedImportPrefix)
+		if err := copyDir(filepath.Join(sharedPackages, pkg), filepath.Join(moduleDir, "internal", pkg)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// goModRequires returns the require directives of the go.mod at path, or ""
+// when the file does not exist.
+func goModRequires(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var requires strings.Builder
+	inBlock := false
+	for _, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case inBlock:
+			requires.WriteString(line + "\n")
+			if trimmed == ")" {
+				inBlock = false
+			}
+		case strings.HasPrefix(trimmed, "require ("):
+			requires.WriteString(line + "\n")
+			inBlock = true
+		case strings.HasPrefix(trimmed, "require "):
+			requires.WriteString(line + "\n")
+		}
+	}
+	return requires.String(), nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func copyDir(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		return copyFile(path, target)
+	})
+}
+
+// tail returns the last n lines of buf, indented on new lines, for appending
+// to an error message.
+func tail(buf *bytes.Buffer, n int) string {
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(buf.Bytes()))
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return "\n  " + strings.Join(lines, "\n  ")
+}
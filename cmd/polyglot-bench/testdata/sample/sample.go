@@ -0,0 +1,45 @@
+// Command sample is a minimal benchmark for the polyglot-bench tests: it
+// loads its config through the shared cli package and echoes it back.
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"benchmark_test/internal/cli"
+)
+
+type Config struct {
+	Name       string `json:"name"`
+	Parameters struct {
+		Iterations int      `json:"iterations"`
+		Tags       []string `json:"tags"`
+	} `json:"parameters"`
+}
+
+type Results struct {
+	Name       string   `json:"name,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+	Iterations int      `json:"iterations"`
+}
+
+func main() {
+	opts, err := cli.Parse(filepath.Base(os.Args[0]), os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+
+	var config Config
+	if err := opts.LoadConfig(&config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	results := Results{Name: config.Name, Tags: config.Parameters.Tags, Iterations: config.Parameters.Iterations}
+	if err := opts.WriteResults(results); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -495,6 +495,10 @@ debug = false
 class GoRunner(BaseLanguageRunner):
     """Runner for Go tests with module management."""
     
+    # Packages under tests/internal are importable by every Go benchmark
+    SHARED_IMPORT_PREFIX = 'benchmark_test/internal/'
+    SHARED_PACKAGES_DIR = os.path.join('tests', 'internal')
+    
     def __init__(self, language: str, config: LanguageConfig):
         super().__init__(language, config)
         self.temp_dirs = []  # Track temporary directories for cleanup
@@ -555,6 +559,15 @@ class GoRunner(BaseLanguageRunner):
         go_file_path = os.path.join(temp_dir, new_name)
         shutil.copy2(source_file, go_file_path)
         
+        # Copy the shared packages the source imports (e.g.
+        # benchmark_test/internal/stats) into the module. The others are left
+        # out so their requirements do not reach go mod tidy.
+        for imp in imports:
+            if imp.startswith(self.SHARED_IMPORT_PREFIX):
+                package = imp[len(self.SHARED_IMPORT_PREFIX):]
+                shutil.copytree(os.path.join(self.SHARED_PACKAGES_DIR, package),
+                                os.path.join(temp_dir, 'internal', package))
+        
         # Create go.mod - always create one for proper module support
         go_mod_content = """module benchmark_test
 
@@ -1,11 +1,32 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"math/rand"
+	"os"
+	"sort"
+	"strings"
 	"time"
 )
 
+// Config is the optional config file; without one the integer search runs
+// with the default sizes.
+type Config struct {
+	Parameters struct {
+		ArraySize   int    `json:"array_size"`
+		NumSearches int    `json:"num_searches"`
+		KeyType     string `json:"key_type"`
+		// Implementation "stdlib" also times the standard library search
+		// on the same targets next to the hand-rolled one.
+		Implementation string `json:"implementation"`
+	} `json:"parameters"`
+}
+
+// stringKeyLength is the length of every generated key. Absent targets are
+// one character longer, so they can never match a key.
+const stringKeyLength = 16
+
 func binarySearch(arr []int, target int) int {
 	left, right := 0, len(arr)-1
 	
@@ -24,25 +45,98 @@ func binarySearch(arr []int, target int) int {
 	return -1
 }
 
-func main() {
-	size := 1000000
+// binarySearchStrings mirrors binarySearch for sorted strings and also
+// returns the number of string comparisons made, since those dominate the
+// cost of each step.
+func binarySearchStrings(arr []string, target string) (int, int) {
+	left, right := 0, len(arr)-1
+	comparisons := 0
+	
+	for left <= right {
+		mid := left + (right-left)/2
+		
+		comparisons++
+		switch c := strings.Compare(arr[mid], target); {
+		case c == 0:
+			return mid, comparisons
+		case c < 0:
+			left = mid + 1
+		default:
+			right = mid - 1
+		}
+	}
+	
+	return -1, comparisons
+}
+
+// searchIntsStdlib is binarySearch implemented with sort.SearchInts.
+func searchIntsStdlib(arr []int, target int) int {
+	i := sort.SearchInts(arr, target)
+	if i < len(arr) && arr[i] == target {
+		return i
+	}
+	return -1
+}
+
+// searchStringsStdlib is binarySearchStrings implemented with
+// sort.SearchStrings.
+func searchStringsStdlib(arr []string, target string) int {
+	i := sort.SearchStrings(arr, target)
+	if i < len(arr) && arr[i] == target {
+		return i
+	}
+	return -1
+}
+
+// reportStdlib prints the stdlib timing next to the custom one and checks
+// that both implementations returned the same index for every target.
+func reportStdlib(custom, stdlib time.Duration, customResults, stdlibResults []int) {
+	mismatches := 0
+	for i := range customResults {
+		if customResults[i] != stdlibResults[i] {
+			mismatches++
+		}
+	}
+	
+	fmt.Printf("Stdlib execution time: %.6f seconds\n", stdlib.Seconds())
+	if stdlib > 0 {
+		fmt.Printf("Custom/stdlib time ratio: %.2f\n", custom.Seconds()/stdlib.Seconds())
+	}
+	if mismatches == 0 {
+		fmt.Println("Stdlib indices: identical for all targets")
+	} else {
+		fmt.Printf("Stdlib indices: %d targets differ\n", mismatches)
+	}
+}
+
+func randomString(length int) string {
+	const letters = "abcdefghijklmnopqrstuvwxyz"
+	b := make([]byte, length)
+	for i := range b {
+		b[i] = letters[rand.Intn(len(letters))]
+	}
+	return string(b)
+}
+
+func runIntSearches(size, numSearches int, implementation string) {
 	arr := make([]int, size)
 	for i := range arr {
 		arr[i] = i
 	}
 	
-	numSearches := 1000
 	targets := make([]int, numSearches)
 	for i := range targets {
 		targets[i] = rand.Intn(size)
 	}
 	
 	fmt.Printf("Performing %d binary searches on array of size %d...\n", numSearches, size)
+	results := make([]int, numSearches)
 	start := time.Now()
 	
 	foundCount := 0
-	for _, target := range targets {
-		if result := binarySearch(arr, target); result != -1 {
+	for i, target := range targets {
+		results[i] = binarySearch(arr, target)
+		if results[i] != -1 {
 			foundCount++
 		}
 	}
@@ -51,4 +145,122 @@ func main() {
 	
 	fmt.Printf("Result: Found %d/%d targets\n", foundCount, numSearches)
 	fmt.Printf("Execution time: %.6f seconds\n", duration.Seconds())
-}
\ No newline at end of file
+	
+	if implementation == "stdlib" {
+		stdlibResults := make([]int, numSearches)
+		stdlibStart := time.Now()
+		for i, target := range targets {
+			stdlibResults[i] = searchIntsStdlib(arr, target)
+		}
+		reportStdlib(duration, time.Since(stdlibStart), results, stdlibResults)
+	}
+}
+
+func runStringSearches(size, numSearches int, implementation string) {
+	arr := make([]string, size)
+	for i := range arr {
+		arr[i] = randomString(stringKeyLength)
+	}
+	sort.Strings(arr)
+	
+	// Half the targets are present keys, half are absent
+	targets := make([]string, numSearches)
+	for i := range targets {
+		if i%2 == 0 {
+			targets[i] = arr[rand.Intn(size)]
+		} else {
+			targets[i] = randomString(stringKeyLength + 1)
+		}
+	}
+	
+	fmt.Printf("Performing %d binary searches on string array of size %d...\n", numSearches, size)
+	results := make([]int, numSearches)
+	totalComparisons := 0
+	start := time.Now()
+	
+	foundCount := 0
+	for i, target := range targets {
+		result, comparisons := binarySearchStrings(arr, target)
+		results[i] = result
+		totalComparisons += comparisons
+		if result != -1 {
+			foundCount++
+		}
+	}
+	
+	duration := time.Since(start)
+	
+	// Verify against the standard library outside the timed loop
+	mismatches := 0
+	for i, target := range targets {
+		expected := sort.SearchStrings(arr, target)
+		found := expected < len(arr) && arr[expected] == target
+		if found != (results[i] != -1) || (found && arr[results[i]] != target) {
+			mismatches++
+		}
+	}
+	
+	fmt.Printf("Result: Found %d/%d targets\n", foundCount, numSearches)
+	fmt.Printf("Comparisons: %d total, %.2f per search\n", totalComparisons, float64(totalComparisons)/float64(numSearches))
+	if mismatches == 0 {
+		fmt.Println("Verification: matches sort.SearchStrings")
+	} else {
+		fmt.Printf("Verification: %d results differ from sort.SearchStrings\n", mismatches)
+	}
+	fmt.Printf("Execution time: %.6f seconds\n", duration.Seconds())
+	
+	if implementation == "stdlib" {
+		stdlibResults := make([]int, numSearches)
+		stdlibStart := time.Now()
+		for i, target := range targets {
+			stdlibResults[i] = searchStringsStdlib(arr, target)
+		}
+		reportStdlib(duration, time.Since(stdlibStart), results, stdlibResults)
+	}
+}
+
+func main() {
+	size := 1000000
+	numSearches := 1000
+	keyType := "int"
+	implementation := "custom"
+	
+	if len(os.Args) > 1 {
+		var config Config
+		data, err := os.ReadFile(os.Args[1])
+		if err == nil {
+			err = json.Unmarshal(data, &config)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading config: %v\n", err)
+			os.Exit(1)
+		}
+		if config.Parameters.ArraySize > 0 {
+			size = config.Parameters.ArraySize
+		}
+		if config.Parameters.NumSearches > 0 {
+			numSearches = config.Parameters.NumSearches
+		}
+		if config.Parameters.KeyType != "" {
+			keyType = config.Parameters.KeyType
+		}
+		if config.Parameters.Implementation != "" {
+			implementation = config.Parameters.Implementation
+		}
+	}
+	
+	if implementation != "custom" && implementation != "stdlib" {
+		fmt.Fprintf(os.Stderr, "Unknown implementation '%s' (expected custom or stdlib)\n", implementation)
+		os.Exit(1)
+	}
+	
+	switch keyType {
+	case "int":
+		runIntSearches(size, numSearches, implementation)
+	case "string":
+		runStringSearches(size, numSearches, implementation)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown key_type '%s' (expected int or string)\n", keyType)
+		os.Exit(1)
+	}
+}
@@ -4,10 +4,24 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"math/big"
+	"os"
 	"time"
 )
 
+// Config is the optional config file. Mode "sequence" generates F(1)
+// through F(n) instead of computing F(n) recursively. A positive modulus
+// makes every mode compute modulo it.
+type Config struct {
+	Parameters struct {
+		N       int    `json:"n"`
+		Mode    string `json:"mode"`
+		Modulus int    `json:"modulus"`
+	} `json:"parameters"`
+}
+
 // fibonacci calculates the nth Fibonacci number recursively.
 func fibonacci(n int) int {
 	if n <= 1 {
@@ -16,17 +30,147 @@ func fibonacci(n int) int {
 	return fibonacci(n-1) + fibonacci(n-2)
 }
 
+// fibonacciMod calculates F(n) mod m iteratively. Both terms stay below m,
+// so nothing overflows for any m below 2^62.
+func fibonacciMod(n, m int) int {
+	a, b := 0, 1%m
+	for i := 0; i < n; i++ {
+		a, b = b, (a+b)%m
+	}
+	return a
+}
+
+// fibonacciSequenceMod returns F(1) through F(n), each mod m.
+func fibonacciSequenceMod(n, m int) []int {
+	var sequence []int
+	a, b := 0, 1%m
+	for i := 1; i <= n; i++ {
+		sequence = append(sequence, b)
+		a, b = b, (a+b)%m
+	}
+	return sequence
+}
+
+// fibonacciSequence returns F(1) through F(n), appending each element as
+// it is computed. Elements are big.Int since F(n) overflows int64 past
+// n = 92.
+func fibonacciSequence(n int) []*big.Int {
+	var sequence []*big.Int
+	a, b := big.NewInt(0), big.NewInt(1)
+	for i := 1; i <= n; i++ {
+		sequence = append(sequence, new(big.Int).Set(b))
+		a.Add(a, b)
+		a, b = b, a
+	}
+	return sequence
+}
+
+// fibonacciFastDoubling calculates F(n) from the identities
+// F(2k) = F(k)(2F(k+1) - F(k)) and F(2k+1) = F(k)^2 + F(k+1)^2, as an
+// independent check of the generated sequence.
+func fibonacciFastDoubling(n int) *big.Int {
+	a, b := big.NewInt(0), big.NewInt(1) // F(k), F(k+1)
+	for bit := 62; bit >= 0; bit-- {
+		// c = F(2k), d = F(2k+1)
+		c := new(big.Int).Lsh(b, 1)
+		c.Sub(c, a)
+		c.Mul(c, a)
+		d := new(big.Int).Mul(a, a)
+		d.Add(d, new(big.Int).Mul(b, b))
+		if n>>uint(bit)&1 == 1 {
+			a, b = d, c.Add(c, d)
+		} else {
+			a, b = c, d
+		}
+	}
+	return a
+}
+
 func main() {
 	// Test parameter
 	n := 35 // Adjusted for reasonable execution time
+	mode := "single"
+	modulus := 0
 
-	fmt.Printf("Calculating fibonacci(%d)...\n", n)
-	startTime := time.Now()
+	if len(os.Args) > 1 {
+		var config Config
+		data, err := os.ReadFile(os.Args[1])
+		if err == nil {
+			err = json.Unmarshal(data, &config)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading config: %v\n", err)
+			os.Exit(1)
+		}
+		if config.Parameters.N > 0 {
+			n = config.Parameters.N
+		}
+		if config.Parameters.Mode != "" {
+			mode = config.Parameters.Mode
+		}
+		if config.Parameters.Modulus > 0 {
+			modulus = config.Parameters.Modulus
+		}
+	}
+
+	if modulus > 0 {
+		fmt.Printf("Modulus: %d\n", modulus)
+	}
+
+	switch mode {
+	case "single":
+		fmt.Printf("Calculating fibonacci(%d)...\n", n)
+		startTime := time.Now()
+
+		var result int
+		if modulus > 0 {
+			result = fibonacciMod(n, modulus)
+		} else {
+			result = fibonacci(n)
+		}
+
+		executionTime := time.Since(startTime)
+
+		fmt.Printf("Result: %d\n", result)
+		fmt.Printf("Execution time: %.6f seconds\n", executionTime.Seconds())
+	case "sequence":
+		if modulus > 0 {
+			fmt.Printf("Generating fibonacci(1) through fibonacci(%d) mod %d...\n", n, modulus)
+			startTime := time.Now()
 
-	result := fibonacci(n)
+			sequence := fibonacciSequenceMod(n, modulus)
 
-	executionTime := time.Since(startTime)
+			executionTime := time.Since(startTime)
 
-	fmt.Printf("Result: %d\n", result)
-	fmt.Printf("Execution time: %.6f seconds\n", executionTime.Seconds())
-}
\ No newline at end of file
+			last := sequence[len(sequence)-1]
+			fmt.Printf("Result: %d numbers, last is %d\n", len(sequence), last)
+			expected := new(big.Int).Mod(fibonacciFastDoubling(n), big.NewInt(int64(modulus)))
+			if expected.Cmp(big.NewInt(int64(last))) == 0 {
+				fmt.Printf("Verification: last element equals fibonacci(%d) mod %d\n", n, modulus)
+			} else {
+				fmt.Printf("Verification: last element differs from fibonacci(%d) mod %d\n", n, modulus)
+			}
+			fmt.Printf("Execution time: %.6f seconds\n", executionTime.Seconds())
+			break
+		}
+
+		fmt.Printf("Generating fibonacci(1) through fibonacci(%d)...\n", n)
+		startTime := time.Now()
+
+		sequence := fibonacciSequence(n)
+
+		executionTime := time.Since(startTime)
+
+		last := sequence[len(sequence)-1]
+		fmt.Printf("Result: %d numbers, last has %d digits\n", len(sequence), len(last.String()))
+		if last.Cmp(fibonacciFastDoubling(n)) == 0 {
+			fmt.Printf("Verification: last element equals fibonacci(%d)\n", n)
+		} else {
+			fmt.Printf("Verification: last element differs from fibonacci(%d)\n", n)
+		}
+		fmt.Printf("Execution time: %.6f seconds\n", executionTime.Seconds())
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown mode '%s' (expected single or sequence)\n", mode)
+		os.Exit(1)
+	}
+}
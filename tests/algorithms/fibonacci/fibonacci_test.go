@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestPisanoPeriods checks that fibonacciMod repeats with known Pisano
+// periods π(m), the period of F(n) mod m: F(π) ≡ 0 and F(π+1) ≡ 1 (mod m).
+func TestPisanoPeriods(t *testing.T) {
+	pisanoPeriods := map[int]int{
+		2:    3,
+		3:    8,
+		5:    20,
+		7:    16,
+		10:   60,
+		100:  300,
+		1000: 1500,
+	}
+	for m, period := range pisanoPeriods {
+		t.Run(fmt.Sprintf("m=%d", m), func(t *testing.T) {
+			if got := fibonacciMod(period, m); got != 0 {
+				t.Errorf("F(%d) mod %d = %d, want 0", period, m, got)
+			}
+			if got := fibonacciMod(period+1, m); got != 1 {
+				t.Errorf("F(%d) mod %d = %d, want 1", period+1, m, got)
+			}
+		})
+	}
+}
+
+func TestFibonacciSequenceMod(t *testing.T) {
+	sequence := fibonacciSequence(50)
+	for _, m := range []int{1, 7, 1000} {
+		for i, value := range fibonacciSequenceMod(50, m) {
+			if want := int(sequence[i].Int64() % int64(m)); value != want {
+				t.Errorf("F(%d) mod %d = %d, want %d", i+1, m, value, want)
+			}
+		}
+	}
+}
@@ -1,16 +1,35 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"math"
+	"math/bits"
+	"math/rand"
+	"os"
+	"runtime"
 	"time"
 )
 
-func sieveOfEratosthenes(n int) []int {
-	if n < 2 {
-		return []int{}
-	}
-	
+// Config is the optional config file. Output "count" reports only the
+// number of primes and the largest one, without building the prime list.
+// Mode "miller_rabin" tests num_tests random 64-bit numbers instead of
+// sieving.
+type Config struct {
+	Parameters struct {
+		N        int    `json:"n"`
+		Output   string `json:"output"`
+		Mode     string `json:"mode"`
+		NumTests int    `json:"num_tests"`
+	} `json:"parameters"`
+}
+
+// millerRabinWitnesses are the first 12 primes, which make Miller-Rabin
+// deterministic for every n < 2^64.
+var millerRabinWitnesses = []uint64{2, 3, 5, 7, 11, 13, 17, 19, 23, 29, 31, 37}
+
+// sieve returns isPrime[i] for every i in [0, n].
+func sieve(n int) []bool {
 	isPrime := make([]bool, n+1)
 	for i := range isPrime {
 		isPrime[i] = true
@@ -27,6 +46,16 @@ func sieveOfEratosthenes(n int) []int {
 		}
 	}
 	
+	return isPrime
+}
+
+func sieveOfEratosthenes(n int) []int {
+	if n < 2 {
+		return []int{}
+	}
+	
+	isPrime := sieve(n)
+	
 	var primes []int
 	for i := 2; i <= n; i++ {
 		if isPrime[i] {
@@ -37,19 +66,202 @@ func sieveOfEratosthenes(n int) []int {
 	return primes
 }
 
-func main() {
-	n := 100000
+// countPrimes returns π(n) and the largest prime up to n (0 if none)
+// without materializing the list of primes.
+func countPrimes(n int) (int, int) {
+	if n < 2 {
+		return 0, 0
+	}
+	
+	isPrime := sieve(n)
+	
+	count, largest := 0, 0
+	for i := 2; i <= n; i++ {
+		if isPrime[i] {
+			count++
+			largest = i
+		}
+	}
+	
+	return count, largest
+}
+
+func mulMod(a, b, m uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	return bits.Rem64(hi, lo, m)
+}
+
+func powMod(base, exp, m uint64) uint64 {
+	result := uint64(1)
+	base %= m
+	for exp > 0 {
+		if exp&1 == 1 {
+			result = mulMod(result, base, m)
+		}
+		base = mulMod(base, base, m)
+		exp >>= 1
+	}
+	return result
+}
+
+// isPrimeMillerRabin tests n for primality with the deterministic 64-bit
+// witness set.
+func isPrimeMillerRabin(n uint64) bool {
+	if n < 2 {
+		return false
+	}
+	for _, p := range millerRabinWitnesses {
+		if n%p == 0 {
+			return n == p
+		}
+	}
+	
+	// n-1 = d * 2^s with d odd
+	d := n - 1
+	s := bits.TrailingZeros64(d)
+	d >>= uint(s)
+	
+	for _, a := range millerRabinWitnesses {
+		x := powMod(a, d, n)
+		if x == 1 || x == n-1 {
+			continue
+		}
+		composite := true
+		for r := 1; r < s; r++ {
+			x = mulMod(x, x, n)
+			if x == n-1 {
+				composite = false
+				break
+			}
+		}
+		if composite {
+			return false
+		}
+	}
 	
-	fmt.Printf("Finding all primes up to %d...\n", n)
+	return true
+}
+
+func runMillerRabin(numTests int) {
+	numbers := make([]uint64, numTests)
+	for i := range numbers {
+		numbers[i] = rand.Uint64() | 1 // even numbers are trivially composite
+	}
+	
+	fmt.Printf("Testing %d random 64-bit numbers with Miller-Rabin...\n", numTests)
 	start := time.Now()
 	
-	primes := sieveOfEratosthenes(n)
+	primeCount := 0
+	for _, n := range numbers {
+		if isPrimeMillerRabin(n) {
+			primeCount++
+		}
+	}
 	
 	duration := time.Since(start)
 	
-	fmt.Printf("Result: Found %d primes\n", len(primes))
-	if len(primes) > 0 {
-		fmt.Printf("Largest prime: %d\n", primes[len(primes)-1])
+	fmt.Printf("Result: %d primes, %d composites\n", primeCount, numTests-primeCount)
+	if duration > 0 {
+		fmt.Printf("Tests per second: %.0f\n", float64(numTests)/duration.Seconds())
 	}
 	fmt.Printf("Execution time: %.6f seconds\n", duration.Seconds())
-}
\ No newline at end of file
+}
+
+// bytesAllocatedDuring returns the heap bytes allocated while fn runs.
+func bytesAllocatedDuring(fn func()) uint64 {
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	fn()
+	runtime.ReadMemStats(&after)
+	return after.TotalAlloc - before.TotalAlloc
+}
+
+func main() {
+	n := 100000
+	output := "list"
+	mode := "sieve"
+	numTests := 100000
+	
+	if len(os.Args) > 1 {
+		var config Config
+		data, err := os.ReadFile(os.Args[1])
+		if err == nil {
+			err = json.Unmarshal(data, &config)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading config: %v\n", err)
+			os.Exit(1)
+		}
+		if config.Parameters.N > 0 {
+			n = config.Parameters.N
+		}
+		if config.Parameters.Output != "" {
+			output = config.Parameters.Output
+		}
+		if config.Parameters.Mode != "" {
+			mode = config.Parameters.Mode
+		}
+		if config.Parameters.NumTests > 0 {
+			numTests = config.Parameters.NumTests
+		}
+	}
+	
+	switch mode {
+	case "sieve":
+	case "miller_rabin":
+		runMillerRabin(numTests)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown mode '%s' (expected sieve or miller_rabin)\n", mode)
+		os.Exit(1)
+	}
+	
+	switch output {
+	case "list":
+		fmt.Printf("Finding all primes up to %d...\n", n)
+		start := time.Now()
+		
+		primes := sieveOfEratosthenes(n)
+		
+		duration := time.Since(start)
+		
+		fmt.Printf("Result: Found %d primes\n", len(primes))
+		if len(primes) > 0 {
+			fmt.Printf("Largest prime: %d\n", primes[len(primes)-1])
+		}
+		fmt.Printf("Execution time: %.6f seconds\n", duration.Seconds())
+	case "count":
+		fmt.Printf("Counting primes up to %d...\n", n)
+		
+		var primeCount, largestPrime int
+		var duration time.Duration
+		countBytes := bytesAllocatedDuring(func() {
+			start := time.Now()
+			primeCount, largestPrime = countPrimes(n)
+			duration = time.Since(start)
+		})
+		
+		// The list mode runs untimed afterwards, only to compare memory and
+		// check the count.
+		var primes []int
+		listBytes := bytesAllocatedDuring(func() {
+			primes = sieveOfEratosthenes(n)
+		})
+		
+		fmt.Printf("Result: Found %d primes\n", primeCount)
+		if primeCount > 0 {
+			fmt.Printf("Largest prime: %d\n", largestPrime)
+		}
+		fmt.Printf("Heap allocated: %d bytes (list mode: %d bytes, %d saved)\n",
+			countBytes, listBytes, int64(listBytes)-int64(countBytes))
+		if len(primes) == primeCount {
+			fmt.Println("Verification: count matches list mode")
+		} else {
+			fmt.Printf("Verification: list mode found %d primes\n", len(primes))
+		}
+		fmt.Printf("Execution time: %.6f seconds\n", duration.Seconds())
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown output '%s' (expected list or count)\n", output)
+		os.Exit(1)
+	}
+}
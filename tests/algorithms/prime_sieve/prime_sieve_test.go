@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestMillerRabinKnownValues checks isPrimeMillerRabin on values near 2^62
+// and 2^64. 3825123056546413051 is a strong pseudoprime to every base up
+// to 23.
+func TestMillerRabinKnownValues(t *testing.T) {
+	tests := []struct {
+		n     uint64
+		prime bool
+	}{
+		{1<<62 - 57, true},
+		{1<<62 - 87, true},
+		{1<<61 - 1, true},
+		{1<<64 - 59, true},
+		{1<<62 - 1, false},
+		{1<<62 + 1, false},
+		{1<<62 - 55, false},
+		{3825123056546413051, false},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.n), func(t *testing.T) {
+			if got := isPrimeMillerRabin(tt.n); got != tt.prime {
+				t.Errorf("isPrimeMillerRabin(%d) = %v, want %v", tt.n, got, tt.prime)
+			}
+		})
+	}
+}
+
+func TestMillerRabinMatchesSieve(t *testing.T) {
+	const n = 10000
+	isPrime := sieve(n)
+	for i := 0; i <= n; i++ {
+		if got := isPrimeMillerRabin(uint64(i)); got != isPrime[i] {
+			t.Errorf("isPrimeMillerRabin(%d) = %v, sieve says %v", i, got, isPrime[i])
+		}
+	}
+}
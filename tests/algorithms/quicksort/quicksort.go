@@ -1,11 +1,44 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"math/rand"
+	"os"
+	"runtime"
+	"sort"
+	"sync"
+	"text/tabwriter"
 	"time"
 )
 
+// Config is the optional config file. Algorithms selects the sorts to
+// compare (quicksort and merge_sort by default) and distributions the
+// inputs they sort (see fillInput); mode "parallel" runs quicksort with
+// parallelQuicksort and reports its speedup.
+type Config struct {
+	Parameters struct {
+		ArraySize     int      `json:"array_size"`
+		Algorithms    []string `json:"algorithms"`
+		Distributions []string `json:"distributions"`
+		Iterations    int      `json:"iterations"`
+		Mode          string   `json:"mode"`
+		Workers       int      `json:"workers"`
+		Threshold     int      `json:"threshold"`
+		// InsertionCutoff makes quicksort finish subarrays shorter than
+		// it with insertion sort; pure quicksort is then timed too.
+		InsertionCutoff int `json:"insertion_cutoff"`
+		// Variant "dual_pivot" makes quicksort partition around two
+		// pivots (dualPivotQuicksort); the default is "single_pivot".
+		Variant string `json:"variant"`
+	} `json:"parameters"`
+}
+
+// defaultParallelThreshold is the subarray length below which the parallel
+// sort stops spawning goroutines; shorter ranges sort faster than a
+// goroutine starts.
+const defaultParallelThreshold = 4096
+
 func quicksort(arr []int) {
 	if len(arr) <= 1 {
 		return
@@ -16,6 +49,74 @@ func quicksort(arr []int) {
 	quicksort(arr[pivotIndex+1:])
 }
 
+// hybridQuicksort is quicksort that hands subarrays shorter than cutoff to
+// insertion sort, whose low overhead wins on a handful of elements. A cutoff
+// of 0 or 1 never switches and sorts exactly like quicksort.
+func hybridQuicksort(arr []int, cutoff int) {
+	if len(arr) < cutoff {
+		insertionSort(arr)
+		return
+	}
+	if len(arr) <= 1 {
+		return
+	}
+	
+	pivotIndex := partition(arr)
+	hybridQuicksort(arr[:pivotIndex], cutoff)
+	hybridQuicksort(arr[pivotIndex+1:], cutoff)
+}
+
+func insertionSort(arr []int) {
+	for i := 1; i < len(arr); i++ {
+		value := arr[i]
+		j := i - 1
+		for j >= 0 && arr[j] > value {
+			arr[j+1] = arr[j]
+			j--
+		}
+		arr[j+1] = value
+	}
+}
+
+// parallelQuicksort sorts arr with at most workers goroutines, finishing
+// ranges no longer than threshold with hybridQuicksort. Partitions are
+// disjoint subslices, so the goroutines never touch the same elements.
+func parallelQuicksort(arr []int, workers, threshold, cutoff int) {
+	// One token per goroutine beyond the caller's.
+	sem := make(chan struct{}, workers-1)
+	parallelQuicksortRange(arr, threshold, cutoff, sem)
+}
+
+// parallelQuicksortRange partitions arr and, while it is longer than
+// threshold and a token is free, sorts the left part in a new goroutine
+// while the current one sorts the right part. Without a free token both
+// parts are sorted inline.
+func parallelQuicksortRange(arr []int, threshold, cutoff int, sem chan struct{}) {
+	if len(arr) <= threshold {
+		hybridQuicksort(arr, cutoff)
+		return
+	}
+	
+	pivotIndex := partition(arr)
+	left, right := arr[:pivotIndex], arr[pivotIndex+1:]
+	
+	select {
+	case sem <- struct{}{}:
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			parallelQuicksortRange(left, threshold, cutoff, sem)
+			<-sem
+		}()
+		parallelQuicksortRange(right, threshold, cutoff, sem)
+		wg.Wait()
+	default:
+		parallelQuicksortRange(left, threshold, cutoff, sem)
+		parallelQuicksortRange(right, threshold, cutoff, sem)
+	}
+}
+
 func partition(arr []int) int {
 	pivot := arr[len(arr)-1]
 	i := 0
@@ -31,38 +132,478 @@ func partition(arr []int) int {
 	return i
 }
 
+// sortCounts tallies the element comparisons and swaps of one sort. The
+// methods accept a nil receiver, so the timed sorts count nothing.
+type sortCounts struct {
+	comparisons int
+	swaps       int
+}
+
+func (c *sortCounts) compare() {
+	if c != nil {
+		c.comparisons++
+	}
+}
+
+func (c *sortCounts) swap() {
+	if c != nil {
+		c.swaps++
+	}
+}
+
+// dualPivotQuicksort sorts arr by partitioning around two pivots into three
+// regions: below the smaller pivot, between the pivots, and above the
+// larger one. Like quicksort it takes its pivots from the ends, so sorted
+// input is still its worst case.
+func dualPivotQuicksort(arr []int) {
+	dualPivotSort(arr, nil)
+}
+
+// dualPivotSort is dualPivotQuicksort, tallying into counts when it is not
+// nil.
+func dualPivotSort(arr []int, counts *sortCounts) {
+	if len(arr) <= 1 {
+		return
+	}
+	
+	hi := len(arr) - 1
+	counts.compare()
+	if arr[0] > arr[hi] {
+		arr[0], arr[hi] = arr[hi], arr[0]
+		counts.swap()
+	}
+	low, high := arr[0], arr[hi]
+	
+	// arr[1:lt] < low, arr[lt:k] is between the pivots, arr[gt+1:hi] > high
+	lt, gt := 1, hi-1
+	for k := lt; k <= gt; k++ {
+		counts.compare()
+		if arr[k] < low {
+			arr[k], arr[lt] = arr[lt], arr[k]
+			counts.swap()
+			lt++
+			continue
+		}
+		counts.compare()
+		if arr[k] <= high {
+			continue
+		}
+		for k < gt {
+			counts.compare()
+			if arr[gt] <= high {
+				break
+			}
+			gt--
+		}
+		arr[k], arr[gt] = arr[gt], arr[k]
+		counts.swap()
+		gt--
+		counts.compare()
+		if arr[k] < low {
+			arr[k], arr[lt] = arr[lt], arr[k]
+			counts.swap()
+			lt++
+		}
+	}
+	
+	// Move the pivots between the regions
+	lt--
+	gt++
+	arr[0], arr[lt] = arr[lt], arr[0]
+	arr[hi], arr[gt] = arr[gt], arr[hi]
+	counts.swap()
+	counts.swap()
+	
+	dualPivotSort(arr[:lt], counts)
+	// With equal pivots the middle region holds only copies of them
+	if low != high {
+		dualPivotSort(arr[lt+1:gt], counts)
+	}
+	dualPivotSort(arr[gt+1:], counts)
+}
+
+// countedQuicksort is quicksort, tallying into counts.
+func countedQuicksort(arr []int, counts *sortCounts) {
+	if len(arr) <= 1 {
+		return
+	}
+	
+	pivot := arr[len(arr)-1]
+	i := 0
+	for j := 0; j < len(arr)-1; j++ {
+		counts.compare()
+		if arr[j] <= pivot {
+			arr[i], arr[j] = arr[j], arr[i]
+			counts.swap()
+			i++
+		}
+	}
+	arr[i], arr[len(arr)-1] = arr[len(arr)-1], arr[i]
+	counts.swap()
+	
+	countedQuicksort(arr[:i], counts)
+	countedQuicksort(arr[i+1:], counts)
+}
+
+// mergeSort sorts arr by allocating a new slice at every merge, as the
+// allocating counterpart to the in-place quicksort.
+func mergeSort(arr []int) []int {
+	if len(arr) <= 1 {
+		return arr
+	}
+	
+	mid := len(arr) / 2
+	left := mergeSort(arr[:mid])
+	right := mergeSort(arr[mid:])
+	
+	merged := make([]int, 0, len(arr))
+	i, j := 0, 0
+	for i < len(left) && j < len(right) {
+		if left[i] <= right[j] {
+			merged = append(merged, left[i])
+			i++
+		} else {
+			merged = append(merged, right[j])
+			j++
+		}
+	}
+	merged = append(merged, left[i:]...)
+	return append(merged, right[j:]...)
+}
+
+// heapSort sorts arr in place by building a max-heap and repeatedly moving
+// its root to the end. It is O(n log n) in every case, but sifting jumps
+// across the array, so it makes poor use of the cache.
+func heapSort(arr []int) {
+	for i := len(arr)/2 - 1; i >= 0; i-- {
+		siftDown(arr, i, len(arr))
+	}
+	for end := len(arr) - 1; end > 0; end-- {
+		arr[0], arr[end] = arr[end], arr[0]
+		siftDown(arr, 0, end)
+	}
+}
+
+// siftDown moves arr[root] down until the subtree it roots, within
+// arr[:end], is a max-heap again.
+func siftDown(arr []int, root, end int) {
+	for {
+		child := 2*root + 1
+		if child >= end {
+			return
+		}
+		if child+1 < end && arr[child+1] > arr[child] {
+			child++
+		}
+		if arr[root] >= arr[child] {
+			return
+		}
+		arr[root], arr[child] = arr[child], arr[root]
+		root = child
+	}
+}
+
+// allocationsDuring returns the number of heap allocations made while fn
+// runs, from the runtime's cumulative malloc counter.
+func allocationsDuring(fn func()) uint64 {
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	fn()
+	runtime.ReadMemStats(&after)
+	return after.Mallocs - before.Mallocs
+}
+
+// sortAlgorithms are the algorithms parameters.algorithms can select. Each
+// returns the sorted slice, which is arr itself for the in-place sorts.
+var sortAlgorithms = map[string]func(arr []int) []int{
+	"quicksort":  func(arr []int) []int { quicksort(arr); return arr },
+	"merge_sort": mergeSort,
+	"heap_sort":  func(arr []int) []int { heapSort(arr); return arr },
+}
+
+// fewUniqueValues is how many distinct values the few_unique distribution
+// draws from.
+const fewUniqueValues = 10
+
+// fillInput fills arr with a permutation of 0..len(arr)-1 for "random",
+// ascending or descending values for "sorted" and "reversed", and random
+// values from a small set for "few_unique". Sorted, reversed and few_unique
+// input drive this quicksort's last-element pivot to its quadratic worst
+// case.
+func fillInput(arr []int, distribution string) {
+	for i := range arr {
+		switch distribution {
+		case "reversed":
+			arr[i] = len(arr) - 1 - i
+		case "few_unique":
+			arr[i] = rand.Intn(fewUniqueValues)
+		default:
+			arr[i] = i
+		}
+	}
+	if distribution == "random" {
+		rand.Shuffle(len(arr), func(i, j int) {
+			arr[i], arr[j] = arr[j], arr[i]
+		})
+	}
+}
+
+// algorithmResult accumulates one algorithm's runs across iterations.
+type algorithmResult struct {
+	totalTime   time.Duration
+	allocations uint64
+	failures    int
+}
+
+// matchesReference reports whether sorted holds the same elements in the
+// same order as reference.
+func matchesReference(sorted, reference []int) bool {
+	if len(sorted) != len(reference) {
+		return false
+	}
+	for i := range sorted {
+		if sorted[i] != reference[i] {
+			return false
+		}
+	}
+	return true
+}
+
 func main() {
 	size := 10000
-	arr := make([]int, size)
-	for i := range arr {
-		arr[i] = i
+	algorithms := []string{"quicksort", "merge_sort"}
+	distributions := []string{"random"}
+	iterations := 1
+	mode := "sequential"
+	workers := runtime.GOMAXPROCS(0)
+	threshold := defaultParallelThreshold
+	cutoff := 0
+	variant := "single_pivot"
+	
+	if len(os.Args) > 1 {
+		var config Config
+		data, err := os.ReadFile(os.Args[1])
+		if err == nil {
+			err = json.Unmarshal(data, &config)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading config: %v\n", err)
+			os.Exit(1)
+		}
+		if config.Parameters.ArraySize > 0 {
+			size = config.Parameters.ArraySize
+		}
+		if len(config.Parameters.Algorithms) > 0 {
+			algorithms = config.Parameters.Algorithms
+		}
+		if len(config.Parameters.Distributions) > 0 {
+			distributions = config.Parameters.Distributions
+		}
+		if config.Parameters.Iterations > 0 {
+			iterations = config.Parameters.Iterations
+		}
+		if config.Parameters.Mode != "" {
+			mode = config.Parameters.Mode
+		}
+		if config.Parameters.Workers > 0 {
+			workers = config.Parameters.Workers
+		}
+		if config.Parameters.Threshold > 0 {
+			threshold = config.Parameters.Threshold
+		}
+		if config.Parameters.InsertionCutoff > 0 {
+			cutoff = config.Parameters.InsertionCutoff
+		}
+		if config.Parameters.Variant != "" {
+			variant = config.Parameters.Variant
+		}
+	}
+	
+	if mode != "sequential" && mode != "parallel" {
+		fmt.Fprintf(os.Stderr, "Unknown mode '%s' (expected sequential or parallel)\n", mode)
+		os.Exit(1)
+	}
+	if variant != "single_pivot" && variant != "dual_pivot" {
+		fmt.Fprintf(os.Stderr, "Unknown variant '%s' (expected single_pivot or dual_pivot)\n", variant)
+		os.Exit(1)
+	}
+	if variant == "dual_pivot" && (mode == "parallel" || cutoff > 1) {
+		fmt.Fprintln(os.Stderr, "variant dual_pivot cannot be combined with parallel mode or an insertion cutoff")
+		os.Exit(1)
+	}
+	for _, name := range algorithms {
+		if _, ok := sortAlgorithms[name]; !ok {
+			fmt.Fprintf(os.Stderr, "Unknown algorithm '%s'\n", name)
+			os.Exit(1)
+		}
+	}
+	for _, distribution := range distributions {
+		switch distribution {
+		case "random", "sorted", "reversed", "few_unique":
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown distribution '%s' (expected random, sorted, reversed or few_unique)\n", distribution)
+			os.Exit(1)
+		}
 	}
 	
-	// Shuffle array
-	rand.Shuffle(len(arr), func(i, j int) {
-		arr[i], arr[j] = arr[j], arr[i]
-	})
+	// With an insertion cutoff quicksort runs hybridQuicksort, in parallel
+	// mode parallelQuicksort, and as the dual_pivot variant
+	// dualPivotQuicksort. Each variant is also timed against its baseline
+	// on the same input: sequential for parallel, pure quicksort for the
+	// cutoff and single-pivot quicksort for dual_pivot.
+	sorts := make(map[string]func([]int) []int, len(algorithms))
+	for _, name := range algorithms {
+		sorts[name] = sortAlgorithms[name]
+	}
+	_, runQuicksort := sorts["quicksort"]
+	parallel := mode == "parallel" && runQuicksort
+	hybrid := cutoff > 1 && runQuicksort
+	dualPivot := variant == "dual_pivot" && runQuicksort
+	if parallel {
+		sorts["quicksort"] = func(arr []int) []int {
+			parallelQuicksort(arr, workers, threshold, cutoff)
+			return arr
+		}
+	} else if hybrid {
+		sorts["quicksort"] = func(arr []int) []int {
+			hybridQuicksort(arr, cutoff)
+			return arr
+		}
+	} else if dualPivot {
+		sorts["quicksort"] = func(arr []int) []int {
+			dualPivotQuicksort(arr)
+			return arr
+		}
+	}
 	
-	fmt.Printf("Sorting array of size %d...\n", size)
-	start := time.Now()
+	fmt.Printf("Sorting array of size %d, %d iteration(s) per distribution", size, iterations)
+	if parallel {
+		fmt.Printf(" (parallel, %d workers, threshold %d)", workers, threshold)
+	}
+	if hybrid {
+		fmt.Printf(" (insertion cutoff %d)", cutoff)
+	}
+	if dualPivot {
+		fmt.Print(" (dual-pivot quicksort)")
+	}
+	fmt.Println("...")
 	
-	quicksort(arr)
+	// results[distribution][algorithm]
+	results := make(map[string]map[string]*algorithmResult, len(distributions))
+	var quicksortTime, sequentialTime, pureTime, singlePivotTime time.Duration
+	// counts[distribution] holds the comparisons and swaps of the
+	// single-pivot and dual-pivot sorts, summed over iterations
+	counts := make(map[string]*[2]sortCounts, len(distributions))
 	
-	duration := time.Since(start)
+	input := make([]int, size)
+	work := make([]int, size)
+	reference := make([]int, size)
+	for _, distribution := range distributions {
+		results[distribution] = make(map[string]*algorithmResult, len(algorithms))
+		for _, name := range algorithms {
+			results[distribution][name] = &algorithmResult{}
+		}
+		
+		for iteration := 0; iteration < iterations; iteration++ {
+			// Every algorithm sorts its own copy of the same input
+			fillInput(input, distribution)
+			copy(reference, input)
+			sort.Ints(reference)
+			
+			for _, name := range algorithms {
+				copy(work, input)
+				var sorted []int
+				var duration time.Duration
+				allocations := allocationsDuring(func() {
+					start := time.Now()
+					sorted = sorts[name](work)
+					duration = time.Since(start)
+				})
+				
+				result := results[distribution][name]
+				result.totalTime += duration
+				result.allocations += allocations
+				if !matchesReference(sorted, reference) {
+					result.failures++
+				}
+				if name == "quicksort" {
+					quicksortTime += duration
+				}
+			}
+			
+			if parallel {
+				copy(work, input)
+				start := time.Now()
+				hybridQuicksort(work, cutoff)
+				sequentialTime += time.Since(start)
+			}
+			if hybrid {
+				copy(work, input)
+				start := time.Now()
+				quicksort(work)
+				pureTime += time.Since(start)
+			}
+			if dualPivot {
+				copy(work, input)
+				start := time.Now()
+				quicksort(work)
+				singlePivotTime += time.Since(start)
+				
+				// Counted in a separate pass, keeping the tallies out of the timings
+				if counts[distribution] == nil {
+					counts[distribution] = &[2]sortCounts{}
+				}
+				copy(work, input)
+				countedQuicksort(work, &counts[distribution][0])
+				copy(work, input)
+				dualPivotSort(work, &counts[distribution][1])
+			}
+		}
+	}
 	
-	// Verify correctness
-	isSorted := true
-	for i := 1; i < len(arr); i++ {
-		if arr[i] < arr[i-1] {
-			isSorted = false
-			break
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "Distribution\tAlgorithm\tResult\tAvg time (s)\tAllocations per sort")
+	for _, distribution := range distributions {
+		for _, name := range algorithms {
+			result := results[distribution][name]
+			status := "Sorted correctly"
+			if result.failures > 0 {
+				status = fmt.Sprintf("Sort failed (%d/%d)", result.failures, iterations)
+			}
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%.6f\t%d\n", distribution, name, status,
+				result.totalTime.Seconds()/float64(iterations), result.allocations/uint64(iterations))
 		}
 	}
+	writer.Flush()
 	
-	if isSorted {
-		fmt.Println("Result: Sorted correctly")
-	} else {
-		fmt.Println("Result: Sort failed")
+	runs := float64(iterations * len(distributions))
+	if parallel {
+		fmt.Printf("Sequential quicksort time: %.6f seconds\n", sequentialTime.Seconds()/runs)
+		fmt.Printf("Speedup vs sequential: %.2fx\n", sequentialTime.Seconds()/quicksortTime.Seconds())
 	}
-	fmt.Printf("Execution time: %.6f seconds\n", duration.Seconds())
-}
\ No newline at end of file
+	if hybrid {
+		// In parallel mode the sequential run is the hybrid one, so the
+		// cutoff's gain is not mixed up with the parallel speedup
+		hybridTime := quicksortTime
+		if parallel {
+			hybridTime = sequentialTime
+		}
+		fmt.Printf("Pure quicksort time: %.6f seconds\n", pureTime.Seconds()/runs)
+		fmt.Printf("Speedup vs pure quicksort: %.2fx\n", pureTime.Seconds()/hybridTime.Seconds())
+	}
+	if dualPivot {
+		fmt.Printf("Single-pivot quicksort time: %.6f seconds\n", singlePivotTime.Seconds()/runs)
+		fmt.Printf("Speedup vs single-pivot: %.2fx\n", singlePivotTime.Seconds()/quicksortTime.Seconds())
+		
+		writer = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, "Distribution\tVariant\tComparisons per sort\tSwaps per sort")
+		for _, distribution := range distributions {
+			for i, name := range []string{"single_pivot", "dual_pivot"} {
+				c := counts[distribution][i]
+				fmt.Fprintf(writer, "%s\t%s\t%d\t%d\n", distribution, name,
+					c.comparisons/iterations, c.swaps/iterations)
+			}
+		}
+		writer.Flush()
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+)
+
+// TestDualPivotQuicksort compares dualPivotQuicksort with sort.Ints on the
+// inputs it is easiest to get wrong: the two-element base case in either
+// order, and runs of duplicates that make both pivots equal.
+func TestDualPivotQuicksort(t *testing.T) {
+	inputs := [][]int{
+		{},
+		{1},
+		{1, 2},
+		{2, 1},
+		{3, 3},
+		{5, 5, 5, 5, 5, 5},
+		{2, 1, 2, 1, 2, 1, 2},
+		{4, 1, 4, 4, 0, 4, 1, 0, 4},
+		{9, 8, 7, 6, 5, 4, 3, 2, 1, 0},
+	}
+	for _, distribution := range []string{"random", "sorted", "reversed", "few_unique"} {
+		input := make([]int, 1000)
+		fillInput(input, distribution)
+		inputs = append(inputs, input)
+	}
+
+	for _, input := range inputs {
+		reference := append([]int(nil), input...)
+		sort.Ints(reference)
+
+		sorted := append([]int(nil), input...)
+		dualPivotQuicksort(sorted)
+		if !matchesReference(sorted, reference) {
+			t.Errorf("dualPivotQuicksort(%v) = %v", truncate(input), truncate(sorted))
+		}
+
+		var counts sortCounts
+		counted := append([]int(nil), input...)
+		dualPivotSort(counted, &counts)
+		if !matchesReference(counted, reference) {
+			t.Errorf("dualPivotSort with counts (%v) = %v", truncate(input), truncate(counted))
+		}
+		if len(input) > 1 && counts.comparisons == 0 {
+			t.Errorf("dualPivotSort counted no comparisons for %v", truncate(input))
+		}
+	}
+}
+
+// truncate shortens long inputs in failure messages.
+func truncate(values []int) string {
+	if len(values) <= 12 {
+		return fmt.Sprint(values)
+	}
+	return fmt.Sprintf("%v... (%d values)", values[:12], len(values))
+}
+
+// TestInPlaceSortsDoNotAllocate checks that the in-place sorts make no heap
+// allocations beyond their input, and that merge sort, their allocating
+// counterpart, does.
+func TestInPlaceSortsDoNotAllocate(t *testing.T) {
+	input := make([]int, 1000)
+	fillInput(input, "random")
+	arr := make([]int, len(input))
+
+	inPlace := []struct {
+		name string
+		sort func([]int)
+	}{
+		{"quicksort", quicksort},
+		{"hybrid quicksort", func(arr []int) { hybridQuicksort(arr, 16) }},
+		{"dual-pivot quicksort", dualPivotQuicksort},
+		{"heap sort", heapSort},
+	}
+	for _, tt := range inPlace {
+		allocs := testing.AllocsPerRun(20, func() {
+			copy(arr, input)
+			tt.sort(arr)
+		})
+		if allocs != 0 {
+			t.Errorf("%s made %v allocations per sort, want 0", tt.name, allocs)
+		}
+	}
+
+	allocs := testing.AllocsPerRun(20, func() {
+		copy(arr, input)
+		mergeSort(arr)
+	})
+	if allocs < float64(len(input))/2 {
+		t.Errorf("merge sort made %v allocations per sort, want about one per merge", allocs)
+	}
+}
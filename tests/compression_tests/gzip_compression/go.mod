@@ -1,3 +1,5 @@
 module gzip_compression
 
-go 1.19
\ No newline at end of file
+go 1.19
+
+require github.com/klauspost/compress v1.17.9
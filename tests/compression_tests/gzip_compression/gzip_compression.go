@@ -2,13 +2,27 @@ package main
 
 import (
 	"bytes"
+	"compress/bzip2"
+	"compress/flate"
 	"compress/gzip"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"math/rand"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
 	"strings"
+	"sync"
 	"time"
+
+	"benchmark_test/internal/cli"
+	"benchmark_test/internal/stats"
+
+	"github.com/klauspost/compress/zstd"
 )
 
 type CompressionResult struct {
@@ -19,16 +33,40 @@ type CompressionResult struct {
 	CompressionTime  float64  `json:"compression_time"`
 	ThroughputMbS    *float64 `json:"throughput_mb_s,omitempty"`
 	Error            *string  `json:"error,omitempty"`
+
+	compressed []byte
+}
+
+type DecompressionResult struct {
+	Success           bool     `json:"success"`
+	DecompressedSize  *int     `json:"decompressed_size,omitempty"`
+	DecompressionTime float64  `json:"decompression_time"`
+	ThroughputMbS     *float64 `json:"throughput_mb_s,omitempty"`
+	ChecksumMismatch  bool     `json:"checksum_mismatch,omitempty"`
+	Error             *string  `json:"error,omitempty"`
+
+	decompressed []byte
 }
 
 type IterationResult struct {
-	Iteration   int               `json:"iteration"`
-	Compression CompressionResult `json:"compression"`
+	Iteration      int                  `json:"iteration"`
+	Compression    CompressionResult    `json:"compression"`
+	Decompression  *DecompressionResult `json:"decompression,omitempty"`
+	RoundtripValid bool                 `json:"roundtrip_valid"`
+
+	// RatioWithoutDictionary is the plain flate ratio of the same input,
+	// reported for flate_dict iterations.
+	RatioWithoutDictionary *float64 `json:"ratio_without_dictionary,omitempty"`
+
+	// SingleThreadedTime is the time plain gzip takes to compress the same
+	// input at the same level, reported for parallel_gzip iterations.
+	SingleThreadedTime *float64 `json:"single_threaded_time,omitempty"`
 }
 
 type TestCase struct {
 	InputSize                  int               `json:"input_size"`
 	DataType                   string            `json:"data_type"`
+	Algorithm                  string            `json:"algorithm"`
 	CompressionLevel           int               `json:"compression_level"`
 	Iterations                 []IterationResult `json:"iterations"`
 	AvgCompressionRatio        float64           `json:"avg_compression_ratio"`
@@ -36,6 +74,13 @@ type TestCase struct {
 	AvgDecompressionTime       float64           `json:"avg_decompression_time"`
 	AvgCompressionThroughput   float64           `json:"avg_compression_throughput"`
 	AvgDecompressionThroughput float64           `json:"avg_decompression_throughput"`
+	DictionarySize             int               `json:"dictionary_size,omitempty"`
+	AvgRatioWithoutDictionary  float64           `json:"avg_ratio_without_dictionary,omitempty"`
+	DictionaryRatioImprovement float64           `json:"dictionary_ratio_improvement,omitempty"`
+	ParallelBlockSize          int               `json:"parallel_block_size,omitempty"`
+	ParallelWorkers            int               `json:"parallel_workers,omitempty"`
+	AvgSingleThreadedTime      float64           `json:"avg_single_threaded_time,omitempty"`
+	SpeedupVsGzip              float64           `json:"speedup_vs_gzip,omitempty"`
 }
 
 type Summary struct {
@@ -47,43 +92,130 @@ type Summary struct {
 	AvgDecompressionTime       float64 `json:"avg_decompression_time"`
 	AvgCompressionThroughput   float64 `json:"avg_compression_throughput"`
 	AvgDecompressionThroughput float64 `json:"avg_decompression_throughput"`
+	WarmupIterations           int     `json:"warmup_iterations"`
+	// LevelBreakdown compares the swept compression levels for each data
+	// type and algorithm; it is left out when only one level was run.
+	LevelBreakdown []LevelBreakdown `json:"level_breakdown,omitempty"`
+}
+
+// LevelPerformance is one compression level's test cases averaged over the
+// input sizes.
+type LevelPerformance struct {
+	CompressionLevel    int     `json:"compression_level"`
+	AvgCompressionRatio float64 `json:"avg_compression_ratio"`
+	AvgCompressionTime  float64 `json:"avg_compression_time"`
+}
+
+// LevelBreakdown lists the levels run for one data type and algorithm.
+// ParetoLevels are those no other level beats on both ratio and time;
+// RecommendedLevel is the best ratio among the levels at most
+// max_level_slowdown times slower than the fastest one.
+type LevelBreakdown struct {
+	DataType         string             `json:"data_type"`
+	Algorithm        string             `json:"algorithm"`
+	Levels           []LevelPerformance `json:"levels"`
+	BestRatioLevel   int                `json:"best_ratio_level"`
+	ParetoLevels     []int              `json:"pareto_levels"`
+	RecommendedLevel int                `json:"recommended_level"`
+}
+
+type Bzip2ComparisonCase struct {
+	InputSize                 int     `json:"input_size"`
+	DataType                  string  `json:"data_type"`
+	Bzip2CompressedSize       int     `json:"bzip2_compressed_size"`
+	GzipCompressedSize        int     `json:"gzip_compressed_size"`
+	AvgBzip2DecompressionTime float64 `json:"avg_bzip2_decompression_time"`
+	AvgGzipDecompressionTime  float64 `json:"avg_gzip_decompression_time"`
+	AvgBzip2Throughput        float64 `json:"avg_bzip2_throughput"`
+	AvgGzipThroughput         float64 `json:"avg_gzip_throughput"`
+	Error                     *string `json:"error,omitempty"`
+}
+
+type Bzip2Comparison struct {
+	Note      string                `json:"note"`
+	TestCases []Bzip2ComparisonCase `json:"test_cases"`
 }
 
 type BenchmarkResults struct {
-	StartTime          float64    `json:"start_time"`
-	TestCases          []TestCase `json:"test_cases"`
-	Summary            Summary    `json:"summary"`
-	EndTime            *float64   `json:"end_time,omitempty"`
-	TotalExecutionTime *float64   `json:"total_execution_time,omitempty"`
+	SchemaVersion      string           `json:"schema_version"`
+	Environment        cli.Environment  `json:"environment"`
+	Name               string           `json:"name,omitempty"`
+	Tags               []string         `json:"tags,omitempty"`
+	StartTime          float64          `json:"start_time"`
+	Seed               int64            `json:"seed"`
+	TestCases          []TestCase       `json:"test_cases"`
+	Summary            Summary          `json:"summary"`
+	Bzip2Comparison    *Bzip2Comparison `json:"bzip2_comparison,omitempty"`
+	EndTime            *float64         `json:"end_time,omitempty"`
+	TotalExecutionTime *float64         `json:"total_execution_time,omitempty"`
 }
 
 type Config struct {
 	Parameters Parameters `json:"parameters"`
+	Name       string     `json:"name"`
 }
 
 type Parameters struct {
 	InputSizes        []int    `json:"input_sizes"`
 	DataTypes         []string `json:"data_types"`
+	Algorithms        []string `json:"algorithms"`
 	CompressionLevels []int    `json:"compression_levels"`
 	Iterations        int      `json:"iterations"`
+	WarmupIterations  int      `json:"warmup_iterations"`
+	Bzip2Comparison   bool     `json:"bzip2_comparison"`
+	UseDictionary     bool     `json:"use_dictionary"`
+	MaxLevelSlowdown  float64  `json:"max_level_slowdown"`
+	// ParallelBlockSize and ParallelWorkers configure parallel_gzip; zero
+	// selects 1 MiB blocks and one worker per GOMAXPROCS.
+	ParallelBlockSize int      `json:"parallel_block_size"`
+	ParallelWorkers   int      `json:"parallel_workers"`
+	Seed              *int64   `json:"seed,omitempty"`
+	Tags              []string `json:"tags"`
 }
 
-func generateTestData(size int, dataType string) ([]byte, error) {
-	rand.Seed(time.Now().UnixNano())
+// defaultMaxLevelSlowdown is how much slower than the fastest level the
+// recommended level may be when parameters.max_level_slowdown is unset.
+const defaultMaxLevelSlowdown = 2.0
+
+// Validate rejects sizes and counts that cannot run. Zero iterations and
+// empty lists fall back to the defaults in runCompressionBenchmark.
+func (c *Config) Validate() error {
+	p := c.Parameters
+	return cli.FirstError(
+		cli.EachPositive("input_sizes", p.InputSizes),
+		cli.NonNegative("iterations", p.Iterations),
+		cli.NonNegative("warmup_iterations", p.WarmupIterations),
+		validMaxLevelSlowdown(p.MaxLevelSlowdown),
+		cli.NonNegative("parallel_block_size", p.ParallelBlockSize),
+		cli.NonNegative("parallel_workers", p.ParallelWorkers),
+	)
+}
+
+func validMaxLevelSlowdown(slowdown float64) error {
+	if slowdown != 0 && slowdown < 1 {
+		return &cli.FieldError{Field: "max_level_slowdown", Message: fmt.Sprintf("must be at least 1, got %g", slowdown)}
+	}
+	return nil
+}
+
+// rng generates the input data. The seed is reported in the results so a run
+// can be replayed with the same inputs.
+var rng *rand.Rand
 
+func generateTestData(size int, dataType string) ([]byte, error) {
 	switch dataType {
 	case "text":
 		chars := "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789 \n"
 		result := make([]byte, size)
 		for i := 0; i < size; i++ {
-			result[i] = chars[rand.Intn(len(chars))]
+			result[i] = chars[rng.Intn(len(chars))]
 		}
 		return result, nil
 
 	case "binary":
 		result := make([]byte, size)
 		for i := 0; i < size; i++ {
-			result[i] = byte(rand.Intn(256))
+			result[i] = byte(rng.Intn(256))
 		}
 		return result, nil
 
@@ -95,8 +227,8 @@ func generateTestData(size int, dataType string) ([]byte, error) {
 			record := map[string]interface{}{
 				"id":     len(data),
 				"name":   generateRandomString(10),
-				"value":  rand.Intn(1000) + 1,
-				"active": rand.Intn(2) == 1,
+				"value":  rng.Intn(1000) + 1,
+				"active": rng.Intn(2) == 1,
 				"data":   generateRandomString(50),
 			}
 			data = append(data, record)
@@ -124,35 +256,225 @@ func generateRandomString(length int) string {
 	chars := "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
 	var result strings.Builder
 	for i := 0; i < length; i++ {
-		result.WriteByte(chars[rand.Intn(len(chars))])
+		result.WriteByte(chars[rng.Intn(len(chars))])
 	}
 	return result.String()
 }
 
-func compressData(data []byte, compressionLevel int) CompressionResult {
-	start := time.Now()
-	originalSize := len(data)
+// minMeasurableTimeMs is the timing resolution of the benchmark. Times are
+// kept at the nanosecond precision of time.Since, so only an operation that
+// the clock could not observe at all reads as 0.
+const minMeasurableTimeMs = 1e-6
 
-	var buf bytes.Buffer
+// throughputMbS returns the throughput in MB/s for size bytes processed in
+// timeMs milliseconds, or nil when timeMs is below minMeasurableTimeMs and the
+// division would produce an infinite or meaningless value.
+func throughputMbS(size int, timeMs float64) *float64 {
+	if timeMs < minMeasurableTimeMs {
+		return nil
+	}
+	throughput := float64(size) / (timeMs / 1000.0) / (1024.0 * 1024.0)
+	return &throughput
+}
+
+// newGzipWriter maps the benchmark's 1-9 level onto a gzip writer, falling
+// back to the default level for anything out of range.
+func newGzipWriter(w io.Writer, compressionLevel int) *gzip.Writer {
 	var writer *gzip.Writer
 
 	switch compressionLevel {
 	case 1:
-		writer, _ = gzip.NewWriterLevel(&buf, gzip.BestSpeed)
+		writer, _ = gzip.NewWriterLevel(w, gzip.BestSpeed)
 	case 2, 3, 4, 5:
-		writer, _ = gzip.NewWriterLevel(&buf, compressionLevel)
+		writer, _ = gzip.NewWriterLevel(w, compressionLevel)
 	case 6:
-		writer = gzip.NewWriter(&buf)
+		writer = gzip.NewWriter(w)
 	case 7, 8, 9:
-		writer, _ = gzip.NewWriterLevel(&buf, compressionLevel)
+		writer, _ = gzip.NewWriterLevel(w, compressionLevel)
 	default:
-		writer = gzip.NewWriter(&buf)
+		writer = gzip.NewWriter(w)
+	}
+
+	return writer
+}
+
+// defaultParallelBlockSize is the parallel_gzip block size when
+// parameters.parallel_block_size is unset, the same as pgzip's default.
+const defaultParallelBlockSize = 1 << 20
+
+// parallelBlockSize and parallelWorkers are the parallel_gzip settings, set
+// from the parameters at the start of a run.
+var (
+	parallelBlockSize = defaultParallelBlockSize
+	parallelWorkers   = runtime.GOMAXPROCS(0)
+)
+
+// gzipBlock is one block of a parallelGzipWriter, compressed into its own
+// gzip member.
+type gzipBlock struct {
+	member bytes.Buffer
+	err    error
+}
+
+// parallelGzipWriter splits its input into fixed-size blocks and compresses
+// each into a separate gzip member on its own goroutine, with at most
+// workers running at once. Blocks share no history, which costs some ratio
+// at block boundaries. Close writes the members to w in input order; the
+// concatenation is a valid multi-member gzip stream that gzip.NewReader
+// reads in full.
+type parallelGzipWriter struct {
+	w         io.Writer
+	level     int
+	blockSize int
+	pending   []byte
+	blocks    []*gzipBlock
+	workers   chan struct{}
+	wg        sync.WaitGroup
+}
+
+func newParallelGzipWriter(w io.Writer, compressionLevel, blockSize, workers int) *parallelGzipWriter {
+	return &parallelGzipWriter{
+		w:         w,
+		level:     compressionLevel,
+		blockSize: blockSize,
+		workers:   make(chan struct{}, workers),
 	}
+}
 
-	_, err := writer.Write(data)
+func (pw *parallelGzipWriter) Write(p []byte) (int, error) {
+	pw.pending = append(pw.pending, p...)
+	for len(pw.pending) >= pw.blockSize {
+		pw.dispatch(pw.pending[:pw.blockSize:pw.blockSize])
+		pw.pending = pw.pending[pw.blockSize:]
+	}
+	return len(p), nil
+}
+
+// dispatch starts compressing data once a worker is free. data must not be
+// modified afterwards.
+func (pw *parallelGzipWriter) dispatch(data []byte) {
+	block := &gzipBlock{}
+	pw.blocks = append(pw.blocks, block)
+	pw.workers <- struct{}{}
+	pw.wg.Add(1)
+	go func() {
+		defer func() {
+			<-pw.workers
+			pw.wg.Done()
+		}()
+		writer := newGzipWriter(&block.member, pw.level)
+		if _, err := writer.Write(data); err != nil {
+			block.err = err
+			return
+		}
+		block.err = writer.Close()
+	}()
+}
+
+// Close compresses the final partial block, waits for the workers and
+// writes the members out. Empty input still produces one (empty) member.
+func (pw *parallelGzipWriter) Close() error {
+	if len(pw.pending) > 0 || len(pw.blocks) == 0 {
+		pw.dispatch(pw.pending)
+		pw.pending = nil
+	}
+	pw.wg.Wait()
+	for _, block := range pw.blocks {
+		if block.err != nil {
+			return block.err
+		}
+		if _, err := pw.w.Write(block.member.Bytes()); err != nil {
+			return err
+		}
+	}
+	pw.blocks = nil
+	return nil
+}
+
+// zstdEncoderLevel maps the gzip-style 1-9 level onto zstd's four encoder
+// presets: 1-2 fastest, 3-5 default, 6-8 better and 9 best compression.
+func zstdEncoderLevel(compressionLevel int) zstd.EncoderLevel {
+	switch {
+	case compressionLevel <= 2:
+		return zstd.SpeedFastest
+	case compressionLevel <= 5:
+		return zstd.SpeedDefault
+	case compressionLevel <= 8:
+		return zstd.SpeedBetterCompression
+	default:
+		return zstd.SpeedBestCompression
+	}
+}
+
+// flateLevel passes 1-9 through to compress/flate and maps anything else to
+// the default level, as newGzipWriter does.
+func flateLevel(compressionLevel int) int {
+	if compressionLevel < flate.BestSpeed || compressionLevel > flate.BestCompression {
+		return flate.DefaultCompression
+	}
+	return compressionLevel
+}
+
+// newCompressor returns a writer for algorithm. dict is the preset
+// dictionary of flate_dict and is ignored by the other algorithms.
+func newCompressor(algorithm string, w io.Writer, compressionLevel int, dict []byte) (io.WriteCloser, error) {
+	switch algorithm {
+	case "gzip":
+		return newGzipWriter(w, compressionLevel), nil
+	case "parallel_gzip":
+		return newParallelGzipWriter(w, compressionLevel, parallelBlockSize, parallelWorkers), nil
+	case "flate":
+		return flate.NewWriter(w, flateLevel(compressionLevel))
+	case "flate_dict":
+		return flate.NewWriterDict(w, flateLevel(compressionLevel), dict)
+	case "zstd":
+		return zstd.NewWriter(w, zstd.WithEncoderLevel(zstdEncoderLevel(compressionLevel)))
+	default:
+		return nil, fmt.Errorf("unknown algorithm: %s", algorithm)
+	}
+}
+
+func newDecompressor(algorithm string, r io.Reader, dict []byte) (io.ReadCloser, error) {
+	switch algorithm {
+	case "gzip", "parallel_gzip":
+		return gzip.NewReader(r)
+	case "flate":
+		return flate.NewReader(r), nil
+	case "flate_dict":
+		return flate.NewReaderDict(r, dict), nil
+	case "bzip2":
+		return io.NopCloser(bzip2.NewReader(r)), nil
+	case "zstd":
+		decoder, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return decoder.IOReadCloser(), nil
+	default:
+		return nil, fmt.Errorf("unknown algorithm: %s", algorithm)
+	}
+}
+
+func compressData(data []byte, algorithm string, compressionLevel int, dict []byte) CompressionResult {
+	start := time.Now()
+	originalSize := len(data)
+
+	var buf bytes.Buffer
+	writer, err := newCompressor(algorithm, &buf, compressionLevel, dict)
+	if err != nil {
+		compressionTime := float64(time.Since(start).Nanoseconds()) / 1e6
+		errStr := err.Error()
+		return CompressionResult{
+			Success:         false,
+			OriginalSize:    &originalSize,
+			CompressionTime: compressionTime,
+			Error:           &errStr,
+		}
+	}
+
+	_, err = writer.Write(data)
 	if err != nil {
 		compressionTime := float64(time.Since(start).Nanoseconds()) / 1e6
-		compressionTime = float64(int(compressionTime*100)) / 100
 		errStr := err.Error()
 		return CompressionResult{
 			Success:         false,
@@ -165,7 +487,6 @@ func compressData(data []byte, compressionLevel int) CompressionResult {
 	err = writer.Close()
 	if err != nil {
 		compressionTime := float64(time.Since(start).Nanoseconds()) / 1e6
-		compressionTime = float64(int(compressionTime*100)) / 100
 		errStr := err.Error()
 		return CompressionResult{
 			Success:         false,
@@ -178,7 +499,6 @@ func compressData(data []byte, compressionLevel int) CompressionResult {
 	compressed := buf.Bytes()
 	compressedSize := len(compressed)
 	compressionTime := float64(time.Since(start).Nanoseconds()) / 1e6
-	compressionTime = float64(int(compressionTime*100)) / 100
 
 	var compressionRatio float64
 	if compressedSize > 0 {
@@ -186,17 +506,79 @@ func compressData(data []byte, compressionLevel int) CompressionResult {
 		compressionRatio = float64(int(compressionRatio*1000)) / 1000
 	}
 
-	throughput := float64(originalSize) / (compressionTime / 1000.0) / (1024.0 * 1024.0)
-	throughput = float64(int(throughput*100)) / 100
-
 	return CompressionResult{
 		Success:          true,
 		OriginalSize:     &originalSize,
 		CompressedSize:   &compressedSize,
 		CompressionRatio: &compressionRatio,
 		CompressionTime:  compressionTime,
-		ThroughputMbS:    &throughput,
+		ThroughputMbS:    throughputMbS(originalSize, compressionTime),
+		compressed:       compressed,
+	}
+}
+
+func decompressData(data []byte, algorithm string, dict []byte) (DecompressionResult, error) {
+	start := time.Now()
+
+	reader, err := newDecompressor(algorithm, bytes.NewReader(data), dict)
+	if err != nil {
+		decompressionTime := float64(time.Since(start).Nanoseconds()) / 1e6
+		errStr := err.Error()
+		return DecompressionResult{
+			Success:           false,
+			DecompressionTime: decompressionTime,
+			Error:             &errStr,
+		}, err
 	}
+	defer reader.Close()
+
+	decompressed, err := io.ReadAll(reader)
+	if err != nil {
+		decompressionTime := float64(time.Since(start).Nanoseconds()) / 1e6
+		errStr := err.Error()
+		return DecompressionResult{
+			Success:           false,
+			DecompressionTime: decompressionTime,
+			ChecksumMismatch:  errors.Is(err, gzip.ErrChecksum) || errors.Is(err, zstd.ErrCRCMismatch),
+			Error:             &errStr,
+		}, err
+	}
+
+	decompressedSize := len(decompressed)
+	decompressionTime := float64(time.Since(start).Nanoseconds()) / 1e6
+
+	return DecompressionResult{
+		Success:           true,
+		DecompressedSize:  &decompressedSize,
+		DecompressionTime: decompressionTime,
+		ThroughputMbS:     throughputMbS(decompressedSize, decompressionTime),
+		decompressed:      decompressed,
+	}, nil
+}
+
+// warmupCompression runs one untimed compress/decompress roundtrip so that
+// the first measured iteration does not pay for allocating encoder state and
+// lazily initialized tables.
+func warmupCompression(size int, dataType string, algorithm string, level int, dict []byte) {
+	testData, err := generateTestData(size, dataType)
+	if err != nil {
+		return
+	}
+	compressionResult := compressData(testData, algorithm, level, dict)
+	if compressionResult.Success {
+		decompressData(compressionResult.compressed, algorithm, dict)
+	}
+}
+
+// maxDictionarySize is the flate window. Bytes of a preset dictionary
+// beyond it can never be referenced, so buildDictionary stops there.
+const maxDictionarySize = 32 * 1024
+
+// buildDictionary generates a training sample of dataType to seed flate_dict.
+// It is separate data from the same generator, standing in for messages
+// seen earlier: the JSON keys and punctuation recur, the values do not.
+func buildDictionary(dataType string) ([]byte, error) {
+	return generateTestData(maxDictionarySize, dataType)
 }
 
 func runCompressionBenchmark(config Parameters) BenchmarkResults {
@@ -210,19 +592,49 @@ func runCompressionBenchmark(config Parameters) BenchmarkResults {
 		dataTypes = []string{"text"}
 	}
 
+	algorithms := config.Algorithms
+	if algorithms == nil {
+		algorithms = []string{"gzip"}
+	}
+
 	compressionLevels := config.CompressionLevels
 	if compressionLevels == nil {
 		compressionLevels = []int{6}
 	}
 
+	if config.UseDictionary && !containsString(algorithms, "flate_dict") {
+		algorithms = append(algorithms, "flate_dict")
+	}
+	dictionaries := make(map[string][]byte)
+
 	iterations := config.Iterations
 	if iterations == 0 {
 		iterations = 5
 	}
 
+	maxLevelSlowdown := config.MaxLevelSlowdown
+	if maxLevelSlowdown == 0 {
+		maxLevelSlowdown = defaultMaxLevelSlowdown
+	}
+
+	parallelBlockSize = config.ParallelBlockSize
+	if parallelBlockSize == 0 {
+		parallelBlockSize = defaultParallelBlockSize
+	}
+	parallelWorkers = config.ParallelWorkers
+	if parallelWorkers == 0 {
+		parallelWorkers = runtime.GOMAXPROCS(0)
+	}
+
+	seed := cli.Seed(config.Seed)
+	rng = rand.New(rand.NewSource(seed))
+
 	results := BenchmarkResults{
-		StartTime: float64(time.Now().Unix()),
-		TestCases: []TestCase{},
+		SchemaVersion: cli.SchemaVersion,
+		Environment:   cli.CurrentEnvironment(),
+		StartTime:     float64(time.Now().Unix()),
+		Seed:          seed,
+		TestCases:     []TestCase{},
 		Summary: Summary{
 			TotalTests:                 0,
 			SuccessfulTests:            0,
@@ -232,90 +644,194 @@ func runCompressionBenchmark(config Parameters) BenchmarkResults {
 			AvgDecompressionTime:       0.0,
 			AvgCompressionThroughput:   0.0,
 			AvgDecompressionThroughput: 0.0,
+			WarmupIterations:           config.WarmupIterations,
 		},
 	}
 
 	var totalCompressionRatios []float64
 	var totalCompressionTimes []float64
 	var totalCompressionThroughputs []float64
+	var totalDecompressionTimes []float64
+	var totalDecompressionThroughputs []float64
+	var levelGroups []*levelGroup
 
 	for _, size := range inputSizes {
 		for _, dataType := range dataTypes {
-			for _, level := range compressionLevels {
-				fmt.Fprintf(os.Stderr, "Testing %s data, size: %d bytes, level: %d...\n", dataType, size, level)
-
-				testCase := TestCase{
-					InputSize:                  size,
-					DataType:                   dataType,
-					CompressionLevel:           level,
-					Iterations:                 []IterationResult{},
-					AvgCompressionRatio:        0.0,
-					AvgCompressionTime:         0.0,
-					AvgDecompressionTime:       0.0,
-					AvgCompressionThroughput:   0.0,
-					AvgDecompressionThroughput: 0.0,
-				}
+			for _, algorithm := range algorithms {
+				for _, level := range compressionLevels {
+					fmt.Fprintf(os.Stderr, "Testing %s data, size: %d bytes, algorithm: %s, level: %d...\n", dataType, size, algorithm, level)
 
-				var iterationCompressionRatios []float64
-				var iterationCompressionTimes []float64
-				var iterationCompressionThroughputs []float64
-
-				for i := 0; i < iterations; i++ {
-					fmt.Fprintf(os.Stderr, "  Iteration %d/%d...\n", i+1, iterations)
+					var dict []byte
+					if algorithm == "flate_dict" {
+						if !config.UseDictionary {
+							fmt.Fprintf(os.Stderr, "Warning: flate_dict needs parameters.use_dictionary, skipping\n")
+							continue
+						}
+						if dictionaries[dataType] == nil {
+							built, err := buildDictionary(dataType)
+							if err != nil {
+								fmt.Fprintf(os.Stderr, "Error building dictionary: %v\n", err)
+								continue
+							}
+							dictionaries[dataType] = built
+						}
+						dict = dictionaries[dataType]
+					}
 
-					testData, err := generateTestData(size, dataType)
-					if err != nil {
-						fmt.Fprintf(os.Stderr, "Error generating test data: %v\n", err)
-						continue
+					testCase := TestCase{
+						InputSize:                  size,
+						DataType:                   dataType,
+						Algorithm:                  algorithm,
+						CompressionLevel:           level,
+						Iterations:                 []IterationResult{},
+						AvgCompressionRatio:        0.0,
+						AvgCompressionTime:         0.0,
+						AvgDecompressionTime:       0.0,
+						AvgCompressionThroughput:   0.0,
+						AvgDecompressionThroughput: 0.0,
 					}
 
-					compressionResult := compressData(testData, level)
+					var iterationCompressionRatios []float64
+					var iterationCompressionTimes []float64
+					var iterationCompressionThroughputs []float64
+					var iterationDecompressionTimes []float64
+					var iterationDecompressionThroughputs []float64
+					var iterationRatiosWithoutDictionary []float64
+					var iterationSingleThreadedTimes []float64
 
-					iterationResult := IterationResult{
-						Iteration:   i + 1,
-						Compression: compressionResult,
+					for i := 0; i < config.WarmupIterations; i++ {
+						fmt.Fprintf(os.Stderr, "  Warmup %d/%d...\n", i+1, config.WarmupIterations)
+						warmupCompression(size, dataType, algorithm, level, dict)
 					}
 
-					results.Summary.TotalTests++
+					for i := 0; i < iterations; i++ {
+						fmt.Fprintf(os.Stderr, "  Iteration %d/%d...\n", i+1, iterations)
+
+						testData, err := generateTestData(size, dataType)
+						if err != nil {
+							fmt.Fprintf(os.Stderr, "Error generating test data: %v\n", err)
+							continue
+						}
+
+						compressionResult := compressData(testData, algorithm, level, dict)
 
-					if compressionResult.Success {
-						results.Summary.SuccessfulTests++
+						iterationResult := IterationResult{
+							Iteration:   i + 1,
+							Compression: compressionResult,
+						}
+
+						if dict != nil {
+							plain := compressData(testData, "flate", level, nil)
+							iterationResult.RatioWithoutDictionary = plain.CompressionRatio
+						}
 
-						if compressionResult.CompressionRatio != nil {
-							iterationCompressionRatios = append(iterationCompressionRatios, *compressionResult.CompressionRatio)
+						if algorithm == "parallel_gzip" {
+							if single := compressData(testData, "gzip", level, nil); single.Success {
+								iterationResult.SingleThreadedTime = &single.CompressionTime
+							}
 						}
-						iterationCompressionTimes = append(iterationCompressionTimes, compressionResult.CompressionTime)
-						if compressionResult.ThroughputMbS != nil {
-							iterationCompressionThroughputs = append(iterationCompressionThroughputs, *compressionResult.ThroughputMbS)
+
+						results.Summary.TotalTests++
+
+						if compressionResult.Success {
+							decompressionResult, err := decompressData(compressionResult.compressed, algorithm, dict)
+							if err == nil {
+								iterationResult.RoundtripValid = bytes.Equal(decompressionResult.decompressed, testData)
+								if !iterationResult.RoundtripValid {
+									decompressionResult.Success = false
+									errStr := "decompressed data does not match original input"
+									decompressionResult.Error = &errStr
+								}
+							}
+							iterationResult.Decompression = &decompressionResult
+						}
+
+						if compressionResult.Success && iterationResult.RoundtripValid {
+							results.Summary.SuccessfulTests++
+
+							if compressionResult.CompressionRatio != nil {
+								iterationCompressionRatios = append(iterationCompressionRatios, *compressionResult.CompressionRatio)
+							}
+							if iterationResult.RatioWithoutDictionary != nil {
+								iterationRatiosWithoutDictionary = append(iterationRatiosWithoutDictionary, *iterationResult.RatioWithoutDictionary)
+							}
+							if iterationResult.SingleThreadedTime != nil {
+								iterationSingleThreadedTimes = append(iterationSingleThreadedTimes, *iterationResult.SingleThreadedTime)
+							}
+							iterationCompressionTimes = append(iterationCompressionTimes, compressionResult.CompressionTime)
+							if compressionResult.ThroughputMbS != nil {
+								iterationCompressionThroughputs = append(iterationCompressionThroughputs, *compressionResult.ThroughputMbS)
+							}
+
+							decompressionResult := iterationResult.Decompression
+							iterationDecompressionTimes = append(iterationDecompressionTimes, decompressionResult.DecompressionTime)
+							if decompressionResult.ThroughputMbS != nil {
+								iterationDecompressionThroughputs = append(iterationDecompressionThroughputs, *decompressionResult.ThroughputMbS)
+							}
+						} else {
+							results.Summary.FailedTests++
 						}
-					} else {
-						results.Summary.FailedTests++
+
+						testCase.Iterations = append(testCase.Iterations, iterationResult)
 					}
 
-					testCase.Iterations = append(testCase.Iterations, iterationResult)
-				}
+					// Calculate averages for this test case
+					if len(iterationCompressionRatios) > 0 {
+						testCase.AvgCompressionRatio = stats.Mean(iterationCompressionRatios)
+						testCase.AvgCompressionTime = stats.Mean(iterationCompressionTimes)
+						testCase.AvgCompressionThroughput = stats.Mean(iterationCompressionThroughputs)
+						testCase.AvgDecompressionTime = stats.Mean(iterationDecompressionTimes)
+						testCase.AvgDecompressionThroughput = stats.Mean(iterationDecompressionThroughputs)
 
-				// Calculate averages for this test case
-				if len(iterationCompressionRatios) > 0 {
-					testCase.AvgCompressionRatio = average(iterationCompressionRatios)
-					testCase.AvgCompressionTime = average(iterationCompressionTimes)
-					testCase.AvgCompressionThroughput = average(iterationCompressionThroughputs)
+						totalCompressionRatios = append(totalCompressionRatios, iterationCompressionRatios...)
+						totalCompressionTimes = append(totalCompressionTimes, iterationCompressionTimes...)
+						totalCompressionThroughputs = append(totalCompressionThroughputs, iterationCompressionThroughputs...)
+						totalDecompressionTimes = append(totalDecompressionTimes, iterationDecompressionTimes...)
+						totalDecompressionThroughputs = append(totalDecompressionThroughputs, iterationDecompressionThroughputs...)
 
-					totalCompressionRatios = append(totalCompressionRatios, iterationCompressionRatios...)
-					totalCompressionTimes = append(totalCompressionTimes, iterationCompressionTimes...)
-					totalCompressionThroughputs = append(totalCompressionThroughputs, iterationCompressionThroughputs...)
-				}
+						levelGroups = addLevelSample(levelGroups, testCase)
+					}
+
+					if dict != nil {
+						testCase.DictionarySize = len(dict)
+						testCase.AvgRatioWithoutDictionary = stats.Mean(iterationRatiosWithoutDictionary)
+						if testCase.AvgRatioWithoutDictionary > 0 {
+							testCase.DictionaryRatioImprovement = testCase.AvgCompressionRatio / testCase.AvgRatioWithoutDictionary
+						}
+					}
 
-				results.TestCases = append(results.TestCases, testCase)
+					if algorithm == "parallel_gzip" {
+						testCase.ParallelBlockSize = parallelBlockSize
+						testCase.ParallelWorkers = parallelWorkers
+						testCase.AvgSingleThreadedTime = stats.Mean(iterationSingleThreadedTimes)
+						if testCase.AvgCompressionTime > 0 {
+							testCase.SpeedupVsGzip = testCase.AvgSingleThreadedTime / testCase.AvgCompressionTime
+						}
+					}
+
+					results.TestCases = append(results.TestCases, testCase)
+				}
 			}
 		}
 	}
 
 	// Calculate overall summary
 	if len(totalCompressionRatios) > 0 {
-		results.Summary.AvgCompressionRatio = average(totalCompressionRatios)
-		results.Summary.AvgCompressionTime = average(totalCompressionTimes)
-		results.Summary.AvgCompressionThroughput = average(totalCompressionThroughputs)
+		results.Summary.AvgCompressionRatio = stats.Mean(totalCompressionRatios)
+		results.Summary.AvgCompressionTime = stats.Mean(totalCompressionTimes)
+		results.Summary.AvgCompressionThroughput = stats.Mean(totalCompressionThroughputs)
+		results.Summary.AvgDecompressionTime = stats.Mean(totalDecompressionTimes)
+		results.Summary.AvgDecompressionThroughput = stats.Mean(totalDecompressionThroughputs)
+	}
+
+	if len(compressionLevels) > 1 {
+		for _, group := range levelGroups {
+			results.Summary.LevelBreakdown = append(results.Summary.LevelBreakdown, group.breakdown(maxLevelSlowdown))
+		}
+	}
+
+	if config.Bzip2Comparison {
+		results.Bzip2Comparison = runBzip2Comparison(inputSizes, dataTypes, iterations)
 	}
 
 	endTime := float64(time.Now().Unix())
@@ -326,45 +842,230 @@ func runCompressionBenchmark(config Parameters) BenchmarkResults {
 	return results
 }
 
-func average(values []float64) float64 {
-	if len(values) == 0 {
-		return 0.0
+// levelGroup collects the averages of the successful test cases of one data
+// type and algorithm, per compression level.
+type levelGroup struct {
+	dataType  string
+	algorithm string
+	ratios    map[int][]float64
+	times     map[int][]float64
+}
+
+// addLevelSample adds testCase's averages to the group for its data type and
+// algorithm, creating it if needed, and returns the groups in the order they
+// were first seen.
+func addLevelSample(groups []*levelGroup, testCase TestCase) []*levelGroup {
+	var group *levelGroup
+	for _, g := range groups {
+		if g.dataType == testCase.DataType && g.algorithm == testCase.Algorithm {
+			group = g
+			break
+		}
 	}
-	sum := 0.0
-	for _, v := range values {
-		sum += v
+	if group == nil {
+		group = &levelGroup{
+			dataType:  testCase.DataType,
+			algorithm: testCase.Algorithm,
+			ratios:    make(map[int][]float64),
+			times:     make(map[int][]float64),
+		}
+		groups = append(groups, group)
 	}
-	return sum / float64(len(values))
+	level := testCase.CompressionLevel
+	group.ratios[level] = append(group.ratios[level], testCase.AvgCompressionRatio)
+	group.times[level] = append(group.times[level], testCase.AvgCompressionTime)
+	return groups
 }
 
-func main() {
-	if len(os.Args) < 2 {
-		fmt.Fprintf(os.Stderr, "Usage: %s <config_file>\n", os.Args[0])
-		os.Exit(1)
+// breakdown averages each level over the input sizes and picks the best,
+// Pareto-optimal and recommended levels. Ties on ratio go to the faster
+// level.
+func (g *levelGroup) breakdown(maxSlowdown float64) LevelBreakdown {
+	result := LevelBreakdown{DataType: g.dataType, Algorithm: g.algorithm}
+	for level := range g.ratios {
+		result.Levels = append(result.Levels, LevelPerformance{
+			CompressionLevel:    level,
+			AvgCompressionRatio: stats.Mean(g.ratios[level]),
+			AvgCompressionTime:  stats.Mean(g.times[level]),
+		})
+	}
+	sort.Slice(result.Levels, func(i, j int) bool {
+		return result.Levels[i].CompressionLevel < result.Levels[j].CompressionLevel
+	})
+
+	better := func(a, b LevelPerformance) bool {
+		return a.AvgCompressionRatio > b.AvgCompressionRatio ||
+			a.AvgCompressionRatio == b.AvgCompressionRatio && a.AvgCompressionTime < b.AvgCompressionTime
+	}
+
+	fastest := result.Levels[0].AvgCompressionTime
+	for _, level := range result.Levels {
+		if level.AvgCompressionTime < fastest {
+			fastest = level.AvgCompressionTime
+		}
 	}
 
-	configFile := os.Args[1]
+	best, recommended := result.Levels[0], LevelPerformance{AvgCompressionRatio: -1}
+	for _, level := range result.Levels {
+		if better(level, best) {
+			best = level
+		}
+		if level.AvgCompressionTime <= fastest*maxSlowdown && better(level, recommended) {
+			recommended = level
+		}
+
+		dominated := false
+		for _, other := range result.Levels {
+			if other.AvgCompressionRatio >= level.AvgCompressionRatio && other.AvgCompressionTime <= level.AvgCompressionTime &&
+				(other.AvgCompressionRatio > level.AvgCompressionRatio || other.AvgCompressionTime < level.AvgCompressionTime) {
+				dominated = true
+				break
+			}
+		}
+		if !dominated {
+			result.ParetoLevels = append(result.ParetoLevels, level.CompressionLevel)
+		}
+	}
+	result.BestRatioLevel = best.CompressionLevel
+	result.RecommendedLevel = recommended.CompressionLevel
+	return result
+}
 
-	configContent, err := os.ReadFile(configFile)
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
+
+// compressWithBzip2Tool compresses data out-of-band with the external bzip2
+// command, since the standard library's compress/bzip2 can only decompress.
+func compressWithBzip2Tool(data []byte) ([]byte, error) {
+	path, err := exec.LookPath("bzip2")
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: Cannot read config file '%s': %v\n", configFile, err)
-		os.Exit(1)
+		return nil, fmt.Errorf("bzip2 executable not found: %v", err)
 	}
 
-	var config Config
-	err = json.Unmarshal(configContent, &config)
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(path, "-c", "-9")
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("bzip2 failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// runBzip2Comparison benchmarks bzip2 decompression against gzip decompression
+// of the same original bytes. Only decompression is timed.
+func runBzip2Comparison(inputSizes []int, dataTypes []string, iterations int) *Bzip2Comparison {
+	comparison := &Bzip2Comparison{
+		Note:      "compress/bzip2 is decompression-only; input is compressed with the external bzip2 tool and only decompression is timed",
+		TestCases: []Bzip2ComparisonCase{},
+	}
+
+	for _, size := range inputSizes {
+		for _, dataType := range dataTypes {
+			fmt.Fprintf(os.Stderr, "Comparing bzip2 and gzip decompression, %s data, size: %d bytes...\n", dataType, size)
+
+			testCase := Bzip2ComparisonCase{
+				InputSize: size,
+				DataType:  dataType,
+			}
+
+			testData, err := generateTestData(size, dataType)
+			if err != nil {
+				errStr := err.Error()
+				testCase.Error = &errStr
+				comparison.TestCases = append(comparison.TestCases, testCase)
+				continue
+			}
+
+			bzip2Data, err := compressWithBzip2Tool(testData)
+			if err != nil {
+				errStr := err.Error()
+				testCase.Error = &errStr
+				comparison.TestCases = append(comparison.TestCases, testCase)
+				continue
+			}
+
+			gzipResult := compressData(testData, "gzip", gzip.DefaultCompression, nil)
+			if !gzipResult.Success {
+				testCase.Error = gzipResult.Error
+				comparison.TestCases = append(comparison.TestCases, testCase)
+				continue
+			}
+
+			testCase.Bzip2CompressedSize = len(bzip2Data)
+			testCase.GzipCompressedSize = len(gzipResult.compressed)
+
+			var bzip2Times, gzipTimes, bzip2Throughputs, gzipThroughputs []float64
+
+			for i := 0; i < iterations; i++ {
+				bzip2Result, err := decompressData(bzip2Data, "bzip2", nil)
+				if err == nil && !bytes.Equal(bzip2Result.decompressed, testData) {
+					err = fmt.Errorf("bzip2 decompressed data does not match original input")
+				}
+				if err != nil {
+					errStr := err.Error()
+					testCase.Error = &errStr
+					break
+				}
+
+				gzipDecompression, err := decompressData(gzipResult.compressed, "gzip", nil)
+				if err == nil && !bytes.Equal(gzipDecompression.decompressed, testData) {
+					err = fmt.Errorf("gzip decompressed data does not match original input")
+				}
+				if err != nil {
+					errStr := err.Error()
+					testCase.Error = &errStr
+					break
+				}
+
+				bzip2Times = append(bzip2Times, bzip2Result.DecompressionTime)
+				gzipTimes = append(gzipTimes, gzipDecompression.DecompressionTime)
+				if bzip2Result.ThroughputMbS != nil {
+					bzip2Throughputs = append(bzip2Throughputs, *bzip2Result.ThroughputMbS)
+				}
+				if gzipDecompression.ThroughputMbS != nil {
+					gzipThroughputs = append(gzipThroughputs, *gzipDecompression.ThroughputMbS)
+				}
+			}
+
+			testCase.AvgBzip2DecompressionTime = stats.Mean(bzip2Times)
+			testCase.AvgGzipDecompressionTime = stats.Mean(gzipTimes)
+			testCase.AvgBzip2Throughput = stats.Mean(bzip2Throughputs)
+			testCase.AvgGzipThroughput = stats.Mean(gzipThroughputs)
+
+			comparison.TestCases = append(comparison.TestCases, testCase)
+		}
+	}
+
+	return comparison
+}
+
+func main() {
+	opts, err := cli.Parse(filepath.Base(os.Args[0]), os.Args[1:])
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: Invalid JSON in config file: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+
+	var config Config
+	if err := opts.LoadConfig(&config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
 	results := runCompressionBenchmark(config.Parameters)
+	results.Name = config.Name
+	results.Tags = config.Parameters.Tags
 
-	output, err := json.MarshalIndent(results, "", "  ")
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: Failed to serialize results: %v\n", err)
+	if err := opts.WriteResults(results); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
-
-	fmt.Println(string(output))
 }
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"testing"
+)
+
+// TestParallelGzip compresses input spanning several blocks, the last one
+// partial, and checks that it is written as one gzip member per block and
+// that gzip.NewReader returns the whole input from the concatenation. Each
+// line is numbered, so members written out of order would not match.
+func TestParallelGzip(t *testing.T) {
+	var data []byte
+	for i := 0; i < 1000; i++ {
+		data = append(data, fmt.Sprintf("parallel gzip member line %d\n", i)...)
+	}
+	const blockSize = 4096
+
+	for _, workers := range []int{1, 3, 8} {
+		t.Run(fmt.Sprintf("workers=%d", workers), func(t *testing.T) {
+			var buf bytes.Buffer
+			writer := newParallelGzipWriter(&buf, 6, blockSize, workers)
+			if _, err := writer.Write(data); err != nil {
+				t.Fatal(err)
+			}
+			if err := writer.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			reader, err := gzip.NewReader(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			decompressed, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(decompressed, data) {
+				t.Errorf("decompressed %d bytes that differ from the %d-byte input", len(decompressed), len(data))
+			}
+
+			members := 0
+			source := bytes.NewReader(buf.Bytes())
+			reader, err = gzip.NewReader(source)
+			for err == nil {
+				reader.Multistream(false)
+				if _, err = io.Copy(io.Discard, reader); err != nil {
+					t.Fatal(err)
+				}
+				members++
+				err = reader.Reset(source)
+			}
+			if err != io.EOF {
+				t.Fatal(err)
+			}
+			if want := (len(data) + blockSize - 1) / blockSize; members != want {
+				t.Errorf("wrote %d gzip members, want one per block (%d)", members, want)
+			}
+		})
+	}
+}
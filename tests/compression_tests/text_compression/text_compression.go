@@ -1,30 +1,47 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
 	"compress/gzip"
+	"compress/lzw"
 	"compress/zlib"
-	"encoding/json"
+	"crypto/sha256"
 	"fmt"
 	"io"
+	"math"
 	"math/rand"
 	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/metrics"
 	"strings"
 	"time"
+	"unicode/utf8"
+
+	"benchmark_test/internal/cli"
+	"benchmark_test/internal/stats"
 )
 
 type CompressionResult struct {
-	Success         bool    `json:"success"`
-	CompressedSize  *int    `json:"compressed_size,omitempty"`
-	CompressionTime float64 `json:"compression_time"`
-	Error           *string `json:"error,omitempty"`
+	Success         bool     `json:"success"`
+	CompressedSize  *int     `json:"compressed_size,omitempty"`
+	CompressionTime float64  `json:"compression_time"`
+	ThroughputMbS   *float64 `json:"throughput_mb_s,omitempty"`
+	Error           *string  `json:"error,omitempty"`
+
+	compressed []byte
 }
 
 type DecompressionResult struct {
-	Success           bool    `json:"success"`
-	DecompressedSize  *int    `json:"decompressed_size,omitempty"`
-	DecompressionTime float64 `json:"decompression_time"`
-	Error             *string `json:"error,omitempty"`
+	Success           bool     `json:"success"`
+	DecompressedSize  *int     `json:"decompressed_size,omitempty"`
+	DecompressionTime float64  `json:"decompression_time"`
+	ThroughputMbS     *float64 `json:"throughput_mb_s,omitempty"`
+	RoundTripMatches  *bool    `json:"round_trip_matches,omitempty"`
+	Error             *string  `json:"error,omitempty"`
+
+	decompressed []byte
 }
 
 type IterationResult struct {
@@ -32,16 +49,27 @@ type IterationResult struct {
 	OriginalSize  int                  `json:"original_size"`
 	Compression   CompressionResult    `json:"compression"`
 	Decompression *DecompressionResult `json:"decompression,omitempty"`
+	PeakHeapBytes uint64               `json:"peak_heap_bytes"`
 }
 
 type TestCase struct {
-	InputSize            int               `json:"input_size"`
-	TextType             string            `json:"text_type"`
-	Algorithm            string            `json:"algorithm"`
-	Iterations           []IterationResult `json:"iterations"`
-	AvgCompressionRatio  float64           `json:"avg_compression_ratio"`
-	AvgCompressionTime   float64           `json:"avg_compression_time"`
-	AvgDecompressionTime float64           `json:"avg_decompression_time"`
+	InputSize                  int               `json:"input_size"`
+	TextType                   string            `json:"text_type"`
+	Algorithm                  string            `json:"algorithm"`
+	CompressionLevel           int               `json:"compression_level"`
+	Mode                       string            `json:"mode"`
+	StreamChunkSize            int               `json:"stream_chunk_size,omitempty"`
+	LzwOrder                   string            `json:"lzw_order,omitempty"`
+	LzwLiteralWidth            int               `json:"lzw_literal_width,omitempty"`
+	StreamingVerified          *bool             `json:"streaming_verified,omitempty"`
+	InputEntropy               float64           `json:"input_entropy"`
+	Iterations                 []IterationResult `json:"iterations"`
+	AvgCompressionRatio        float64           `json:"avg_compression_ratio"`
+	AvgCompressionTime         float64           `json:"avg_compression_time"`
+	AvgDecompressionTime       float64           `json:"avg_decompression_time"`
+	AvgCompressionThroughput   float64           `json:"avg_compression_throughput"`
+	AvgDecompressionThroughput float64           `json:"avg_decompression_throughput"`
+	AvgPeakHeapBytes           float64           `json:"avg_peak_heap_bytes"`
 }
 
 type AlgorithmPerformance struct {
@@ -51,32 +79,92 @@ type AlgorithmPerformance struct {
 }
 
 type Summary struct {
-	TotalTests             int                             `json:"total_tests"`
-	SuccessfulCompressions int                             `json:"successful_compressions"`
-	FailedCompressions     int                             `json:"failed_compressions"`
-	BestCompressionRatios  map[string]float64              `json:"best_compression_ratios"`
-	AlgorithmPerformance   map[string]AlgorithmPerformance `json:"algorithm_performance"`
+	TotalTests                 int                             `json:"total_tests"`
+	SuccessfulCompressions     int                             `json:"successful_compressions"`
+	FailedCompressions         int                             `json:"failed_compressions"`
+	AvgCompressionThroughput   float64                         `json:"avg_compression_throughput"`
+	AvgDecompressionTime       float64                         `json:"avg_decompression_time"`
+	AvgDecompressionThroughput float64                         `json:"avg_decompression_throughput"`
+	BestCompressionRatios      map[string]float64              `json:"best_compression_ratios"`
+	AlgorithmPerformance       map[string]AlgorithmPerformance `json:"algorithm_performance"`
+	AvgPeakHeapBytesByMode     map[string]float64              `json:"avg_peak_heap_bytes_by_mode"`
+	WarmupIterations           int                             `json:"warmup_iterations"`
 }
 
 type BenchmarkResults struct {
-	StartTime          float64    `json:"start_time"`
-	TestCases          []TestCase `json:"test_cases"`
-	Summary            Summary    `json:"summary"`
-	EndTime            *float64   `json:"end_time,omitempty"`
-	TotalExecutionTime *float64   `json:"total_execution_time,omitempty"`
+	SchemaVersion      string          `json:"schema_version"`
+	Environment        cli.Environment `json:"environment"`
+	Name               string          `json:"name,omitempty"`
+	Tags               []string        `json:"tags,omitempty"`
+	StartTime          float64         `json:"start_time"`
+	Seed               int64           `json:"seed"`
+	TestCases          []TestCase      `json:"test_cases"`
+	Summary            Summary         `json:"summary"`
+	EndTime            *float64        `json:"end_time,omitempty"`
+	TotalExecutionTime *float64        `json:"total_execution_time,omitempty"`
 }
 
 type Config struct {
 	Parameters Parameters `json:"parameters"`
+	Name       string     `json:"name"`
 }
 
 type Parameters struct {
 	InputSizes            []int    `json:"input_sizes"`
 	TextTypes             []string `json:"text_types"`
 	CompressionAlgorithms []string `json:"compression_algorithms"`
+	CompressionLevels     []int    `json:"compression_levels"`
+	Modes                 []string `json:"modes"`
+	StreamChunkSize       int      `json:"stream_chunk_size"`
 	Iterations            int      `json:"iterations"`
+	WarmupIterations      int      `json:"warmup_iterations"`
+	Seed                  *int64   `json:"seed,omitempty"`
+	Tags                  []string `json:"tags"`
+	// LzwOrder ("lsb", the default, or "msb") and LzwLiteralWidth (2 to
+	// 8 bits, default 8) configure the lzw algorithm. A literal width
+	// below 8 only fits input bytes below 1<<width.
+	LzwOrder        string `json:"lzw_order"`
+	LzwLiteralWidth int    `json:"lzw_literal_width"`
+}
+
+// Validate rejects negative and zero sizes; a zero iteration count or chunk
+// size selects the default.
+func (c *Config) Validate() error {
+	p := c.Parameters
+	return cli.FirstError(
+		cli.EachPositive("input_sizes", p.InputSizes),
+		cli.NonNegative("stream_chunk_size", p.StreamChunkSize),
+		cli.NonNegative("iterations", p.Iterations),
+		cli.NonNegative("warmup_iterations", p.WarmupIterations),
+		validLzwOrder(p.LzwOrder),
+		validLzwLiteralWidth(p.LzwLiteralWidth),
+	)
+}
+
+func validLzwOrder(order string) error {
+	switch order {
+	case "", "lsb", "msb":
+		return nil
+	}
+	return &cli.FieldError{Field: "lzw_order", Message: fmt.Sprintf("unknown order %q (expected lsb or msb)", order)}
+}
+
+// validLzwLiteralWidth allows 0 for the default and the widths compress/lzw
+// accepts.
+func validLzwLiteralWidth(width int) error {
+	if width == 0 {
+		return nil
+	}
+	return cli.InRange("lzw_literal_width", width, 2, 8)
 }
 
+// lzwOrder and lzwLiteralWidth are the lzw algorithm's settings, set from
+// the parameters at the start of a run.
+var (
+	lzwOrder        = lzw.LSB
+	lzwLiteralWidth = 8
+)
+
 func safeTruncate(s string, byteLimit int) string {
 	if len(s) <= byteLimit {
 		return s
@@ -89,93 +177,181 @@ func safeTruncate(s string, byteLimit int) string {
 	return s[:end]
 }
 
+// rng generates the input text; see parameters.seed.
+var rng *rand.Rand
+
 func generateTextData(size int, textType string) (string, error) {
-	// Seeding is now handled automatically in Go 1.20+
-	// rand.Seed(time.Now().UnixNano()) // This is deprecated and not needed
+	var sb strings.Builder
+	sb.Grow(size)
+	if _, err := writeTextData(&sb, size, textType); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// textWriter is what the generator writes to: a strings.Builder for the
+// buffered mode, or a bufio.Writer in front of the compressor when streaming.
+type textWriter interface {
+	io.StringWriter
+	io.ByteWriter
+	WriteRune(r rune) (int, error)
+}
+
+// textSink forwards generated text to a textWriter until limit bytes have
+// been written and drops the rest. A piece that does not fit is cut at a rune
+// boundary, so the output equals safeTruncate of the unlimited text.
+type textSink struct {
+	w         textWriter
+	remaining int
+	written   int
+	err       error
+}
+
+func (s *textSink) full() bool {
+	return s.remaining <= 0 || s.err != nil
+}
+
+func (s *textSink) writeString(piece string) {
+	if s.full() {
+		return
+	}
+	if len(piece) >= s.remaining {
+		piece = safeTruncate(piece, s.remaining)
+		s.remaining = 0
+	} else {
+		s.remaining -= len(piece)
+	}
+	s.written += len(piece)
+	_, s.err = s.w.WriteString(piece)
+}
+
+func (s *textSink) writeByte(b byte) {
+	if s.full() {
+		return
+	}
+	s.remaining--
+	s.written++
+	s.err = s.w.WriteByte(b)
+}
+
+func (s *textSink) writeRune(r rune) {
+	if s.full() {
+		return
+	}
+	n := utf8.RuneLen(r)
+	if n > s.remaining {
+		s.remaining = 0
+		return
+	}
+	s.remaining -= n
+	s.written += n
+	_, s.err = s.w.WriteRune(r)
+}
+
+// writeTextData writes up to size bytes of textType text to w and returns how
+// many it wrote. The generator keeps drawing from rng after the limit is
+// reached exactly as it always has, so a seed produces the same text whether
+// it is buffered or streamed.
+func writeTextData(w textWriter, size int, textType string) (int, error) {
+	text := &textSink{w: w, remaining: size}
 
 	switch textType {
 	case "ascii":
 		chars := "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789 \n"
-		var sb strings.Builder
-		sb.Grow(size)
 		for i := 0; i < size; i++ {
-			sb.WriteByte(chars[rand.Intn(len(chars))])
+			text.writeByte(chars[rng.Intn(len(chars))])
 		}
-		return sb.String(), nil
 
 	case "unicode":
 		chars := "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789àáâãäåæçèéêëìíîïñòóôõöøùúûüý你好世界🌟🚀📊 \n"
 		runes := []rune(chars)
-		var sb strings.Builder
-		sb.Grow(size)
-		for sb.Len() < size {
-			sb.WriteRune(runes[rand.Intn(len(runes))])
+		for !text.full() {
+			text.writeRune(runes[rng.Intn(len(runes))])
 		}
-		return safeTruncate(sb.String(), size), nil
 
 	case "code":
 		keywords := []string{"package", "func", "var", "if", "else", "for", "range", "return", "struct", "interface"}
 		operators := []string{"=", "+", "-", "*", "/", "(", ")", "{", "}", "[", "]", ";", ":"}
-		var text strings.Builder
-		text.Grow(size)
-		for text.Len() < size {
-			if rand.Float64() < 0.3 {
-				text.WriteString(keywords[rand.Intn(len(keywords))])
+		for !text.full() {
+			if rng.Float64() < 0.3 {
+				text.writeString(keywords[rng.Intn(len(keywords))])
 			} else {
-				wordLen := rand.Intn(8) + 3
+				wordLen := rng.Intn(8) + 3
 				for i := 0; i < wordLen; i++ {
-					text.WriteByte(byte('a' + rand.Intn(26)))
+					text.writeByte(byte('a' + rng.Intn(26)))
 				}
 			}
 
-			if rand.Float64() < 0.2 {
-				text.WriteString(operators[rand.Intn(len(operators))])
+			if rng.Float64() < 0.2 {
+				text.writeString(operators[rng.Intn(len(operators))])
 			}
 
-			if rand.Float64() < 0.1 {
-				text.WriteString("\n")
+			if rng.Float64() < 0.1 {
+				text.writeString("\n")
 			} else {
-				text.WriteString(" ")
+				text.writeString(" ")
 			}
 		}
 
-		return safeTruncate(text.String(), size), nil
-
 	case "natural_language":
 		words := []string{"the", "quick", "brown", "fox", "jumps", "over", "lazy", "dog", "and", "runs", "through",
 			"forest", "meadow", "river", "mountain", "valley", "beautiful", "magnificent", "wonderful"}
-		var text strings.Builder
-		text.Grow(size)
-		for text.Len() < size {
-			text.WriteString(words[rand.Intn(len(words))])
-
-			if rand.Float64() < 0.1 {
-				text.WriteString(". ")
-			} else if rand.Float64() < 0.05 {
-				text.WriteString(", ")
+		for !text.full() {
+			text.writeString(words[rng.Intn(len(words))])
+
+			if rng.Float64() < 0.1 {
+				text.writeString(". ")
+			} else if rng.Float64() < 0.05 {
+				text.writeString(", ")
 			} else {
-				text.WriteString(" ")
+				text.writeString(" ")
 			}
 
-			if rand.Float64() < 0.05 {
-				text.WriteString("\n")
+			if rng.Float64() < 0.05 {
+				text.writeString("\n")
 			}
 		}
 
-		return safeTruncate(text.String(), size), nil
-
 	default:
-		return "", fmt.Errorf("unknown text type: %s", textType)
+		return 0, fmt.Errorf("unknown text type: %s", textType)
+	}
+
+	return text.written, text.err
+}
+
+// validateCompressionLevel checks that level is accepted by both gzip and zlib,
+// i.e. within [HuffmanOnly, BestCompression].
+func validateCompressionLevel(level int) error {
+	if level < gzip.HuffmanOnly || level > gzip.BestCompression {
+		return fmt.Errorf("invalid compression level %d: must be between %d and %d", level, gzip.HuffmanOnly, gzip.BestCompression)
 	}
+	return nil
 }
 
-func compressWithGzip(data []byte) CompressionResult {
+func compressWithGzip(data []byte, level int) CompressionResult {
 	start := time.Now()
 
+	if err := validateCompressionLevel(level); err != nil {
+		errStr := err.Error()
+		return CompressionResult{
+			Success:         false,
+			CompressionTime: float64(time.Since(start).Nanoseconds()) / 1e6,
+			Error:           &errStr,
+		}
+	}
+
 	var buf bytes.Buffer
-	writer := gzip.NewWriter(&buf)
+	writer, err := gzip.NewWriterLevel(&buf, level)
+	if err != nil {
+		errStr := err.Error()
+		return CompressionResult{
+			Success:         false,
+			CompressionTime: float64(time.Since(start).Nanoseconds()) / 1e6,
+			Error:           &errStr,
+		}
+	}
 
-	_, err := writer.Write(data)
+	_, err = writer.Write(data)
 	if err != nil {
 		errStr := err.Error()
 		return CompressionResult{
@@ -197,21 +373,41 @@ func compressWithGzip(data []byte) CompressionResult {
 
 	compressed := buf.Bytes()
 	compressedSize := len(compressed)
+	compressionTime := float64(time.Since(start).Nanoseconds()) / 1e6
 
 	return CompressionResult{
 		Success:         true,
 		CompressedSize:  &compressedSize,
-		CompressionTime: float64(time.Since(start).Nanoseconds()) / 1e6,
+		CompressionTime: compressionTime,
+		ThroughputMbS:   throughputMbS(len(data), compressionTime),
+		compressed:      compressed,
 	}
 }
 
-func compressWithZlib(data []byte) CompressionResult {
+func compressWithZlib(data []byte, level int) CompressionResult {
 	start := time.Now()
 
+	if err := validateCompressionLevel(level); err != nil {
+		errStr := err.Error()
+		return CompressionResult{
+			Success:         false,
+			CompressionTime: float64(time.Since(start).Nanoseconds()) / 1e6,
+			Error:           &errStr,
+		}
+	}
+
 	var buf bytes.Buffer
-	writer := zlib.NewWriter(&buf)
+	writer, err := zlib.NewWriterLevel(&buf, level)
+	if err != nil {
+		errStr := err.Error()
+		return CompressionResult{
+			Success:         false,
+			CompressionTime: float64(time.Since(start).Nanoseconds()) / 1e6,
+			Error:           &errStr,
+		}
+	}
 
-	_, err := writer.Write(data)
+	_, err = writer.Write(data)
 	if err != nil {
 		errStr := err.Error()
 		return CompressionResult{
@@ -233,14 +429,28 @@ func compressWithZlib(data []byte) CompressionResult {
 
 	compressed := buf.Bytes()
 	compressedSize := len(compressed)
+	compressionTime := float64(time.Since(start).Nanoseconds()) / 1e6
 
 	return CompressionResult{
 		Success:         true,
 		CompressedSize:  &compressedSize,
-		CompressionTime: float64(time.Since(start).Nanoseconds()) / 1e6,
+		CompressionTime: compressionTime,
+		ThroughputMbS:   throughputMbS(len(data), compressionTime),
+		compressed:      compressed,
 	}
 }
 
+// throughputMbS converts a byte count processed in timeMs milliseconds into
+// MB/s. It returns nil when the elapsed time is too small to measure, so tiny
+// inputs never report an infinite throughput.
+func throughputMbS(size int, timeMs float64) *float64 {
+	if timeMs <= 0 {
+		return nil
+	}
+	throughput := float64(size) / (timeMs / 1000.0) / (1024.0 * 1024.0)
+	return &throughput
+}
+
 func decompressGzip(data []byte) DecompressionResult {
 	start := time.Now()
 
@@ -266,11 +476,14 @@ func decompressGzip(data []byte) DecompressionResult {
 	}
 
 	decompressedSize := len(decompressed)
+	decompressionTime := float64(time.Since(start).Nanoseconds()) / 1e6
 
 	return DecompressionResult{
 		Success:           true,
 		DecompressedSize:  &decompressedSize,
-		DecompressionTime: float64(time.Since(start).Nanoseconds()) / 1e6,
+		DecompressionTime: decompressionTime,
+		ThroughputMbS:     throughputMbS(decompressedSize, decompressionTime),
+		decompressed:      decompressed,
 	}
 }
 
@@ -299,11 +512,339 @@ func decompressZlib(data []byte) DecompressionResult {
 	}
 
 	decompressedSize := len(decompressed)
+	decompressionTime := float64(time.Since(start).Nanoseconds()) / 1e6
 
 	return DecompressionResult{
 		Success:           true,
 		DecompressedSize:  &decompressedSize,
-		DecompressionTime: float64(time.Since(start).Nanoseconds()) / 1e6,
+		DecompressionTime: decompressionTime,
+		ThroughputMbS:     throughputMbS(decompressedSize, decompressionTime),
+		decompressed:      decompressed,
+	}
+}
+
+// compressWithLzw compresses data with compress/lzw at the configured
+// order and literal width. LZW has no compression levels.
+func compressWithLzw(data []byte) CompressionResult {
+	start := time.Now()
+	fail := func(err error) CompressionResult {
+		errStr := err.Error()
+		return CompressionResult{
+			Success:         false,
+			CompressionTime: float64(time.Since(start).Nanoseconds()) / 1e6,
+			Error:           &errStr,
+		}
+	}
+
+	var buf bytes.Buffer
+	writer := lzw.NewWriter(&buf, lzwOrder, lzwLiteralWidth)
+	if _, err := writer.Write(data); err != nil {
+		writer.Close()
+		return fail(err)
+	}
+	if err := writer.Close(); err != nil {
+		return fail(err)
+	}
+
+	compressed := buf.Bytes()
+	compressedSize := len(compressed)
+	compressionTime := float64(time.Since(start).Nanoseconds()) / 1e6
+
+	return CompressionResult{
+		Success:         true,
+		CompressedSize:  &compressedSize,
+		CompressionTime: compressionTime,
+		ThroughputMbS:   throughputMbS(len(data), compressionTime),
+		compressed:      compressed,
+	}
+}
+
+func decompressLzw(data []byte) DecompressionResult {
+	start := time.Now()
+
+	reader := lzw.NewReader(bytes.NewReader(data), lzwOrder, lzwLiteralWidth)
+	defer reader.Close()
+
+	decompressed, err := io.ReadAll(reader)
+	if err != nil {
+		errStr := err.Error()
+		return DecompressionResult{
+			Success:           false,
+			DecompressionTime: float64(time.Since(start).Nanoseconds()) / 1e6,
+			Error:             &errStr,
+		}
+	}
+
+	decompressedSize := len(decompressed)
+	decompressionTime := float64(time.Since(start).Nanoseconds()) / 1e6
+
+	return DecompressionResult{
+		Success:           true,
+		DecompressedSize:  &decompressedSize,
+		DecompressionTime: decompressionTime,
+		ThroughputMbS:     throughputMbS(decompressedSize, decompressionTime),
+		decompressed:      decompressed,
+	}
+}
+
+const (
+	modeBuffered  = "buffered"
+	modeStreaming = "streaming"
+
+	defaultStreamChunkSize = 64 * 1024
+)
+
+func newCompressor(algorithm string, w io.Writer, level int) (io.WriteCloser, error) {
+	switch algorithm {
+	case "gzip":
+		return gzip.NewWriterLevel(w, level)
+	case "zlib":
+		return zlib.NewWriterLevel(w, level)
+	case "lzw":
+		return lzw.NewWriter(w, lzwOrder, lzwLiteralWidth), nil
+	default:
+		return nil, fmt.Errorf("unknown algorithm: %s", algorithm)
+	}
+}
+
+func newDecompressor(algorithm string, r io.Reader) (io.ReadCloser, error) {
+	switch algorithm {
+	case "gzip":
+		return gzip.NewReader(r)
+	case "zlib":
+		return zlib.NewReader(r)
+	case "lzw":
+		return lzw.NewReader(r, lzwOrder, lzwLiteralWidth), nil
+	default:
+		return nil, fmt.Errorf("unknown algorithm: %s", algorithm)
+	}
+}
+
+// countingWriter discards what is written to it and keeps the byte count.
+type countingWriter struct {
+	n int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += len(p)
+	return len(p), nil
+}
+
+// streamCompress generates the input text through a chunkSize buffer straight
+// into the compressor writing to w and returns the uncompressed size. Memory
+// use is one chunk plus the compressor's window, independent of size.
+func streamCompress(w io.Writer, size int, textType, algorithm string, level, chunkSize int) (int, error) {
+	if err := validateCompressionLevel(level); err != nil {
+		return 0, err
+	}
+	compressor, err := newCompressor(algorithm, w, level)
+	if err != nil {
+		return 0, err
+	}
+	chunks := bufio.NewWriterSize(compressor, chunkSize)
+	written, err := writeTextData(chunks, size, textType)
+	if err != nil {
+		return written, err
+	}
+	if err := chunks.Flush(); err != nil {
+		return written, err
+	}
+	return written, compressor.Close()
+}
+
+// compressStreaming times streamCompress into a byte counter. Unlike the
+// buffered mode, the time includes generating the text, since the two are
+// interleaved, and there is no compressed output left to decompress.
+func compressStreaming(size int, textType, algorithm string, level, chunkSize int) (CompressionResult, int) {
+	start := time.Now()
+
+	var out countingWriter
+	originalSize, err := streamCompress(&out, size, textType, algorithm, level, chunkSize)
+	if err != nil {
+		errStr := err.Error()
+		return CompressionResult{
+			Success:         false,
+			CompressionTime: float64(time.Since(start).Nanoseconds()) / 1e6,
+			Error:           &errStr,
+		}, originalSize
+	}
+
+	compressedSize := out.n
+	compressionTime := float64(time.Since(start).Nanoseconds()) / 1e6
+
+	return CompressionResult{
+		Success:         true,
+		CompressedSize:  &compressedSize,
+		CompressionTime: compressionTime,
+		ThroughputMbS:   throughputMbS(originalSize, compressionTime),
+	}, originalSize
+}
+
+// verifyStreaming checks that streaming with seed decompresses to exactly the
+// text the buffered mode generates from the same seed. The streamed side is
+// decompressed through a pipe and hashed, so it never holds the whole text.
+// The shared rng is restored afterwards, leaving the measured runs unchanged.
+func verifyStreaming(seed int64, size int, textType, algorithm string, level, chunkSize int) (bool, error) {
+	saved := rng
+	defer func() { rng = saved }()
+
+	rng = rand.New(rand.NewSource(seed))
+	textData, err := generateTextData(size, textType)
+	if err != nil {
+		return false, err
+	}
+	want := sha256.Sum256([]byte(textData))
+
+	rng = rand.New(rand.NewSource(seed))
+	pr, pw := io.Pipe()
+	go func() {
+		_, err := streamCompress(pw, size, textType, algorithm, level, chunkSize)
+		pw.CloseWithError(err)
+	}()
+
+	reader, err := newDecompressor(algorithm, pr)
+	if err != nil {
+		pr.CloseWithError(err)
+		return false, err
+	}
+	defer reader.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, reader); err != nil {
+		pr.CloseWithError(err)
+		return false, err
+	}
+	return bytes.Equal(hash.Sum(nil), want[:]), nil
+}
+
+// byteHistogram is a textWriter that only counts how often each byte value
+// is written.
+type byteHistogram struct {
+	counts [256]int
+	total  int
+}
+
+func (h *byteHistogram) WriteString(s string) (int, error) {
+	for i := 0; i < len(s); i++ {
+		h.counts[s[i]]++
+	}
+	h.total += len(s)
+	return len(s), nil
+}
+
+func (h *byteHistogram) WriteByte(b byte) error {
+	h.counts[b]++
+	h.total++
+	return nil
+}
+
+func (h *byteHistogram) WriteRune(r rune) (int, error) {
+	return h.WriteString(string(r))
+}
+
+// entropy returns the Shannon entropy of the counted bytes in bits per
+// byte: 0 when a single value repeats, 8 when all 256 are equally likely.
+func (h *byteHistogram) entropy() float64 {
+	bits := 0.0
+	for _, count := range h.counts {
+		if count > 0 {
+			p := float64(count) / float64(h.total)
+			bits -= p * math.Log2(p)
+		}
+	}
+	return bits
+}
+
+// inputEntropy measures the byte entropy of the text generated from seed,
+// the same text verifyStreaming checks and the first buffered iteration
+// compresses. Later iterations draw different text of the same kind, whose
+// entropy differs only by sampling noise. The shared rng is restored
+// afterwards.
+func inputEntropy(seed int64, size int, textType string) (float64, error) {
+	saved := rng
+	defer func() { rng = saved }()
+
+	rng = rand.New(rand.NewSource(seed))
+	var histogram byteHistogram
+	if _, err := writeTextData(&histogram, size, textType); err != nil {
+		return 0, err
+	}
+	return histogram.entropy(), nil
+}
+
+// peakHeapSampleInterval is how often peakHeapDuring samples the heap.
+const peakHeapSampleInterval = time.Millisecond
+
+// peakHeapDuring runs fn and returns the highest heap object size seen while
+// it ran, above the level measured just before. Sampling can miss short
+// spikes, so the result is a lower bound on the true peak.
+func peakHeapDuring(fn func()) uint64 {
+	sample := []metrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}}
+	heapBytes := func() uint64 {
+		metrics.Read(sample)
+		return sample[0].Value.Uint64()
+	}
+
+	runtime.GC()
+	base := heapBytes()
+
+	var peak uint64
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(peakHeapSampleInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if current := heapBytes(); current > peak {
+					peak = current
+				}
+			}
+		}
+	}()
+
+	fn()
+	close(stop)
+	<-stopped
+
+	if current := heapBytes(); current > peak {
+		peak = current
+	}
+	if peak < base {
+		return 0
+	}
+	return peak - base
+}
+
+// warmupCompression runs one untimed compress/decompress roundtrip so that
+// the first measured iteration does not pay for allocating compressor state.
+func warmupCompression(size int, textType string, algorithm string, level int, mode string, chunkSize int) {
+	if mode == modeStreaming {
+		compressStreaming(size, textType, algorithm, level, chunkSize)
+		return
+	}
+
+	textData, err := generateTextData(size, textType)
+	if err != nil {
+		return
+	}
+	switch algorithm {
+	case "gzip":
+		if result := compressWithGzip([]byte(textData), level); result.Success {
+			decompressGzip(result.compressed)
+		}
+	case "zlib":
+		if result := compressWithZlib([]byte(textData), level); result.Success {
+			decompressZlib(result.compressed)
+		}
+	case "lzw":
+		if result := compressWithLzw([]byte(textData)); result.Success {
+			decompressLzw(result.compressed)
+		}
 	}
 }
 
@@ -323,112 +864,251 @@ func runTextCompressionBenchmark(config Parameters) (BenchmarkResults, error) {
 		algorithms = []string{"gzip"}
 	}
 
+	compressionLevels := config.CompressionLevels
+	if len(compressionLevels) == 0 {
+		compressionLevels = []int{gzip.DefaultCompression}
+	}
+
 	iterations := config.Iterations
 	if iterations == 0 {
 		iterations = 3
 	}
 
+	modes := config.Modes
+	if len(modes) == 0 {
+		modes = []string{modeBuffered}
+	}
+	for _, mode := range modes {
+		if mode != modeBuffered && mode != modeStreaming {
+			return BenchmarkResults{}, fmt.Errorf("unknown mode: %s (expected %s or %s)", mode, modeBuffered, modeStreaming)
+		}
+	}
+
+	chunkSize := config.StreamChunkSize
+	if chunkSize <= 0 {
+		chunkSize = defaultStreamChunkSize
+	}
+
+	lzwOrder, lzwLiteralWidth = lzw.LSB, 8
+	if config.LzwOrder == "msb" {
+		lzwOrder = lzw.MSB
+	}
+	if config.LzwLiteralWidth > 0 {
+		lzwLiteralWidth = config.LzwLiteralWidth
+	}
+
+	seed := cli.Seed(config.Seed)
+	rng = rand.New(rand.NewSource(seed))
+
 	results := BenchmarkResults{
-		StartTime: float64(time.Now().Unix()),
-		TestCases: []TestCase{},
+		SchemaVersion: cli.SchemaVersion,
+		Environment:   cli.CurrentEnvironment(),
+		StartTime:     float64(time.Now().Unix()),
+		Seed:          seed,
+		TestCases:     []TestCase{},
 		Summary: Summary{
-			BestCompressionRatios: make(map[string]float64),
-			AlgorithmPerformance:  make(map[string]AlgorithmPerformance),
+			BestCompressionRatios:  make(map[string]float64),
+			AlgorithmPerformance:   make(map[string]AlgorithmPerformance),
+			AvgPeakHeapBytesByMode: make(map[string]float64),
+			WarmupIterations:       config.WarmupIterations,
 		},
 	}
 
 	algorithmStats := make(map[string][]float64)
+	var totalCompressionThroughputs []float64
+	var totalDecompressionThroughputs []float64
+	var totalDecompressionTimes []float64
+	peakHeapByMode := make(map[string][]float64)
 
 	for _, size := range inputSizes {
 		for _, textType := range textTypes {
 			for _, algorithm := range algorithms {
-				fmt.Fprintf(os.Stderr, "Testing %s text, size: %d, algorithm: %s...\n", textType, size, algorithm)
-
-				testCase := TestCase{
-					InputSize:  size,
-					TextType:   textType,
-					Algorithm:  algorithm,
-					Iterations: []IterationResult{},
-				}
-
-				var compressionRatios []float64
-				var compressionTimes []float64
-				var decompressionTimes []float64
-
-				for i := 0; i < iterations; i++ {
-					fmt.Fprintf(os.Stderr, "  Iteration %d/%d...\n", i+1, iterations)
-
-					textData, err := generateTextData(size, textType)
-					if err != nil {
-						return results, err
-					}
-
-					dataBytes := []byte(textData)
-					originalSize := len(dataBytes)
-
-					var compressResult CompressionResult
-
-					switch algorithm {
-					case "gzip":
-						compressResult = compressWithGzip(dataBytes)
-					case "zlib":
-						compressResult = compressWithZlib(dataBytes)
-					default:
-						fmt.Fprintf(os.Stderr, "Warning: Algorithm %s not implemented, skipping\n", algorithm)
+				for levelIndex, level := range compressionLevels {
+					// LZW has no levels, so it runs once, under the first level.
+					if algorithm == "lzw" && levelIndex > 0 {
 						continue
 					}
+					for _, mode := range modes {
+						fmt.Fprintf(os.Stderr, "Testing %s text, size: %d, algorithm: %s, level: %d, mode: %s...\n", textType, size, algorithm, level, mode)
+
+						testCase := TestCase{
+							InputSize:        size,
+							TextType:         textType,
+							Algorithm:        algorithm,
+							CompressionLevel: level,
+							Mode:             mode,
+							Iterations:       []IterationResult{},
+						}
+						if algorithm == "lzw" {
+							testCase.LzwOrder = "lsb"
+							if lzwOrder == lzw.MSB {
+								testCase.LzwOrder = "msb"
+							}
+							testCase.LzwLiteralWidth = lzwLiteralWidth
+						}
 
-					iterationResult := IterationResult{
-						Iteration:    i + 1,
-						OriginalSize: originalSize,
-						Compression:  compressResult,
-					}
-
-					results.Summary.TotalTests++
+						entropy, err := inputEntropy(seed, size, textType)
+						if err != nil {
+							return results, err
+						}
+						testCase.InputEntropy = entropy
+
+						if mode == modeStreaming {
+							testCase.StreamChunkSize = chunkSize
+							verified, err := verifyStreaming(seed, size, textType, algorithm, level, chunkSize)
+							if err != nil {
+								fmt.Fprintf(os.Stderr, "  Warning: could not verify streamed output: %v\n", err)
+							} else {
+								testCase.StreamingVerified = &verified
+							}
+						}
 
-					if compressResult.Success && compressResult.CompressedSize != nil {
-						results.Summary.SuccessfulCompressions++
+						var compressionRatios []float64
+						var compressionTimes []float64
+						var decompressionTimes []float64
+						var compressionThroughputs []float64
+						var decompressionThroughputs []float64
+						var peakHeaps []float64
 
-						compressedSize := *compressResult.CompressedSize
-						var compressionRatio float64
-						if compressedSize > 0 {
-							compressionRatio = float64(originalSize) / float64(compressedSize)
+						for i := 0; i < config.WarmupIterations; i++ {
+							fmt.Fprintf(os.Stderr, "  Warmup %d/%d...\n", i+1, config.WarmupIterations)
+							warmupCompression(size, textType, algorithm, level, mode, chunkSize)
 						}
 
-						compressionRatios = append(compressionRatios, compressionRatio)
-						compressionTimes = append(compressionTimes, compressResult.CompressionTime)
+						for i := 0; i < iterations; i++ {
+							fmt.Fprintf(os.Stderr, "  Iteration %d/%d...\n", i+1, iterations)
+
+							var compressResult CompressionResult
+							var originalSize int
+							var original []byte
+							var generateErr error
+
+							peakHeap := peakHeapDuring(func() {
+								if mode == modeStreaming {
+									compressResult, originalSize = compressStreaming(size, textType, algorithm, level, chunkSize)
+									return
+								}
+
+								textData, err := generateTextData(size, textType)
+								if err != nil {
+									generateErr = err
+									return
+								}
+
+								dataBytes := []byte(textData)
+								originalSize = len(dataBytes)
+								original = dataBytes
+
+								switch algorithm {
+								case "gzip":
+									compressResult = compressWithGzip(dataBytes, level)
+								case "zlib":
+									compressResult = compressWithZlib(dataBytes, level)
+								case "lzw":
+									compressResult = compressWithLzw(dataBytes)
+								}
+							})
+							if generateErr != nil {
+								return results, generateErr
+							}
+							if algorithm != "gzip" && algorithm != "zlib" && algorithm != "lzw" {
+								fmt.Fprintf(os.Stderr, "Warning: Algorithm %s not implemented, skipping\n", algorithm)
+								continue
+							}
+
+							iterationResult := IterationResult{
+								Iteration:     i + 1,
+								OriginalSize:  originalSize,
+								Compression:   compressResult,
+								PeakHeapBytes: peakHeap,
+							}
+							peakHeaps = append(peakHeaps, float64(peakHeap))
+
+							results.Summary.TotalTests++
+
+							if compressResult.Success && compressResult.CompressedSize != nil {
+								results.Summary.SuccessfulCompressions++
+
+								compressedSize := *compressResult.CompressedSize
+								var compressionRatio float64
+								if compressedSize > 0 {
+									compressionRatio = float64(originalSize) / float64(compressedSize)
+								}
+
+								compressionRatios = append(compressionRatios, compressionRatio)
+								compressionTimes = append(compressionTimes, compressResult.CompressionTime)
+								if compressResult.ThroughputMbS != nil {
+									compressionThroughputs = append(compressionThroughputs, *compressResult.ThroughputMbS)
+								}
+
+								algorithmStats[algorithm] = append(algorithmStats[algorithm], compressionRatio)
+
+								// Streaming keeps no compressed output around; its
+								// round trip is checked once by verifyStreaming.
+								if mode == modeBuffered {
+									var decompressResult DecompressionResult
+									switch algorithm {
+									case "zlib":
+										decompressResult = decompressZlib(compressResult.compressed)
+									case "lzw":
+										decompressResult = decompressLzw(compressResult.compressed)
+									default:
+										decompressResult = decompressGzip(compressResult.compressed)
+									}
+									if decompressResult.Success {
+										matches := bytes.Equal(decompressResult.decompressed, original)
+										decompressResult.RoundTripMatches = &matches
+										if !matches {
+											errStr := "decompressed data differs from the input"
+											decompressResult.Success = false
+											decompressResult.Error = &errStr
+										}
+									}
+									iterationResult.Decompression = &decompressResult
+
+									if decompressResult.Success {
+										decompressionTimes = append(decompressionTimes, decompressResult.DecompressionTime)
+										if decompressResult.ThroughputMbS != nil {
+											decompressionThroughputs = append(decompressionThroughputs, *decompressResult.ThroughputMbS)
+										}
+									}
+								}
+							} else {
+								results.Summary.FailedCompressions++
+							}
+
+							testCase.Iterations = append(testCase.Iterations, iterationResult)
+						}
 
-						algorithmStats[algorithm] = append(algorithmStats[algorithm], compressionRatio)
-					} else {
-						results.Summary.FailedCompressions++
-					}
+						if len(compressionRatios) > 0 {
+							sum := 0.0
+							for _, ratio := range compressionRatios {
+								sum += ratio
+							}
+							testCase.AvgCompressionRatio = sum / float64(len(compressionRatios))
 
-					testCase.Iterations = append(testCase.Iterations, iterationResult)
-				}
+							sum = 0.0
+							for _, time := range compressionTimes {
+								sum += time
+							}
+							testCase.AvgCompressionTime = sum / float64(len(compressionTimes))
 
-				if len(compressionRatios) > 0 {
-					sum := 0.0
-					for _, ratio := range compressionRatios {
-						sum += ratio
-					}
-					testCase.AvgCompressionRatio = sum / float64(len(compressionRatios))
+							testCase.AvgDecompressionTime = stats.Mean(decompressionTimes)
 
-					sum = 0.0
-					for _, time := range compressionTimes {
-						sum += time
-					}
-					testCase.AvgCompressionTime = sum / float64(len(compressionTimes))
+							testCase.AvgCompressionThroughput = stats.Mean(compressionThroughputs)
+							testCase.AvgDecompressionThroughput = stats.Mean(decompressionThroughputs)
 
-					if len(decompressionTimes) > 0 {
-						sum = 0.0
-						for _, time := range decompressionTimes {
-							sum += time
+							totalCompressionThroughputs = append(totalCompressionThroughputs, compressionThroughputs...)
+							totalDecompressionThroughputs = append(totalDecompressionThroughputs, decompressionThroughputs...)
+							totalDecompressionTimes = append(totalDecompressionTimes, decompressionTimes...)
 						}
-						testCase.AvgDecompressionTime = sum / float64(len(decompressionTimes))
+
+						testCase.AvgPeakHeapBytes = stats.Mean(peakHeaps)
+						peakHeapByMode[mode] = append(peakHeapByMode[mode], peakHeaps...)
+
+						results.TestCases = append(results.TestCases, testCase)
 					}
 				}
-
-				results.TestCases = append(results.TestCases, testCase)
 			}
 		}
 	}
@@ -458,6 +1138,13 @@ func runTextCompressionBenchmark(config Parameters) (BenchmarkResults, error) {
 		}
 	}
 
+	results.Summary.AvgCompressionThroughput = stats.Mean(totalCompressionThroughputs)
+	results.Summary.AvgDecompressionTime = stats.Mean(totalDecompressionTimes)
+	results.Summary.AvgDecompressionThroughput = stats.Mean(totalDecompressionThroughputs)
+	for mode, peaks := range peakHeapByMode {
+		results.Summary.AvgPeakHeapBytesByMode[mode] = stats.Mean(peaks)
+	}
+
 	endTime := float64(time.Now().Unix())
 	results.EndTime = &endTime
 	totalTime := endTime - results.StartTime
@@ -467,23 +1154,15 @@ func runTextCompressionBenchmark(config Parameters) (BenchmarkResults, error) {
 }
 
 func main() {
-	if len(os.Args) < 2 {
-		fmt.Fprintf(os.Stderr, "Usage: %s <config_file>\n", os.Args[0])
-		os.Exit(1)
-	}
-
-	configFile := os.Args[1]
-
-	configData, err := os.ReadFile(configFile)
+	opts, err := cli.Parse(filepath.Base(os.Args[0]), os.Args[1:])
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: Config file '%s' not found\n", configFile)
-		os.Exit(1)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
 	}
 
 	var config Config
-	err = json.Unmarshal(configData, &config)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: Invalid JSON in config file: %v\n", err)
+	if err := opts.LoadConfig(&config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
@@ -492,12 +1171,11 @@ func main() {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
+	results.Name = config.Name
+	results.Tags = config.Parameters.Tags
 
-	output, err := json.MarshalIndent(results, "", "  ")
-	if err != nil {
+	if err := opts.WriteResults(results); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
-
-	fmt.Println(string(output))
 }
@@ -4,11 +4,57 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"math"
 	"math/rand"
+	"os"
+	"sort"
 	"time"
 )
 
+// Config is the optional config file. TreeType selects the tree under test
+// ("bst", the default, "red_black" or "btree", a B-tree with Order children
+// per node at most) and InsertOrder the order the values are inserted in
+// ("random" or "sorted"). SerializeSizes are the BST sizes the
+// serialize/deserialize round trip is timed for (nodes_count by default),
+// each averaged over SerializeIterations runs. SuccessorQueries is the
+// number of Min, Max and Successor calls timed.
+type Config struct {
+	Parameters struct {
+		NodesCount          int    `json:"nodes_count"`
+		TreeType            string `json:"tree_type"`
+		InsertOrder         string `json:"insert_order"`
+		SerializeSizes      []int  `json:"serialize_sizes"`
+		SerializeIterations int    `json:"serialize_iterations"`
+		SuccessorQueries    int    `json:"successor_queries"`
+		Order               int    `json:"order"`
+	} `json:"parameters"`
+}
+
+// Tree is the interface the benchmark drives. Rotations counts the
+// rebalancing rotations done so far; a plain BST never rotates. Min, Max
+// and Successor return noValue when the value asked for does not exist.
+type Tree interface {
+	Insert(val int)
+	Search(val int) bool
+	InorderTraversal() []int
+	GetSize() int
+	Height() int
+	Rotations() int
+	Min() int
+	Max() int
+	Successor(val int) int
+}
+
+// noValue is returned by Min and Max on an empty tree and by Successor when
+// no stored value is greater than its argument.
+const noValue = math.MinInt
+
+// defaultBTreeOrder is the B-tree order used when none is configured: 15
+// keys of 8 bytes fill two cache lines.
+const defaultBTreeOrder = 16
+
 // TreeNode represents a node in the binary tree
 type TreeNode struct {
 	Val   int
@@ -94,6 +140,606 @@ func (bst *BinarySearchTree) GetSize() int {
 	return bst.Size
 }
 
+// Height returns the number of nodes on the longest root-to-leaf path
+func (bst *BinarySearchTree) Height() int {
+	return nodeHeight(bst.Root)
+}
+
+func nodeHeight(node *TreeNode) int {
+	if node == nil {
+		return 0
+	}
+	left, right := nodeHeight(node.Left), nodeHeight(node.Right)
+	if left > right {
+		return left + 1
+	}
+	return right + 1
+}
+
+// Rotations always returns 0: a plain BST does not rebalance
+func (bst *BinarySearchTree) Rotations() int {
+	return 0
+}
+
+// Min returns the smallest value in the tree
+func (bst *BinarySearchTree) Min() int {
+	node := bst.Root
+	if node == nil {
+		return noValue
+	}
+	for node.Left != nil {
+		node = node.Left
+	}
+	return node.Val
+}
+
+// Max returns the largest value in the tree
+func (bst *BinarySearchTree) Max() int {
+	node := bst.Root
+	if node == nil {
+		return noValue
+	}
+	for node.Right != nil {
+		node = node.Right
+	}
+	return node.Val
+}
+
+// Successor returns the smallest value in the tree greater than val. val
+// does not have to be in the tree.
+func (bst *BinarySearchTree) Successor(val int) int {
+	successor := noValue
+	node := bst.Root
+	for node != nil {
+		if val < node.Val {
+			successor = node.Val
+			node = node.Left
+		} else {
+			node = node.Right
+		}
+	}
+	return successor
+}
+
+// nilMarker stands for a missing child in serialized trees, so
+// math.MinInt cannot itself be stored in a tree that is serialized.
+const nilMarker = math.MinInt
+
+// Serialize returns the tree in level order, with nilMarker for each
+// missing child of a present node. Trailing markers are dropped, so an
+// empty tree serializes to an empty slice.
+func (bst *BinarySearchTree) Serialize() []int {
+	var result []int
+	queue := []*TreeNode{bst.Root}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		if node == nil {
+			result = append(result, nilMarker)
+			continue
+		}
+		result = append(result, node.Val)
+		queue = append(queue, node.Left, node.Right)
+	}
+	
+	end := len(result)
+	for end > 0 && result[end-1] == nilMarker {
+		end--
+	}
+	return result[:end]
+}
+
+// Deserialize rebuilds a tree from the output of Serialize
+func Deserialize(data []int) *BinarySearchTree {
+	bst := NewBinarySearchTree()
+	if len(data) == 0 || data[0] == nilMarker {
+		return bst
+	}
+	
+	bst.Root = &TreeNode{Val: data[0]}
+	bst.Size = 1
+	queue := []*TreeNode{bst.Root}
+	i := 1
+	for len(queue) > 0 && i < len(data) {
+		node := queue[0]
+		queue = queue[1:]
+		for _, child := range []**TreeNode{&node.Left, &node.Right} {
+			if i < len(data) && data[i] != nilMarker {
+				*child = &TreeNode{Val: data[i]}
+				bst.Size++
+				queue = append(queue, *child)
+			}
+			i++
+		}
+	}
+	return bst
+}
+
+// RBNode is a red-black tree node. Nil children count as black leaves.
+type RBNode struct {
+	Val    int
+	Red    bool
+	Left   *RBNode
+	Right  *RBNode
+	Parent *RBNode
+}
+
+// RedBlackTree is a self-balancing binary search tree. It rebalances less
+// strictly than an AVL tree: a path may be up to twice as long as another,
+// which keeps rotations per insert to at most two.
+type RedBlackTree struct {
+	Root      *RBNode
+	Size      int
+	rotations int
+}
+
+// NewRedBlackTree creates a new red-black tree
+func NewRedBlackTree() *RedBlackTree {
+	return &RedBlackTree{}
+}
+
+// Insert adds a value to the tree, ignoring duplicates
+func (t *RedBlackTree) Insert(val int) {
+	var parent *RBNode
+	node := t.Root
+	for node != nil {
+		parent = node
+		if val < node.Val {
+			node = node.Left
+		} else if val > node.Val {
+			node = node.Right
+		} else {
+			return
+		}
+	}
+	
+	inserted := &RBNode{Val: val, Red: true, Parent: parent}
+	if parent == nil {
+		t.Root = inserted
+	} else if val < parent.Val {
+		parent.Left = inserted
+	} else {
+		parent.Right = inserted
+	}
+	t.Size++
+	t.fixInsert(inserted)
+}
+
+// fixInsert restores the red-black properties after node was inserted red
+func (t *RedBlackTree) fixInsert(node *RBNode) {
+	for node.Parent != nil && node.Parent.Red {
+		parent := node.Parent
+		grandparent := parent.Parent
+		if parent == grandparent.Left {
+			uncle := grandparent.Right
+			if uncle != nil && uncle.Red {
+				parent.Red, uncle.Red, grandparent.Red = false, false, true
+				node = grandparent
+				continue
+			}
+			if node == parent.Right {
+				t.rotateLeft(parent)
+				node, parent = parent, node
+			}
+			parent.Red, grandparent.Red = false, true
+			t.rotateRight(grandparent)
+		} else {
+			uncle := grandparent.Left
+			if uncle != nil && uncle.Red {
+				parent.Red, uncle.Red, grandparent.Red = false, false, true
+				node = grandparent
+				continue
+			}
+			if node == parent.Left {
+				t.rotateRight(parent)
+				node, parent = parent, node
+			}
+			parent.Red, grandparent.Red = false, true
+			t.rotateLeft(grandparent)
+		}
+	}
+	t.Root.Red = false
+}
+
+func (t *RedBlackTree) rotateLeft(node *RBNode) {
+	pivot := node.Right
+	node.Right = pivot.Left
+	if pivot.Left != nil {
+		pivot.Left.Parent = node
+	}
+	t.replaceChild(node, pivot)
+	pivot.Left = node
+	node.Parent = pivot
+	t.rotations++
+}
+
+func (t *RedBlackTree) rotateRight(node *RBNode) {
+	pivot := node.Left
+	node.Left = pivot.Right
+	if pivot.Right != nil {
+		pivot.Right.Parent = node
+	}
+	t.replaceChild(node, pivot)
+	pivot.Right = node
+	node.Parent = pivot
+	t.rotations++
+}
+
+// replaceChild puts replacement where node hangs off its parent
+func (t *RedBlackTree) replaceChild(node, replacement *RBNode) {
+	replacement.Parent = node.Parent
+	if node.Parent == nil {
+		t.Root = replacement
+	} else if node == node.Parent.Left {
+		node.Parent.Left = replacement
+	} else {
+		node.Parent.Right = replacement
+	}
+}
+
+// Search finds a value in the tree
+func (t *RedBlackTree) Search(val int) bool {
+	node := t.Root
+	for node != nil {
+		if val == node.Val {
+			return true
+		} else if val < node.Val {
+			node = node.Left
+		} else {
+			node = node.Right
+		}
+	}
+	return false
+}
+
+// InorderTraversal performs inorder traversal of the tree
+func (t *RedBlackTree) InorderTraversal() []int {
+	result := make([]int, 0, t.Size)
+	var walk func(node *RBNode)
+	walk = func(node *RBNode) {
+		if node != nil {
+			walk(node.Left)
+			result = append(result, node.Val)
+			walk(node.Right)
+		}
+	}
+	walk(t.Root)
+	return result
+}
+
+// GetSize returns the size of the tree
+func (t *RedBlackTree) GetSize() int {
+	return t.Size
+}
+
+// Height returns the number of nodes on the longest root-to-leaf path
+func (t *RedBlackTree) Height() int {
+	return rbNodeHeight(t.Root)
+}
+
+func rbNodeHeight(node *RBNode) int {
+	if node == nil {
+		return 0
+	}
+	left, right := rbNodeHeight(node.Left), rbNodeHeight(node.Right)
+	if left > right {
+		return left + 1
+	}
+	return right + 1
+}
+
+// Rotations returns the number of rotations done by inserts so far
+func (t *RedBlackTree) Rotations() int {
+	return t.rotations
+}
+
+// Min returns the smallest value in the tree
+func (t *RedBlackTree) Min() int {
+	node := t.Root
+	if node == nil {
+		return noValue
+	}
+	for node.Left != nil {
+		node = node.Left
+	}
+	return node.Val
+}
+
+// Max returns the largest value in the tree
+func (t *RedBlackTree) Max() int {
+	node := t.Root
+	if node == nil {
+		return noValue
+	}
+	for node.Right != nil {
+		node = node.Right
+	}
+	return node.Val
+}
+
+// Successor returns the smallest value in the tree greater than val
+func (t *RedBlackTree) Successor(val int) int {
+	successor := noValue
+	node := t.Root
+	for node != nil {
+		if val < node.Val {
+			successor = node.Val
+			node = node.Left
+		} else {
+			node = node.Right
+		}
+	}
+	return successor
+}
+
+// VerifyInvariants checks the red-black properties: the root is black, no
+// red node has a red child, and every root-to-leaf path crosses the same
+// number of black nodes. It returns nil when they all hold.
+func (t *RedBlackTree) VerifyInvariants() error {
+	if t.Root != nil && t.Root.Red {
+		return fmt.Errorf("root is red")
+	}
+	_, err := blackHeight(t.Root)
+	return err
+}
+
+// blackHeight returns the black-height of the subtree at node, checking
+// the red and black-height rules on the way.
+func blackHeight(node *RBNode) (int, error) {
+	if node == nil {
+		return 1, nil
+	}
+	if node.Red {
+		for _, child := range []*RBNode{node.Left, node.Right} {
+			if child != nil && child.Red {
+				return 0, fmt.Errorf("red node %d has red child %d", node.Val, child.Val)
+			}
+		}
+	}
+	left, err := blackHeight(node.Left)
+	if err != nil {
+		return 0, err
+	}
+	right, err := blackHeight(node.Right)
+	if err != nil {
+		return 0, err
+	}
+	if left != right {
+		return 0, fmt.Errorf("node %d has black-heights %d (left) and %d (right)", node.Val, left, right)
+	}
+	if !node.Red {
+		left++
+	}
+	return left, nil
+}
+
+// BTreeNode is a B-tree node holding its keys in ascending order. An
+// inner node has one more child than it has keys; a leaf has no children.
+type BTreeNode struct {
+	Keys     []int
+	Children []*BTreeNode
+}
+
+// BTree is a B-tree whose nodes have at most Order children and Order-1
+// keys. Packing many keys into each node keeps it a few levels deep where
+// a binary tree needs dozens, and searches scan contiguous key slices
+// instead of chasing a pointer per comparison.
+type BTree struct {
+	Root  *BTreeNode
+	Order int
+	Size  int
+	nodes int
+}
+
+// NewBTree creates an empty B-tree of the given order, which must be at
+// least 3
+func NewBTree(order int) *BTree {
+	return &BTree{Order: order}
+}
+
+// Insert adds a value to the tree, ignoring duplicates. Nodes that fill up
+// are split, and a split root grows the tree by one level.
+func (t *BTree) Insert(val int) {
+	if t.Root == nil {
+		t.Root = &BTreeNode{Keys: []int{val}}
+		t.Size, t.nodes = 1, 1
+		return
+	}
+	median, right, inserted := t.insert(t.Root, val)
+	if !inserted {
+		return
+	}
+	t.Size++
+	if right != nil {
+		t.Root = &BTreeNode{Keys: []int{median}, Children: []*BTreeNode{t.Root, right}}
+		t.nodes++
+	}
+}
+
+// insert adds val to the subtree at node. If node overflows it is split,
+// and the median key and new right sibling are returned for the parent.
+func (t *BTree) insert(node *BTreeNode, val int) (median int, right *BTreeNode, inserted bool) {
+	i := sort.SearchInts(node.Keys, val)
+	if i < len(node.Keys) && node.Keys[i] == val {
+		return 0, nil, false
+	}
+	if node.Children == nil {
+		node.Keys = insertAt(node.Keys, i, val)
+	} else {
+		median, right, inserted = t.insert(node.Children[i], val)
+		if right == nil {
+			return 0, nil, inserted
+		}
+		node.Keys = insertAt(node.Keys, i, median)
+		node.Children = insertAt(node.Children, i+1, right)
+	}
+	if len(node.Keys) < t.Order {
+		return 0, nil, true
+	}
+	
+	// Split around the middle key, which moves up to the parent
+	mid := len(node.Keys) / 2
+	median = node.Keys[mid]
+	right = &BTreeNode{Keys: append([]int(nil), node.Keys[mid+1:]...)}
+	node.Keys = node.Keys[:mid]
+	if node.Children != nil {
+		right.Children = append([]*BTreeNode(nil), node.Children[mid+1:]...)
+		node.Children = node.Children[:mid+1]
+	}
+	t.nodes++
+	return median, right, true
+}
+
+// insertAt inserts v into s at index i
+func insertAt[T any](s []T, i int, v T) []T {
+	s = append(s, v)
+	copy(s[i+1:], s[i:])
+	s[i] = v
+	return s
+}
+
+// Search finds a value in the tree
+func (t *BTree) Search(val int) bool {
+	node := t.Root
+	for node != nil {
+		i := sort.SearchInts(node.Keys, val)
+		if i < len(node.Keys) && node.Keys[i] == val {
+			return true
+		}
+		if node.Children == nil {
+			return false
+		}
+		node = node.Children[i]
+	}
+	return false
+}
+
+// InorderTraversal performs inorder traversal of the tree
+func (t *BTree) InorderTraversal() []int {
+	result := make([]int, 0, t.Size)
+	var walk func(node *BTreeNode)
+	walk = func(node *BTreeNode) {
+		for i, key := range node.Keys {
+			if node.Children != nil {
+				walk(node.Children[i])
+			}
+			result = append(result, key)
+		}
+		if node.Children != nil {
+			walk(node.Children[len(node.Keys)])
+		}
+	}
+	if t.Root != nil {
+		walk(t.Root)
+	}
+	return result
+}
+
+// GetSize returns the size of the tree
+func (t *BTree) GetSize() int {
+	return t.Size
+}
+
+// NodeCount returns the number of nodes, each holding up to Order-1 keys
+func (t *BTree) NodeCount() int {
+	return t.nodes
+}
+
+// Height returns the number of levels. Every leaf of a B-tree is at the
+// same depth, so it is the length of the leftmost path.
+func (t *BTree) Height() int {
+	height := 0
+	for node := t.Root; node != nil; height++ {
+		if node.Children == nil {
+			return height + 1
+		}
+		node = node.Children[0]
+	}
+	return height
+}
+
+// Rotations always returns 0: a B-tree rebalances by splitting nodes
+func (t *BTree) Rotations() int {
+	return 0
+}
+
+// Min returns the smallest value in the tree
+func (t *BTree) Min() int {
+	node := t.Root
+	if node == nil {
+		return noValue
+	}
+	for node.Children != nil {
+		node = node.Children[0]
+	}
+	return node.Keys[0]
+}
+
+// Max returns the largest value in the tree
+func (t *BTree) Max() int {
+	node := t.Root
+	if node == nil {
+		return noValue
+	}
+	for node.Children != nil {
+		node = node.Children[len(node.Children)-1]
+	}
+	return node.Keys[len(node.Keys)-1]
+}
+
+// Successor returns the smallest value in the tree greater than val
+func (t *BTree) Successor(val int) int {
+	successor := noValue
+	node := t.Root
+	for node != nil {
+		i := sort.Search(len(node.Keys), func(j int) bool { return node.Keys[j] > val })
+		if i < len(node.Keys) {
+			successor = node.Keys[i]
+		}
+		if node.Children == nil {
+			break
+		}
+		node = node.Children[i]
+	}
+	return successor
+}
+
+// VerifyInvariants checks the B-tree shape: every node but the root holds
+// between ceil(Order/2)-1 and Order-1 keys, inner nodes have one child
+// more than keys, and all leaves are at the same depth. It returns nil
+// when they all hold.
+func (t *BTree) VerifyInvariants() error {
+	if t.Root == nil {
+		return nil
+	}
+	leafDepth := -1
+	minKeys := (t.Order+1)/2 - 1
+	var check func(node *BTreeNode, depth int) error
+	check = func(node *BTreeNode, depth int) error {
+		if len(node.Keys) > t.Order-1 || (node != t.Root && len(node.Keys) < minKeys) {
+			return fmt.Errorf("node at depth %d holds %d keys (order %d)", depth, len(node.Keys), t.Order)
+		}
+		if node.Children == nil {
+			if leafDepth == -1 {
+				leafDepth = depth
+			} else if depth != leafDepth {
+				return fmt.Errorf("leaves at depths %d and %d", leafDepth, depth)
+			}
+			return nil
+		}
+		if len(node.Children) != len(node.Keys)+1 {
+			return fmt.Errorf("node at depth %d has %d keys but %d children", depth, len(node.Keys), len(node.Children))
+		}
+		for _, child := range node.Children {
+			if err := check(child, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return check(t.Root, 0)
+}
+
 // isSorted checks if a slice is sorted
 func isSorted(arr []int) bool {
 	for i := 1; i < len(arr); i++ {
@@ -104,22 +750,98 @@ func isSorted(arr []int) bool {
 	return true
 }
 
+// equalSlices reports whether a and b hold the same values in order
+func equalSlices(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
 func main() {
 	fmt.Println("Starting binary tree benchmark...")
-	startTime := time.Now()
 	
-	bst := NewBinarySearchTree()
 	nodesCount := 1000
+	treeType := "bst"
+	insertOrder := "random"
+	var serializeSizes []int
+	serializeIterations := 5
+	successorQueries := 1000
+	order := defaultBTreeOrder
 	
-	// Create shuffled values for insertion
+	if len(os.Args) > 1 {
+		var config Config
+		data, err := os.ReadFile(os.Args[1])
+		if err == nil {
+			err = json.Unmarshal(data, &config)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading config: %v\n", err)
+			os.Exit(1)
+		}
+		if config.Parameters.NodesCount > 0 {
+			nodesCount = config.Parameters.NodesCount
+		}
+		if config.Parameters.TreeType != "" {
+			treeType = config.Parameters.TreeType
+		}
+		if config.Parameters.InsertOrder != "" {
+			insertOrder = config.Parameters.InsertOrder
+		}
+		serializeSizes = config.Parameters.SerializeSizes
+		if config.Parameters.SerializeIterations > 0 {
+			serializeIterations = config.Parameters.SerializeIterations
+		}
+		if config.Parameters.SuccessorQueries > 0 {
+			successorQueries = config.Parameters.SuccessorQueries
+		}
+		if config.Parameters.Order > 0 {
+			order = config.Parameters.Order
+		}
+	}
+	if len(serializeSizes) == 0 {
+		serializeSizes = []int{nodesCount}
+	}
+	
+	var bst Tree
+	switch treeType {
+	case "bst":
+		bst = NewBinarySearchTree()
+	case "red_black":
+		bst = NewRedBlackTree()
+	case "btree":
+		if order < 3 {
+			fmt.Fprintf(os.Stderr, "order must be at least 3, got %d\n", order)
+			os.Exit(1)
+		}
+		bst = NewBTree(order)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown tree_type '%s' (expected bst, red_black or btree)\n", treeType)
+		os.Exit(1)
+	}
+	if insertOrder != "random" && insertOrder != "sorted" {
+		fmt.Fprintf(os.Stderr, "Unknown insert_order '%s' (expected random or sorted)\n", insertOrder)
+		os.Exit(1)
+	}
+	
+	startTime := time.Now()
+	
+	// Create values for insertion, shuffled unless sorted order was asked for
 	rand.Seed(42) // For reproducible results
 	values := make([]int, nodesCount)
 	for i := 0; i < nodesCount; i++ {
 		values[i] = i
 	}
-	rand.Shuffle(len(values), func(i, j int) {
-		values[i], values[j] = values[j], values[i]
-	})
+	if insertOrder == "random" {
+		rand.Shuffle(len(values), func(i, j int) {
+			values[i], values[j] = values[j], values[i]
+		})
+	}
 	
 	// Insert operations
 	for _, val := range values {
@@ -145,10 +867,123 @@ func main() {
 	
 	executionTime := time.Since(startTime)
 	
+	fmt.Printf("Tree type: %s (%s insertion order)\n", treeType, insertOrder)
 	fmt.Printf("Tree operations completed: %d inserts, %d searches\n", 
 		nodesCount, foundCount)
 	fmt.Printf("Final tree size: %d\n", bst.GetSize())
+	fmt.Printf("Tree height: %d\n", bst.Height())
+	fmt.Printf("Rotations: %d\n", bst.Rotations())
 	fmt.Printf("Inorder traversal length: %d\n", len(traversalResult))
 	fmt.Printf("Traversal is sorted: %t\n", sorted)
+	if rb, ok := bst.(*RedBlackTree); ok {
+		if err := rb.VerifyInvariants(); err != nil {
+			fmt.Fprintf(os.Stderr, "Red-black invariants violated: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("Red-black invariants hold: true")
+	}
+	if bt, ok := bst.(*BTree); ok {
+		if err := bt.VerifyInvariants(); err != nil {
+			fmt.Fprintf(os.Stderr, "B-tree invariants violated: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("B-tree order: %d, node count: %d\n", bt.Order, bt.NodeCount())
+		fmt.Println("B-tree invariants hold: true")
+	}
+	
+	// Search for every inserted value, timed on its own so tree shapes
+	// can be compared
+	start := time.Now()
+	allFound := true
+	for _, val := range values {
+		if !bst.Search(val) {
+			allFound = false
+		}
+	}
+	searchTime := time.Since(start)
+	fmt.Printf("Average search time: %.9f seconds (%d searches)\n",
+		searchTime.Seconds()/float64(len(values)), len(values))
+	fmt.Printf("All inserted values found: %t\n", allFound)
+	if !allFound {
+		os.Exit(1)
+	}
+	
+	// Ordered queries: Min, Max and a batch of Successor lookups on values
+	// spread over the whole key range, the maximum among them
+	queries := make([]int, successorQueries)
+	for i := range queries {
+		queries[i] = rand.Intn(nodesCount)
+	}
+	queries[0] = nodesCount - 1
+	
+	start = time.Now()
+	minValue, maxValue := noValue, noValue
+	for i := 0; i < successorQueries; i++ {
+		minValue = bst.Min()
+		maxValue = bst.Max()
+	}
+	minMaxTime := time.Since(start)
+	
+	successors := make([]int, successorQueries)
+	start = time.Now()
+	for i, val := range queries {
+		successors[i] = bst.Successor(val)
+	}
+	successorTime := time.Since(start)
+	
+	successorsCorrect := minValue == traversalResult[0] &&
+		maxValue == traversalResult[len(traversalResult)-1]
+	for i, val := range queries {
+		expected := noValue
+		if next := sort.SearchInts(traversalResult, val+1); next < len(traversalResult) {
+			expected = traversalResult[next]
+		}
+		if successors[i] != expected {
+			successorsCorrect = false
+		}
+	}
+	
+	fmt.Printf("Min: %d, max: %d\n", minValue, maxValue)
+	fmt.Printf("Average min+max time: %.9f seconds\n", minMaxTime.Seconds()/float64(successorQueries))
+	fmt.Printf("Average successor time: %.9f seconds (%d queries)\n",
+		successorTime.Seconds()/float64(successorQueries), successorQueries)
+	fmt.Printf("Successors match in-order traversal: %t\n", successorsCorrect)
+	if !successorsCorrect {
+		os.Exit(1)
+	}
+	
+	// Serialize/deserialize round trip, timed separately for each size
+	for _, size := range serializeSizes {
+		if size < 0 {
+			fmt.Fprintf(os.Stderr, "serialize_sizes must not be negative, got %d\n", size)
+			os.Exit(1)
+		}
+		original := NewBinarySearchTree()
+		for _, val := range rand.Perm(size) {
+			original.Insert(val)
+		}
+		
+		var serializeTime, deserializeTime time.Duration
+		var restored *BinarySearchTree
+		for i := 0; i < serializeIterations; i++ {
+			start = time.Now()
+			data := original.Serialize()
+			serializeTime += time.Since(start)
+			
+			start = time.Now()
+			restored = Deserialize(data)
+			deserializeTime += time.Since(start)
+		}
+		
+		matches := restored.GetSize() == original.GetSize() &&
+			equalSlices(restored.InorderTraversal(), original.InorderTraversal())
+		fmt.Printf("Round trip (%d nodes): avg serialize %.6f s, avg deserialize %.6f s, in-order matches: %t\n",
+			size, serializeTime.Seconds()/float64(serializeIterations),
+			deserializeTime.Seconds()/float64(serializeIterations), matches)
+		if !matches {
+			os.Exit(1)
+		}
+	}
+	
 	fmt.Printf("Execution time: %.6f seconds\n", executionTime.Seconds())
-}
\ No newline at end of file
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// TestBTree builds B-trees of small and large order from shuffled and
+// sorted keys with duplicates mixed in, and checks that each keeps its
+// invariants, traverses in order, and finds every key and no others.
+func TestBTree(t *testing.T) {
+	const n = 500
+	inputs := map[string][]int{
+		"shuffled":        append(rand.Perm(n), rand.Perm(n)...),
+		"half duplicated": append(rand.Perm(n)[:n/2], rand.Perm(n)...),
+		"sorted":          nil,
+	}
+	for i := 0; i < n; i++ {
+		inputs["sorted"] = append(inputs["sorted"], i)
+	}
+
+	for _, order := range []int{3, 4, 5, 16} {
+		for name, keys := range inputs {
+			t.Run(fmt.Sprintf("order %d %s", order, name), func(t *testing.T) {
+				tree := NewBTree(order)
+				for _, key := range keys {
+					tree.Insert(key)
+				}
+
+				if err := tree.VerifyInvariants(); err != nil {
+					t.Fatalf("invariants broken: %v", err)
+				}
+				if tree.GetSize() != n {
+					t.Errorf("GetSize() = %d, want %d", tree.GetSize(), n)
+				}
+				traversal := tree.InorderTraversal()
+				if len(traversal) != n {
+					t.Fatalf("traversal has %d keys, want %d", len(traversal), n)
+				}
+				for i, key := range traversal {
+					if key != i {
+						t.Fatalf("traversal[%d] = %d, want %d", i, key, i)
+					}
+					if !tree.Search(i) {
+						t.Fatalf("Search(%d) = false for an inserted key", i)
+					}
+				}
+				if tree.Search(-1) || tree.Search(n) {
+					t.Error("Search found a key that was never inserted")
+				}
+				if tree.Min() != 0 || tree.Max() != n-1 {
+					t.Errorf("Min, Max = %d, %d, want 0, %d", tree.Min(), tree.Max(), n-1)
+				}
+			})
+		}
+	}
+}
@@ -1,77 +1,649 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"math"
+	"math/bits"
 	"math/rand"
+	"os"
+	"sort"
 	"time"
 )
 
+// Config is the optional config file. KeyType lists the key types to run
+// as separate test cases: "string" (random KeyLength-character keys in a
+// map[string]int) and "int" (random keys in a map[int]int). With Presize
+// each key type is also run on a map made with room for all its keys.
+// Operations adds timed passes over the populated map: "iterate" ranges
+// over it and "sorted_iterate" visits it in key order; each is repeated
+// IterateRuns times. CollisionStrategy runs the int keys through a custom
+// open-addressing table at LoadFactor (0.9 by default): "linear_probing",
+// or "robin_hood", which is compared against linear probing.
+type Config struct {
+	Parameters struct {
+		NumOperations int      `json:"num_operations"`
+		KeyLength     int      `json:"key_length"`
+		KeyType       []string `json:"key_type"`
+		Presize       bool     `json:"presize"`
+		Operations    []string `json:"operations"`
+		IterateRuns   int      `json:"iterate_runs"`
+		
+		CollisionStrategy string  `json:"collision_strategy"`
+		LoadFactor        float64 `json:"load_factor"`
+	} `json:"parameters"`
+}
+
+// mapKey is the set of key types the benchmark runs; keys must be ordered
+// for sorted iteration.
+type mapKey interface {
+	~int | ~string
+}
+
+// iterateOptions selects the iteration passes run on each populated map
+type iterateOptions struct {
+	plain  bool
+	sorted bool
+	runs   int
+}
+
+// caseResult holds the counts and timings of one test case
+type caseResult struct {
+	keyType      string
+	presized     bool
+	foundCount   int
+	deletedCount int
+	remaining    int
+	correct      bool
+	insertTime   time.Duration
+	lookupTime   time.Duration
+	deleteTime   time.Duration
+	totalTime    time.Duration
+	
+	// Averages over the iteration runs, zero when not requested
+	avgIterateTime       time.Duration
+	avgSortedIterateTime time.Duration
+}
+
+// keyCharset is the alphabet of the random string keys.
+const keyCharset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
 func randomString(length int) string {
-	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 	result := make([]byte, length)
 	for i := range result {
-		result[i] = charset[rand.Intn(len(charset))]
+		result[i] = keyCharset[rand.Intn(len(keyCharset))]
 	}
 	return string(result)
 }
 
-func main() {
-	rand.Seed(time.Now().UnixNano())
-	numOperations := 100000
+// distinctStrings returns the number of distinct keys of the given length,
+// len(keyCharset)^length, saturating at math.MaxInt.
+func distinctStrings(length int) int {
+	count := 1
+	for i := 0; i < length; i++ {
+		if count > math.MaxInt/len(keyCharset) {
+			return math.MaxInt
+		}
+		count *= len(keyCharset)
+	}
+	return count
+}
+
+// stringKeys returns n distinct random strings of the given length. n must
+// not exceed distinctStrings(length), or it never finds enough.
+func stringKeys(n, length int) []string {
+	keys := make([]string, 0, n)
+	seen := make(map[string]bool, n)
+	for len(keys) < n {
+		key := randomString(length)
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// intKeys returns n distinct random non-negative ints
+func intKeys(n int) []int {
+	keys := make([]int, 0, n)
+	seen := make(map[int]bool, n)
+	for len(keys) < n {
+		key := rand.Int()
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// mapLoadFactor approximates the average number of entries per slot at
+// which a Go map grows. Both the bucket-based maps (6.5 entries per
+// 8-slot bucket) and the Swiss-table maps (7/8 full) grow at about this
+// fill.
+const mapLoadFactor = 0.8
+
+// estimatedGrowths returns roughly how many times a map made without a size
+// hint doubles while n distinct keys are inserted, starting from a single
+// 8-slot bucket. A map pre-sized for n keys needs none of these.
+func estimatedGrowths(n int) int {
+	growths := 0
+	for capacity := 8.0; capacity*mapLoadFactor < float64(n); capacity *= 2 {
+		growths++
+	}
+	return growths
+}
+
+// openSlot is one slot of an openTable. dist is how many slots past its
+// home the entry sits, so a successful lookup probes dist+1 slots.
+type openSlot struct {
+	key   int
+	value int
+	dist  int
+	used  bool
+}
+
+// openTable is a fixed-capacity open-addressing hash table of int keys. On
+// a collision it probes the following slots in turn. With robinHood set,
+// an insert that has probed further than the entry in its way takes that
+// slot and carries the displaced entry on, which evens out probe lengths
+// and lets lookups stop early. The table does not grow, so it must be made
+// with room for every key.
+type openTable struct {
+	slots     []openSlot
+	count     int
+	robinHood bool
+}
+
+func newOpenTable(capacity int, robinHood bool) *openTable {
+	return &openTable{slots: make([]openSlot, capacity), robinHood: robinHood}
+}
+
+// home maps key to its first slot: a Fibonacci hash scaled onto the
+// capacity, which need not be a power of two.
+func (t *openTable) home(key int) int {
+	hi, _ := bits.Mul64(uint64(key)*0x9E3779B97F4A7C15, uint64(len(t.slots)))
+	return int(hi)
+}
+
+func (t *openTable) next(i int) int {
+	if i++; i == len(t.slots) {
+		return 0
+	}
+	return i
+}
+
+// Put inserts key or updates its value. It panics when the table is full.
+func (t *openTable) Put(key, value int) {
+	if t.count == len(t.slots) {
+		panic("openTable: table is full")
+	}
+	entry := openSlot{key: key, value: value, used: true}
+	for i := t.home(key); ; i = t.next(i) {
+		slot := &t.slots[i]
+		if !slot.used {
+			*slot = entry
+			t.count++
+			return
+		}
+		if slot.key == entry.key {
+			slot.value = entry.value
+			return
+		}
+		if t.robinHood && slot.dist < entry.dist {
+			*slot, entry = entry, *slot
+		}
+		entry.dist++
+	}
+}
+
+// find returns the slot holding key, or -1. Robin Hood lookups stop at the
+// first entry closer to its home than key would be, as key cannot be past
+// it.
+func (t *openTable) find(key int) int {
+	i := t.home(key)
+	for dist := 0; dist < len(t.slots); dist++ {
+		slot := &t.slots[i]
+		if !slot.used || (t.robinHood && slot.dist < dist) {
+			return -1
+		}
+		if slot.key == key {
+			return i
+		}
+		i = t.next(i)
+	}
+	return -1
+}
+
+// Get returns the value stored for key
+func (t *openTable) Get(key int) (int, bool) {
+	if i := t.find(key); i >= 0 {
+		return t.slots[i].value, true
+	}
+	return 0, false
+}
+
+// Delete removes key without tombstones by shifting later entries of its
+// run back into the hole, as far as each entry's home allows.
+func (t *openTable) Delete(key int) bool {
+	hole := t.find(key)
+	if hole < 0 {
+		return false
+	}
+	for j := t.next(hole); t.slots[j].used; j = t.next(j) {
+		gap := j - hole
+		if gap < 0 {
+			gap += len(t.slots)
+		}
+		if t.slots[j].dist < gap {
+			// Its home lies after the hole. A Robin Hood run is ordered by
+			// home, so nothing later can move back either.
+			if t.robinHood {
+				break
+			}
+			continue
+		}
+		t.slots[hole] = t.slots[j]
+		t.slots[hole].dist -= gap
+		hole = j
+	}
+	t.slots[hole] = openSlot{}
+	t.count--
+	return true
+}
+
+// probeStats summarizes the number of slots a successful lookup of each
+// stored key probes.
+type probeStats struct {
+	avg    float64
+	stddev float64
+	max    int
+}
+
+func (t *openTable) probeLengths() probeStats {
+	var stats probeStats
+	if t.count == 0 {
+		return stats
+	}
+	sum, sumSquares := 0.0, 0.0
+	for _, slot := range t.slots {
+		if !slot.used {
+			continue
+		}
+		length := slot.dist + 1
+		sum += float64(length)
+		sumSquares += float64(length * length)
+		if length > stats.max {
+			stats.max = length
+		}
+	}
+	n := float64(t.count)
+	stats.avg = sum / n
+	stats.stddev = math.Sqrt(math.Max(sumSquares/n-stats.avg*stats.avg, 0))
+	return stats
+}
+
+// openCaseResult holds the counts, timings and probe lengths of one
+// openTable run
+type openCaseResult struct {
+	strategy   string
+	capacity   int
+	foundCount int
+	deleted    int
+	remaining  int
+	correct    bool
+	insertTime time.Duration
+	lookupTime time.Duration
+	deleteTime time.Duration
+	probes     probeStats
+}
+
+// runOpenCase fills an openTable sized for loadFactor with keys, looks
+// every key up and deletes every other one, like runCase does for the
+// built-in map. Probe lengths are taken at full load, before the deletes;
+// afterwards the kept keys must still be found and the deleted ones not.
+func runOpenCase(strategy string, keys, values []int, loadFactor float64) openCaseResult {
+	capacity := int(math.Ceil(float64(len(keys)) / loadFactor))
+	table := newOpenTable(capacity, strategy == "robin_hood")
+	result := openCaseResult{strategy: strategy, capacity: capacity}
 	
-	// Generate test data
-	keys := make([]string, numOperations)
-	values := make([]int, numOperations)
+	insertStart := time.Now()
+	for i, key := range keys {
+		table.Put(key, values[i])
+	}
+	result.insertTime = time.Since(insertStart)
+	result.probes = table.probeLengths()
 	
-	for i := 0; i < numOperations; i++ {
-		keys[i] = randomString(10)
-		values[i] = rand.Intn(1000000) + 1
+	lookupStart := time.Now()
+	valueSum := 0
+	for _, key := range keys {
+		if value, ok := table.Get(key); ok {
+			result.foundCount++
+			valueSum += value
+		}
 	}
+	result.lookupTime = time.Since(lookupStart)
 	
-	fmt.Printf("Testing hash table with %d operations...\n", numOperations)
+	deleteStart := time.Now()
+	for i := 0; i < len(keys); i += 2 {
+		if table.Delete(keys[i]) {
+			result.deleted++
+		}
+	}
+	result.deleteTime = time.Since(deleteStart)
+	result.remaining = table.count
+	
+	expectedSum := 0
+	for _, value := range values {
+		expectedSum += value
+	}
+	result.correct = result.foundCount == len(keys) && valueSum == expectedSum &&
+		result.deleted == (len(keys)+1)/2 && result.remaining == len(keys)-result.deleted
+	for i, key := range keys {
+		_, ok := table.Get(key)
+		result.correct = result.correct && ok == (i%2 == 1)
+	}
+	return result
+}
+
+// runCases runs runCase on a growing map and, with presize, again on a
+// pre-sized map with the same keys.
+func runCases[K mapKey](keyType string, keys []K, values []int, presize bool, iterate iterateOptions) []caseResult {
+	results := []caseResult{runCase(keyType, keys, values, false, iterate)}
+	if presize {
+		results = append(results, runCase(keyType, keys, values, true, iterate))
+	}
+	return results
+}
+
+// runCase inserts, looks up and deletes every other key in a fresh map
+// keyed by K, made with a size hint of len(keys) when presized is set. The
+// keys must be distinct; lookups are checked against the inserted values
+// and the counts against the number of keys. The requested iteration
+// passes run between the lookups and the deletes, and must sum to the same
+// total as the lookups on every run.
+func runCase[K mapKey](keyType string, keys []K, values []int, presized bool, iterate iterateOptions) caseResult {
+	result := caseResult{keyType: keyType, presized: presized}
 	totalStart := time.Now()
 	
-	// Create hash table (map in Go)
-	hashTable := make(map[string]int)
+	var hashTable map[K]int
+	if presized {
+		hashTable = make(map[K]int, len(keys))
+	} else {
+		hashTable = make(map[K]int)
+	}
 	
 	// Insert operations
 	insertStart := time.Now()
-	for i := 0; i < numOperations; i++ {
-		hashTable[keys[i]] = values[i]
+	for i, key := range keys {
+		hashTable[key] = values[i]
 	}
-	insertTime := time.Since(insertStart)
+	result.insertTime = time.Since(insertStart)
 	
 	// Lookup operations
 	lookupStart := time.Now()
-	foundCount := 0
+	valueSum := 0
 	for _, key := range keys {
-		if _, exists := hashTable[key]; exists {
-			foundCount++
+		if value, exists := hashTable[key]; exists {
+			result.foundCount++
+			valueSum += value
+		}
+	}
+	result.lookupTime = time.Since(lookupStart)
+	
+	// Iteration passes
+	iterationStable := true
+	if iterate.plain {
+		var elapsed time.Duration
+		for run := 0; run < iterate.runs; run++ {
+			start := time.Now()
+			sum := iterateSum(hashTable)
+			elapsed += time.Since(start)
+			iterationStable = iterationStable && sum == valueSum
 		}
+		result.avgIterateTime = elapsed / time.Duration(iterate.runs)
+	}
+	if iterate.sorted {
+		var elapsed time.Duration
+		for run := 0; run < iterate.runs; run++ {
+			start := time.Now()
+			sum := sortedIterateSum(hashTable)
+			elapsed += time.Since(start)
+			iterationStable = iterationStable && sum == valueSum
+		}
+		result.avgSortedIterateTime = elapsed / time.Duration(iterate.runs)
 	}
-	lookupTime := time.Since(lookupStart)
 	
 	// Delete operations (every other key)
 	deleteStart := time.Now()
-	deletedCount := 0
-	for i := 0; i < numOperations; i += 2 {
+	for i := 0; i < len(keys); i += 2 {
 		if _, exists := hashTable[keys[i]]; exists {
 			delete(hashTable, keys[i])
-			deletedCount++
+			result.deletedCount++
+		}
+	}
+	result.deleteTime = time.Since(deleteStart)
+	
+	result.totalTime = time.Since(totalStart)
+	result.remaining = len(hashTable)
+	
+	expectedSum := 0
+	for _, value := range values {
+		expectedSum += value
+	}
+	result.correct = result.foundCount == len(keys) && valueSum == expectedSum && iterationStable &&
+		result.deletedCount == (len(keys)+1)/2 && result.remaining == len(keys)-result.deletedCount
+	return result
+}
+
+// iterateSum ranges over hashTable in Go's randomized order, summing values
+func iterateSum[K mapKey](hashTable map[K]int) int {
+	sum := 0
+	for _, value := range hashTable {
+		sum += value
+	}
+	return sum
+}
+
+// sortedIterateSum collects and sorts the keys of hashTable, then sums the
+// values in key order
+func sortedIterateSum[K mapKey](hashTable map[K]int) int {
+	keys := make([]K, 0, len(hashTable))
+	for key := range hashTable {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	
+	sum := 0
+	for _, key := range keys {
+		sum += hashTable[key]
+	}
+	return sum
+}
+
+func main() {
+	rand.Seed(time.Now().UnixNano())
+	numOperations := 100000
+	keyLength := 10
+	keyTypes := []string{"string"}
+	presize := false
+	iterate := iterateOptions{runs: 10}
+	collisionStrategy := ""
+	loadFactor := 0.9
+	
+	if len(os.Args) > 1 {
+		var config Config
+		data, err := os.ReadFile(os.Args[1])
+		if err == nil {
+			err = json.Unmarshal(data, &config)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading config: %v\n", err)
+			os.Exit(1)
+		}
+		if config.Parameters.NumOperations > 0 {
+			numOperations = config.Parameters.NumOperations
+		}
+		if config.Parameters.KeyLength > 0 {
+			keyLength = config.Parameters.KeyLength
+		}
+		if len(config.Parameters.KeyType) > 0 {
+			keyTypes = config.Parameters.KeyType
+		}
+		presize = config.Parameters.Presize
+		for _, operation := range config.Parameters.Operations {
+			switch operation {
+			case "iterate":
+				iterate.plain = true
+			case "sorted_iterate":
+				iterate.sorted = true
+			default:
+				fmt.Fprintf(os.Stderr, "Unknown operation '%s' (expected iterate or sorted_iterate)\n", operation)
+				os.Exit(1)
+			}
+		}
+		if config.Parameters.IterateRuns > 0 {
+			iterate.runs = config.Parameters.IterateRuns
+		}
+		collisionStrategy = config.Parameters.CollisionStrategy
+		if config.Parameters.LoadFactor > 0 {
+			loadFactor = config.Parameters.LoadFactor
+		}
+	}
+	
+	stringKeyed := false
+	for _, keyType := range keyTypes {
+		stringKeyed = stringKeyed || keyType == "string"
+		if keyType != "string" && keyType != "int" {
+			fmt.Fprintf(os.Stderr, "Unknown key_type '%s' (expected string or int)\n", keyType)
+			os.Exit(1)
+		}
+	}
+	switch collisionStrategy {
+	case "", "linear_probing", "robin_hood":
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown collision_strategy '%s' (expected linear_probing or robin_hood)\n", collisionStrategy)
+		os.Exit(1)
+	}
+	if loadFactor >= 1 {
+		fmt.Fprintf(os.Stderr, "load_factor must be below 1, got %g\n", loadFactor)
+		os.Exit(1)
+	}
+	if stringKeyed && numOperations > distinctStrings(keyLength) {
+		fmt.Fprintf(os.Stderr, "num_operations %d exceeds the %d distinct string keys of key_length %d\n",
+			numOperations, distinctStrings(keyLength), keyLength)
+		os.Exit(1)
+	}
+	
+	// Generate test data
+	values := make([]int, numOperations)
+	for i := 0; i < numOperations; i++ {
+		values[i] = rand.Intn(1000000) + 1
+	}
+	
+	fmt.Printf("Testing hash table with %d operations...\n", numOperations)
+	
+	// results holds the growing-map case of each key type
+	results := make(map[string]caseResult, len(keyTypes))
+	allCorrect := true
+	for _, keyType := range keyTypes {
+		var cases []caseResult
+		if keyType == "int" {
+			cases = runCases(keyType, intKeys(numOperations), values, presize, iterate)
+		} else {
+			cases = runCases(keyType, stringKeys(numOperations, keyLength), values, presize, iterate)
+		}
+		results[keyType] = cases[0]
+		
+		for _, result := range cases {
+			allCorrect = allCorrect && result.correct
+			
+			label := keyType + " keys"
+			if result.presized {
+				label += ", pre-sized"
+			}
+			fmt.Printf("Result (%s):\n", label)
+			fmt.Printf("  Inserted: %d items\n", numOperations)
+			fmt.Printf("  Found: %d/%d items\n", result.foundCount, numOperations)
+			fmt.Printf("  Deleted: %d items\n", result.deletedCount)
+			fmt.Printf("  Remaining: %d items\n", result.remaining)
+			fmt.Printf("  Correct: %t\n", result.correct)
+			fmt.Println("Timing:")
+			fmt.Printf("  Insert time: %.6f seconds\n", result.insertTime.Seconds())
+			fmt.Printf("  Lookup time: %.6f seconds\n", result.lookupTime.Seconds())
+			fmt.Printf("  Delete time: %.6f seconds\n", result.deleteTime.Seconds())
+			if iterate.plain {
+				fmt.Printf("  AvgIterateTime: %.6f seconds\n", result.avgIterateTime.Seconds())
+			}
+			if iterate.sorted {
+				fmt.Printf("  AvgSortedIterateTime: %.6f seconds\n", result.avgSortedIterateTime.Seconds())
+			}
+			fmt.Printf("  Total time: %.6f seconds\n", result.totalTime.Seconds())
+		}
+		
+		if presize {
+			growing, presized := cases[0], cases[1]
+			fmt.Printf("Pre-sized vs growing map (%s keys):\n", keyType)
+			fmt.Printf("  Insert time difference: %.6f seconds (pre-sized %.2fx faster)\n",
+				(growing.insertTime - presized.insertTime).Seconds(),
+				growing.insertTime.Seconds()/presized.insertTime.Seconds())
+			fmt.Printf("  Growths avoided: ~%d\n", estimatedGrowths(numOperations))
 		}
 	}
-	deleteTime := time.Since(deleteStart)
 	
-	totalTime := time.Since(totalStart)
+	stringResult, haveString := results["string"]
+	intResult, haveInt := results["int"]
+	if haveString && haveInt {
+		fmt.Println("Int keys vs string keys:")
+		fmt.Printf("  Insert time difference: %.6f seconds (int keys %.2fx faster)\n",
+			(stringResult.insertTime - intResult.insertTime).Seconds(),
+			stringResult.insertTime.Seconds()/intResult.insertTime.Seconds())
+		fmt.Printf("  Lookup time difference: %.6f seconds (int keys %.2fx faster)\n",
+			(stringResult.lookupTime - intResult.lookupTime).Seconds(),
+			stringResult.lookupTime.Seconds()/intResult.lookupTime.Seconds())
+	}
 	
-	fmt.Println("Result:")
-	fmt.Printf("  Inserted: %d items\n", numOperations)
-	fmt.Printf("  Found: %d/%d items\n", foundCount, numOperations)
-	fmt.Printf("  Deleted: %d items\n", deletedCount)
-	fmt.Printf("  Remaining: %d items\n", len(hashTable))
-	fmt.Println("Timing:")
-	fmt.Printf("  Insert time: %.6f seconds\n", insertTime.Seconds())
-	fmt.Printf("  Lookup time: %.6f seconds\n", lookupTime.Seconds())
-	fmt.Printf("  Delete time: %.6f seconds\n", deleteTime.Seconds())
-	fmt.Printf("  Total time: %.6f seconds\n", totalTime.Seconds())
+	if collisionStrategy != "" {
+		// Robin Hood is measured against linear probing on the same keys
+		strategies := []string{collisionStrategy}
+		if collisionStrategy == "robin_hood" {
+			strategies = []string{"linear_probing", "robin_hood"}
+		}
+		keys := intKeys(numOperations)
+		var openResults []openCaseResult
+		for _, strategy := range strategies {
+			result := runOpenCase(strategy, keys, values, loadFactor)
+			openResults = append(openResults, result)
+			allCorrect = allCorrect && result.correct
+			
+			fmt.Printf("Result (int keys, open addressing, %s):\n", strategy)
+			fmt.Printf("  Capacity: %d slots (load factor %.2f)\n", result.capacity,
+				float64(numOperations)/float64(result.capacity))
+			fmt.Printf("  Found: %d/%d items\n", result.foundCount, numOperations)
+			fmt.Printf("  Deleted: %d items\n", result.deleted)
+			fmt.Printf("  Remaining: %d items\n", result.remaining)
+			fmt.Printf("  Correct: %t\n", result.correct)
+			fmt.Printf("  Probe length: avg %.3f, max %d, std dev %.3f\n",
+				result.probes.avg, result.probes.max, result.probes.stddev)
+			fmt.Println("Timing:")
+			fmt.Printf("  Insert time: %.6f seconds\n", result.insertTime.Seconds())
+			fmt.Printf("  Lookup time: %.6f seconds\n", result.lookupTime.Seconds())
+			fmt.Printf("  Delete time: %.6f seconds\n", result.deleteTime.Seconds())
+		}
+		
+		if len(openResults) == 2 {
+			linear, robinHood := openResults[0], openResults[1]
+			fmt.Println("Robin Hood vs linear probing:")
+			fmt.Printf("  Max probe length: %d vs %d\n", robinHood.probes.max, linear.probes.max)
+			fmt.Printf("  Probe length std dev: %.3f vs %.3f\n", robinHood.probes.stddev, linear.probes.stddev)
+			fmt.Printf("  Lookup time difference: %.6f seconds (Robin Hood %.2fx faster)\n",
+				(linear.lookupTime - robinHood.lookupTime).Seconds(),
+				linear.lookupTime.Seconds()/robinHood.lookupTime.Seconds())
+		}
+	}
+	
+	if !allCorrect {
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// TestOpenTableHeavyCollisions drives both strategies through a nearly full
+// table whose keys all hash to the last two slots or the first, so every
+// run is long and wraps around the end. Lookups, deletes and re-inserts are
+// checked against a map.
+func TestOpenTableHeavyCollisions(t *testing.T) {
+	const capacity, count = 64, 58
+	for _, robinHood := range []bool{false, true} {
+		t.Run(fmt.Sprintf("robinHood=%v", robinHood), func(t *testing.T) {
+			table := newOpenTable(capacity, robinHood)
+			var keys, missing []int
+			for key := 0; len(keys) < count || len(missing) < count; key++ {
+				if home := table.home(key); home == 0 || home >= capacity-2 {
+					if len(keys) < count {
+						keys = append(keys, key)
+					} else {
+						missing = append(missing, key)
+					}
+				}
+			}
+
+			expected := make(map[int]int, count)
+			for i, key := range keys {
+				table.Put(key, i)
+				expected[key] = i
+			}
+			for i := 0; i < count; i += 2 {
+				table.Delete(keys[i])
+				delete(expected, keys[i])
+			}
+			for i := 0; i < count; i += 4 {
+				table.Put(keys[i], -i)
+				expected[keys[i]] = -i
+			}
+
+			if table.count != len(expected) {
+				t.Errorf("count = %d, want %d", table.count, len(expected))
+			}
+			for _, key := range append(keys, missing...) {
+				value, ok := table.Get(key)
+				want, exists := expected[key]
+				if ok != exists || value != want {
+					t.Errorf("Get(%d) = %d, %v, want %d, %v", key, value, ok, want, exists)
+				}
+			}
+		})
+	}
+}
+
+func TestDistinctStrings(t *testing.T) {
+	tests := []struct {
+		length int
+		want   int
+	}{
+		{0, 1},
+		{1, 62},
+		{2, 3844},
+		{10, 839299365868340224},
+		{11, math.MaxInt},
+		{100, math.MaxInt},
+	}
+	for _, tt := range tests {
+		if got := distinctStrings(tt.length); got != tt.want {
+			t.Errorf("distinctStrings(%d) = %d, want %d", tt.length, got, tt.want)
+		}
+	}
+
+	// Every possible key is found when n is exactly the limit.
+	if keys := stringKeys(62, 1); len(keys) != 62 {
+		t.Errorf("stringKeys(62, 1) returned %d keys", len(keys))
+	}
+}
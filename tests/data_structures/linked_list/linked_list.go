@@ -4,10 +4,29 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"math/rand"
+	"os"
 	"time"
 )
 
+// Config is the optional config file. Structure "skip_list" runs the
+// insert, search and delete operations on a SkipList as well as on the
+// linked list and compares the two; the default is "linked_list".
+// SortSizes are the list lengths Sort is timed on, SortIterations how many
+// lists of each length are sorted, and SortOrders the order the values are
+// inserted in: "random", "sorted" or "reversed".
+type Config struct {
+	Parameters struct {
+		Operations     int      `json:"operations"`
+		SortSizes      []int    `json:"sort_sizes"`
+		SortIterations int      `json:"sort_iterations"`
+		SortOrders     []string `json:"sort_orders"`
+		Structure      string   `json:"structure"`
+	} `json:"parameters"`
+}
+
 // ListNode represents a node in the linked list
 type ListNode struct {
 	Val  int
@@ -76,38 +95,417 @@ func (ll *LinkedList) GetSize() int {
 	return ll.Size
 }
 
+// Sort orders the list ascending with a bottom-up merge sort: runs of width
+// 1, 2, 4, ... are merged pairwise by relinking nodes, so no extra memory or
+// recursion is needed. The sort is stable.
+func (ll *LinkedList) Sort() {
+	dummy := &ListNode{Next: ll.Head}
+	for width := 1; width < ll.Size; width *= 2 {
+		tail := dummy
+		current := dummy.Next
+		for current != nil {
+			left := current
+			right := splitAfter(left, width)
+			current = splitAfter(right, width)
+			tail = mergeLists(left, right, tail)
+		}
+	}
+	ll.Head = dummy.Next
+}
+
+// splitAfter cuts the list after its first n nodes and returns the rest
+func splitAfter(head *ListNode, n int) *ListNode {
+	for i := 1; head != nil && i < n; i++ {
+		head = head.Next
+	}
+	if head == nil {
+		return nil
+	}
+	rest := head.Next
+	head.Next = nil
+	return rest
+}
+
+// mergeLists links the merge of the sorted lists a and b after tail and
+// returns the last node of the merged run
+func mergeLists(a, b, tail *ListNode) *ListNode {
+	for a != nil && b != nil {
+		if a.Val <= b.Val {
+			tail.Next = a
+			a = a.Next
+		} else {
+			tail.Next = b
+			b = b.Next
+		}
+		tail = tail.Next
+	}
+	if a != nil {
+		tail.Next = a
+	} else {
+		tail.Next = b
+	}
+	for tail.Next != nil {
+		tail = tail.Next
+	}
+	return tail
+}
+
+// skipListMaxLevel caps the levels of a skip list node; with
+// skipListPromotion 1/2 it suits lists of up to 2^32 elements.
+const (
+	skipListMaxLevel  = 32
+	skipListPromotion = 0.5
+)
+
+// SkipNode is a skip list node with one forward link per level it is on
+type SkipNode struct {
+	Val  int
+	Next []*SkipNode
+}
+
+// SkipList is a sorted linked list with extra express lanes: each node is
+// also linked on a random number of higher levels, each holding about half
+// the nodes of the one below, so a search skips ahead in O(log n) expected
+// steps. Levels come from the list's own seeded source, making a run
+// reproducible.
+type SkipList struct {
+	head        *SkipNode
+	level       int
+	Size        int
+	rng         *rand.Rand
+	comparisons int
+}
+
+// NewSkipList creates an empty skip list drawing levels from seed
+func NewSkipList(seed int64) *SkipList {
+	return &SkipList{
+		head:  &SkipNode{Next: make([]*SkipNode, skipListMaxLevel)},
+		level: 1,
+		rng:   rand.New(rand.NewSource(seed)),
+	}
+}
+
+// randomLevel returns the number of levels for a new node: 1, then one
+// more with probability skipListPromotion each time
+func (sl *SkipList) randomLevel() int {
+	level := 1
+	for level < skipListMaxLevel && sl.rng.Float64() < skipListPromotion {
+		level++
+	}
+	return level
+}
+
+// findPredecessors fills update with the last node before val on each
+// level and returns the node after it on level 0, which holds val if val
+// is in the list. Every key comparison is counted.
+func (sl *SkipList) findPredecessors(val int, update []*SkipNode) *SkipNode {
+	node := sl.head
+	for i := sl.level - 1; i >= 0; i-- {
+		for node.Next[i] != nil {
+			sl.comparisons++
+			if node.Next[i].Val >= val {
+				break
+			}
+			node = node.Next[i]
+		}
+		if update != nil {
+			update[i] = node
+		}
+	}
+	return node.Next[0]
+}
+
+// Insert adds a value in sorted position, ignoring duplicates
+func (sl *SkipList) Insert(val int) {
+	update := make([]*SkipNode, skipListMaxLevel)
+	if next := sl.findPredecessors(val, update); next != nil && next.Val == val {
+		return
+	}
+	
+	level := sl.randomLevel()
+	for i := sl.level; i < level; i++ {
+		update[i] = sl.head
+	}
+	if level > sl.level {
+		sl.level = level
+	}
+	
+	node := &SkipNode{Val: val, Next: make([]*SkipNode, level)}
+	for i := 0; i < level; i++ {
+		node.Next[i] = update[i].Next[i]
+		update[i].Next[i] = node
+	}
+	sl.Size++
+}
+
+// Search reports whether val is in the list
+func (sl *SkipList) Search(val int) bool {
+	next := sl.findPredecessors(val, nil)
+	if next != nil {
+		sl.comparisons++
+		return next.Val == val
+	}
+	return false
+}
+
+// Delete removes val, unlinking it on every level it is on
+func (sl *SkipList) Delete(val int) bool {
+	update := make([]*SkipNode, skipListMaxLevel)
+	node := sl.findPredecessors(val, update)
+	if node == nil || node.Val != val {
+		return false
+	}
+	
+	for i := range node.Next {
+		update[i].Next[i] = node.Next[i]
+	}
+	for sl.level > 1 && sl.head.Next[sl.level-1] == nil {
+		sl.level--
+	}
+	sl.Size--
+	return true
+}
+
+// GetSize returns the size of the list
+func (sl *SkipList) GetSize() int {
+	return sl.Size
+}
+
+// Comparisons returns the number of key comparisons made so far
+func (sl *SkipList) Comparisons() int {
+	return sl.comparisons
+}
+
+// ResetComparisons zeroes the comparison count
+func (sl *SkipList) ResetComparisons() {
+	sl.comparisons = 0
+}
+
+// Values returns the values on level 0, which are in ascending order
+func (sl *SkipList) Values() []int {
+	values := make([]int, 0, sl.Size)
+	for node := sl.head.Next[0]; node != nil; node = node.Next[0] {
+		values = append(values, node.Val)
+	}
+	return values
+}
+
+// buildList returns a list holding 0..size-1, inserted so that a forward
+// traversal sees them in the given order
+func buildList(size int, order string) *LinkedList {
+	values := make([]int, size)
+	for i := range values {
+		switch order {
+		case "sorted":
+			values[i] = i
+		case "reversed":
+			values[i] = size - 1 - i
+		}
+	}
+	if order == "random" {
+		values = rand.Perm(size)
+	}
+	
+	list := NewLinkedList()
+	for i := size - 1; i >= 0; i-- {
+		list.Insert(values[i])
+	}
+	return list
+}
+
+// isSortedPermutation walks the list forward and reports whether it holds
+// exactly 0..size-1 in ascending order
+func isSortedPermutation(list *LinkedList, size int) bool {
+	expected := 0
+	for node := list.Head; node != nil; node = node.Next {
+		if node.Val != expected {
+			return false
+		}
+		expected++
+	}
+	return expected == size
+}
+
 func main() {
 	fmt.Println("Starting linked list benchmark...")
+	
+	operationsCount := 10000
+	sortSizes := []int{10000}
+	sortIterations := 5
+	sortOrders := []string{"random"}
+	structure := "linked_list"
+	
+	if len(os.Args) > 1 {
+		var config Config
+		data, err := os.ReadFile(os.Args[1])
+		if err == nil {
+			err = json.Unmarshal(data, &config)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading config: %v\n", err)
+			os.Exit(1)
+		}
+		if config.Parameters.Operations > 0 {
+			operationsCount = config.Parameters.Operations
+		}
+		if len(config.Parameters.SortSizes) > 0 {
+			sortSizes = config.Parameters.SortSizes
+		}
+		if config.Parameters.SortIterations > 0 {
+			sortIterations = config.Parameters.SortIterations
+		}
+		if len(config.Parameters.SortOrders) > 0 {
+			sortOrders = config.Parameters.SortOrders
+		}
+		if config.Parameters.Structure != "" {
+			structure = config.Parameters.Structure
+		}
+	}
+	
+	if structure != "linked_list" && structure != "skip_list" {
+		fmt.Fprintf(os.Stderr, "Unknown structure '%s' (expected linked_list or skip_list)\n", structure)
+		os.Exit(1)
+	}
+	
+	for _, order := range sortOrders {
+		switch order {
+		case "random", "sorted", "reversed":
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown sort order '%s' (expected random, sorted or reversed)\n", order)
+			os.Exit(1)
+		}
+	}
+	for _, size := range sortSizes {
+		if size < 0 {
+			fmt.Fprintf(os.Stderr, "sort_sizes must not be negative, got %d\n", size)
+			os.Exit(1)
+		}
+	}
+	
 	startTime := time.Now()
 	
 	linkedList := NewLinkedList()
-	operationsCount := 10000
 	
 	// Insert operations
+	phaseStart := time.Now()
 	for i := 0; i < operationsCount; i++ {
 		linkedList.Insert(i)
 	}
+	insertTime := time.Since(phaseStart)
 	
-	// Search operations
-	foundCount := 0
+	// Search operations. A search compares against every node up to the
+	// one it finds, or all of them when the value is missing.
+	phaseStart = time.Now()
+	foundCount, searchCount, comparisons := 0, 0, 0
 	for i := 0; i < operationsCount; i += 100 {
-		if linkedList.Search(i) != -1 {
+		searchCount++
+		if position := linkedList.Search(i); position != -1 {
 			foundCount++
+			comparisons += position + 1
+		} else {
+			comparisons += linkedList.GetSize()
 		}
 	}
+	searchTime := time.Since(phaseStart)
 	
 	// Delete operations
+	phaseStart = time.Now()
 	deletedCount := 0
 	for i := 0; i < operationsCount; i += 200 {
 		if linkedList.Delete(i) {
 			deletedCount++
 		}
 	}
+	deleteTime := time.Since(phaseStart)
 	
 	executionTime := time.Since(startTime)
 	
 	fmt.Printf("Operations completed: %d inserts, %d searches, %d deletes\n", 
 		operationsCount, foundCount, deletedCount)
 	fmt.Printf("Final list size: %d\n", linkedList.GetSize())
+	
+	if structure == "skip_list" {
+		// The same operations on a skip list, whose inserts keep it sorted
+		skipList := NewSkipList(42)
+		phaseStart = time.Now()
+		for i := 0; i < operationsCount; i++ {
+			skipList.Insert(i)
+		}
+		skipInsertTime := time.Since(phaseStart)
+		
+		skipList.ResetComparisons()
+		phaseStart = time.Now()
+		skipFoundCount := 0
+		for i := 0; i < operationsCount; i += 100 {
+			if skipList.Search(i) {
+				skipFoundCount++
+			}
+		}
+		skipSearchTime := time.Since(phaseStart)
+		skipComparisons := skipList.Comparisons()
+		
+		phaseStart = time.Now()
+		skipDeletedCount := 0
+		for i := 0; i < operationsCount; i += 200 {
+			if skipList.Delete(i) {
+				skipDeletedCount++
+			}
+		}
+		skipDeleteTime := time.Since(phaseStart)
+		
+		// Both structures must end up holding the same values
+		values := skipList.Values()
+		matches := skipFoundCount == foundCount && skipDeletedCount == deletedCount &&
+			skipList.GetSize() == linkedList.GetSize() && len(values) == skipList.GetSize()
+		for i := 1; matches && i < len(values); i++ {
+			matches = values[i-1] < values[i]
+		}
+		inLinkedList := make(map[int]bool, linkedList.GetSize())
+		for node := linkedList.Head; node != nil; node = node.Next {
+			inLinkedList[node.Val] = true
+		}
+		for _, val := range values {
+			matches = matches && inLinkedList[val]
+		}
+		
+		fmt.Printf("Skip list operations completed: %d inserts, %d searches, %d deletes\n",
+			operationsCount, skipFoundCount, skipDeletedCount)
+		fmt.Printf("Skip list final size: %d, matches linked list: %t\n", skipList.GetSize(), matches)
+		fmt.Printf("Insert time: linked list %.6f s, skip list %.6f s\n",
+			insertTime.Seconds(), skipInsertTime.Seconds())
+		fmt.Printf("Search time: linked list %.6f s, skip list %.6f s\n",
+			searchTime.Seconds(), skipSearchTime.Seconds())
+		fmt.Printf("Delete time: linked list %.6f s, skip list %.6f s\n",
+			deleteTime.Seconds(), skipDeleteTime.Seconds())
+		fmt.Printf("Average search comparisons: linked list %.1f, skip list %.1f\n",
+			float64(comparisons)/float64(searchCount), float64(skipComparisons)/float64(searchCount))
+		if !matches {
+			os.Exit(1)
+		}
+	}
+	
+	// Sort phase: each list is built untimed, then sorted and checked
+	rand.Seed(42) // For reproducible results
+	for _, order := range sortOrders {
+		for _, size := range sortSizes {
+			var sortTime time.Duration
+			sorted := true
+			for i := 0; i < sortIterations; i++ {
+				list := buildList(size, order)
+				start := time.Now()
+				list.Sort()
+				sortTime += time.Since(start)
+				if !isSortedPermutation(list, size) {
+					sorted = false
+				}
+			}
+			fmt.Printf("Sort (%s, %d nodes): AvgSortTime %.6f seconds, sorted: %t\n",
+				order, size, sortTime.Seconds()/float64(sortIterations), sorted)
+			if !sorted {
+				os.Exit(1)
+			}
+		}
+	}
+	
 	fmt.Printf("Execution time: %.6f seconds\n", executionTime.Seconds())
 }
\ No newline at end of file
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// TestSkipList inserts a shuffled range into a seeded skip list, deletes
+// every third value and checks that Search, Delete and the level-0 order
+// agree with what is left.
+func TestSkipList(t *testing.T) {
+	const n = 1000
+	sl := NewSkipList(1)
+	for _, val := range rand.New(rand.NewSource(2)).Perm(n) {
+		sl.Insert(val)
+	}
+	sl.Insert(0)
+	if sl.GetSize() != n {
+		t.Fatalf("GetSize() = %d after inserting %d values and a duplicate, want %d", sl.GetSize(), n, n)
+	}
+
+	for i := 0; i < n; i += 3 {
+		if !sl.Delete(i) {
+			t.Fatalf("Delete(%d) = false for a present value", i)
+		}
+		if sl.Delete(i) {
+			t.Fatalf("Delete(%d) = true for an already deleted value", i)
+		}
+	}
+
+	expected := 0
+	for _, val := range sl.Values() {
+		for expected%3 == 0 {
+			expected++
+		}
+		if val != expected {
+			t.Fatalf("level 0 holds %d where %d was expected", val, expected)
+		}
+		expected++
+	}
+	for i := -1; i <= n; i++ {
+		if want := i >= 0 && i < n && i%3 != 0; sl.Search(i) != want {
+			t.Errorf("Search(%d) = %v, want %v", i, !want, want)
+		}
+	}
+	if want := n - (n+2)/3; sl.GetSize() != want {
+		t.Errorf("GetSize() = %d after deletes, want %d", sl.GetSize(), want)
+	}
+}
+
+func TestSkipListComparisons(t *testing.T) {
+	sl := NewSkipList(42)
+	for i := 0; i < 1024; i++ {
+		sl.Insert(i)
+	}
+	sl.ResetComparisons()
+	for i := 0; i < 1024; i++ {
+		sl.Search(i)
+	}
+	// Expected search cost is O(log n); a linked list averages n/2 = 512.
+	if avg := float64(sl.Comparisons()) / 1024; avg <= 0 || avg > 100 {
+		t.Errorf("average comparisons per search = %.1f, want O(log n)", avg)
+	}
+}
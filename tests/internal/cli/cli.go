@@ -0,0 +1,207 @@
+// Package cli implements the command line shared by the Go benchmarks:
+//
+//	<benchmark> [-iterations N] [-seed N] [-output FILE] [-format F]
+//	            [-baseline FILE [-regression-threshold PCT]] -config FILE
+//	<benchmark> [flags] FILE
+//
+// The positional config path is what the orchestrator passes and remains
+// supported. Flags given on the command line override the matching keys of
+// the config's "parameters" object.
+//
+// Only the benchmarks that write JSON results use this package. The
+// algorithm, data-structure and math programs print a plain-text report,
+// which -format, -output and -baseline cannot apply to, and their optional
+// config is read from os.Args[1] directly.
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Options holds the parsed command line.
+type Options struct {
+	ConfigPath string
+	Iterations int
+	Output     string
+	Seed       int64
+	Format     string
+	CSVDetail  bool
+	Name       string
+
+	// Baseline is a JSON results file from an earlier run. When set,
+	// WriteResults fails if a test case average is more than
+	// RegressionThreshold percent worse than in the baseline.
+	Baseline            string
+	RegressionThreshold float64
+
+	// IterationsKey is the parameters key that -iterations overrides.
+	// Benchmarks that count packets or requests instead of iterations set it
+	// before calling LoadConfig.
+	IterationsKey string
+
+	seedSet bool
+}
+
+// Parse parses args (without the program name). Invalid flags and -h exit
+// the process with the flag package's usage message.
+func Parse(name string, args []string) (*Options, error) {
+	opts := &Options{IterationsKey: "iterations", Name: name}
+
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.StringVar(&opts.ConfigPath, "config", "", "path to the JSON config file")
+	fs.IntVar(&opts.Iterations, "iterations", 0, "override parameters.iterations")
+	fs.StringVar(&opts.Output, "output", "", "write results to this file instead of stdout")
+	fs.Int64Var(&opts.Seed, "seed", 0, "override parameters.seed")
+	fs.StringVar(&opts.Format, "format", formatJSON, "output format: "+strings.Join(formats, ", "))
+	fs.BoolVar(&opts.CSVDetail, "csv-detail", false, "with -format csv, emit one row per iteration")
+	fs.StringVar(&opts.Baseline, "baseline", "", "compare averages with this earlier JSON result file and fail on regressions")
+	fs.Float64Var(&opts.RegressionThreshold, "regression-threshold", defaultRegressionThreshold, "with -baseline, percent change counted as a regression")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s [flags] <config_file>\n", name)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "seed" {
+			opts.seedSet = true
+		}
+	})
+
+	if opts.ConfigPath == "" && fs.NArg() > 0 {
+		opts.ConfigPath = fs.Arg(0)
+	}
+	if opts.ConfigPath == "" {
+		fs.Usage()
+		return nil, errors.New("no config file given")
+	}
+	if opts.RegressionThreshold < 0 {
+		return nil, fmt.Errorf("-regression-threshold must not be negative")
+	}
+	if !isKnownFormat(opts.Format) {
+		return nil, fmt.Errorf("unknown format '%s' (expected one of: %s)", opts.Format, strings.Join(formats, ", "))
+	}
+
+	return opts, nil
+}
+
+// LoadConfig reads the config file into config, applying command line
+// overrides to its "parameters" object first. Unknown parameters and values
+// of the wrong type are rejected, and a config implementing Validator is
+// validated before LoadConfig returns.
+func (o *Options) LoadConfig(config interface{}) error {
+	data, err := os.ReadFile(o.ConfigPath)
+	if err != nil {
+		return fmt.Errorf("cannot read config file '%s': %v", o.ConfigPath, err)
+	}
+
+	if o.Iterations > 0 || o.seedSet {
+		data, err = o.applyOverrides(data)
+		if err != nil {
+			return fmt.Errorf("invalid JSON in config file: %v", err)
+		}
+	}
+
+	if err := json.Unmarshal(data, config); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return fmt.Errorf("invalid config file '%s': %v", o.ConfigPath, describeDecodeError(err))
+		}
+		return fmt.Errorf("invalid JSON in config file: %v", err)
+	}
+	if err := checkParameters(data, config); err != nil {
+		return fmt.Errorf("invalid config file '%s': %v", o.ConfigPath, err)
+	}
+	if validator, ok := config.(Validator); ok {
+		if err := validator.Validate(); err != nil {
+			return fmt.Errorf("invalid config file '%s': %v", o.ConfigPath, err)
+		}
+	}
+	return nil
+}
+
+func (o *Options) applyOverrides(data []byte) ([]byte, error) {
+	var document map[string]interface{}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+
+	parameters, _ := document["parameters"].(map[string]interface{})
+	if parameters == nil {
+		parameters = make(map[string]interface{})
+		document["parameters"] = parameters
+	}
+	if o.Iterations > 0 {
+		parameters[o.IterationsKey] = o.Iterations
+	}
+	if o.seedSet {
+		parameters["seed"] = o.Seed
+	}
+
+	return json.Marshal(document)
+}
+
+// WriteResults renders results in the selected format and writes them to
+// stdout, or atomically to the -output file when one was given, leaving
+// stdout empty. With -baseline, the results are then compared with the
+// baseline and regressions are returned as an error.
+func (o *Options) WriteResults(results interface{}) error {
+	output, err := o.render(results)
+	if err != nil {
+		return err
+	}
+
+	if o.Output == "" {
+		fmt.Print(string(output))
+	} else if err := writeFileAtomic(o.Output, output); err != nil {
+		return fmt.Errorf("failed to write results to '%s': %v", o.Output, err)
+	}
+
+	if o.Baseline != "" {
+		return o.compareWithBaseline(results)
+	}
+	return nil
+}
+
+// writeFileAtomic writes data to a temporary file in the same directory as
+// path and renames it into place, so readers never observe a partial file.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Seed returns configured when it is set, otherwise a new time-based seed.
+// Generated seeds fit in 53 bits so they survive a round trip through JSON
+// numbers and can be passed back as parameters.seed to replay a run.
+func Seed(configured *int64) int64 {
+	if configured != nil {
+		return *configured
+	}
+	return time.Now().UnixNano() & (1<<53 - 1)
+}
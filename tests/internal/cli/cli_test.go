@@ -0,0 +1,144 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// testConfig is shaped like a benchmark config: a top-level name and the
+// tags list inside parameters.
+type testConfig struct {
+	Name       string `json:"name"`
+	Parameters struct {
+		Iterations int      `json:"iterations"`
+		Tags       []string `json:"tags"`
+	} `json:"parameters"`
+}
+
+type testResults struct {
+	SchemaVersion string   `json:"schema_version"`
+	Name          string   `json:"name,omitempty"`
+	Tags          []string `json:"tags,omitempty"`
+}
+
+func writeConfig(t *testing.T, config string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestTagsRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		wantName string
+		wantTags []string
+	}{
+		{"name and tags", `{"name": "nightly-run", "parameters": {"tags": ["nightly", "smoke"]}}`, "nightly-run", []string{"nightly", "smoke"}},
+		{"tags only", `{"parameters": {"iterations": 2, "tags": ["smoke"]}}`, "", []string{"smoke"}},
+		{"neither", `{"parameters": {"iterations": 2}}`, "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := filepath.Join(t.TempDir(), "results.json")
+			opts, err := Parse("test", []string{"-output", output, writeConfig(t, tt.config)})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var config testConfig
+			if err := opts.LoadConfig(&config); err != nil {
+				t.Fatal(err)
+			}
+			results := testResults{SchemaVersion: SchemaVersion, Name: config.Name, Tags: config.Parameters.Tags}
+			if err := opts.WriteResults(results); err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := os.ReadFile(output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var written map[string]interface{}
+			if err := json.Unmarshal(data, &written); err != nil {
+				t.Fatal(err)
+			}
+			if name, _ := written["name"].(string); name != tt.wantName {
+				t.Errorf("name = %q, want %q", name, tt.wantName)
+			}
+			var tags []string
+			if raw, ok := written["tags"].([]interface{}); ok {
+				for _, tag := range raw {
+					tags = append(tags, tag.(string))
+				}
+			}
+			if !reflect.DeepEqual(tags, tt.wantTags) {
+				t.Errorf("tags = %q, want %q", tags, tt.wantTags)
+			}
+		})
+	}
+}
+
+func TestLoadConfigRejectsUnknownParameter(t *testing.T) {
+	opts, err := Parse("test", []string{writeConfig(t, `{"parameters": {"tag": ["smoke"]}}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var config testConfig
+	err = opts.LoadConfig(&config)
+	want := `invalid config file '` + opts.ConfigPath + `': parameters.tag: unknown field (did you mean "tags"?)`
+	if err == nil || err.Error() != want {
+		t.Errorf("LoadConfig error = %v, want %s", err, want)
+	}
+}
+
+func TestFlagsOverrideConfig(t *testing.T) {
+	type overrideConfig struct {
+		Parameters struct {
+			Iterations  int    `json:"iterations"`
+			PacketCount int    `json:"packet_count"`
+			Seed        *int64 `json:"seed"`
+		} `json:"parameters"`
+	}
+	path := writeConfig(t, `{"parameters": {"iterations": 2, "packet_count": 4, "seed": 1}}`)
+
+	tests := []struct {
+		name          string
+		args          []string
+		iterationsKey string
+		iterations    int
+		packetCount   int
+		seed          int64
+	}{
+		{"config only", []string{path}, "", 2, 4, 1},
+		{"-iterations", []string{"-iterations", "7", path}, "", 7, 4, 1},
+		{"-seed", []string{"-seed", "0", "-config", path}, "", 2, 4, 0},
+		{"custom iterations key", []string{"-iterations", "9", path}, "packet_count", 2, 9, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := Parse("test", tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.iterationsKey != "" {
+				opts.IterationsKey = tt.iterationsKey
+			}
+			var config overrideConfig
+			if err := opts.LoadConfig(&config); err != nil {
+				t.Fatal(err)
+			}
+			p := config.Parameters
+			if p.Iterations != tt.iterations || p.PacketCount != tt.packetCount || p.Seed == nil || *p.Seed != tt.seed {
+				t.Errorf("parameters = iterations %d, packet_count %d, seed %v; want %d, %d, %d",
+					p.Iterations, p.PacketCount, p.Seed, tt.iterations, tt.packetCount, tt.seed)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/csv"
+)
+
+// renderCSV writes one row per test case with the benchmark name followed by
+// the case's flattened scalar fields in sorted column order, so the header
+// is stable across runs. With detail set, each case is expanded into one row
+// per iteration, with the iteration's fields prefixed by "iteration.".
+func renderCSV(benchmark string, document map[string]interface{}, detail bool) ([]byte, error) {
+	var rows []map[string]string
+	for _, testCase := range testCases(document) {
+		row := make(map[string]string)
+		flatten("", testCase, row)
+
+		records := detailRecords(testCase)
+		if !detail || len(records) == 0 {
+			rows = append(rows, row)
+			continue
+		}
+		for _, record := range records {
+			detailRow := make(map[string]string, len(row))
+			for k, v := range row {
+				detailRow[k] = v
+			}
+			flatten("iteration.", record, detailRow)
+			rows = append(rows, detailRow)
+		}
+	}
+
+	columns := columnsOf(rows)
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	writer.Write(append([]string{"benchmark"}, columns...))
+	for _, row := range rows {
+		record := make([]string, 0, len(columns)+1)
+		record = append(record, benchmark)
+		for _, column := range columns {
+			record = append(record, row[column])
+		}
+		writer.Write(record)
+	}
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
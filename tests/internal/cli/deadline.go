@@ -0,0 +1,23 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Deadline returns the context a benchmark run executes under. With a
+// positive parameters.deadline_seconds it is cancelled once that much time
+// has passed; otherwise it only ends when cancel is called.
+func Deadline(seconds int) (context.Context, context.CancelFunc) {
+	if seconds <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), time.Duration(seconds)*time.Second)
+}
+
+// TimedOut reports whether ctx ended because its deadline passed, which
+// benchmarks record as summary.timed_out.
+func TimedOut(ctx context.Context) bool {
+	return errors.Is(ctx.Err(), context.DeadlineExceeded)
+}
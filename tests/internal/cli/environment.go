@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"os"
+	"runtime"
+)
+
+// Environment describes the machine and Go runtime a benchmark ran on, so
+// results from different hosts can be told apart.
+type Environment struct {
+	GoVersion  string `json:"go_version"`
+	GOOS       string `json:"goos"`
+	GOARCH     string `json:"goarch"`
+	NumCPU     int    `json:"num_cpu"`
+	GOMAXPROCS int    `json:"gomaxprocs"`
+	Hostname   string `json:"hostname"`
+}
+
+// CurrentEnvironment returns the Environment of the running process. The
+// hostname is "unknown" when the OS does not report one.
+func CurrentEnvironment() Environment {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "unknown"
+	}
+	return Environment{
+		GoVersion:  runtime.Version(),
+		GOOS:       runtime.GOOS,
+		GOARCH:     runtime.GOARCH,
+		NumCPU:     runtime.NumCPU(),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		Hostname:   hostname,
+	}
+}
+
+// SetGOMAXPROCS sets GOMAXPROCS to n when n is positive, so a benchmark can
+// be repeated with different core counts, and returns a function restoring
+// the previous value. Call it before CurrentEnvironment so the effective
+// value is the one reported.
+func SetGOMAXPROCS(n int) (restore func()) {
+	if n <= 0 {
+		return func() {}
+	}
+	previous := runtime.GOMAXPROCS(n)
+	return func() { runtime.GOMAXPROCS(previous) }
+}
@@ -0,0 +1,215 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	formatJSON       = "json"
+	formatCSV        = "csv"
+	formatPrometheus = "prometheus"
+	formatMarkdown   = "markdown"
+)
+
+var formats = []string{formatJSON, formatCSV, formatPrometheus, formatMarkdown}
+
+// SchemaVersion is reported as schema_version by every benchmark's results.
+// Bump the major version when a field is removed, renamed or changes
+// meaning, and the minor version when fields are added.
+const SchemaVersion = "1.2"
+
+// caseKeys are the top-level result keys holding per-test-case data, either
+// as a list (test_cases) or keyed by target (http_request's urls and
+// ping_test's targets).
+var caseKeys = []string{"test_cases", "urls", "targets"}
+
+// detailKeys are the per-case lists expanded into one row each by
+// -csv-detail.
+var detailKeys = []string{"iterations", "requests"}
+
+// detailScalarKeys are per-case lists of bare measurements that -csv-detail
+// also expands, one row per value, under the field name given here: ping
+// test's round-trip times, one per packet.
+var detailScalarKeys = map[string]string{"rtts": "rtt"}
+
+func isKnownFormat(format string) bool {
+	for _, f := range formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *Options) render(results interface{}) ([]byte, error) {
+	output, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal results: %v", err)
+	}
+	if o.Format == formatJSON {
+		return append(output, '\n'), nil
+	}
+
+	// The tabular formats work on the JSON form of the results, so they
+	// apply to every benchmark without knowing its result types.
+	var document map[string]interface{}
+	if err := json.Unmarshal(output, &document); err != nil {
+		return nil, fmt.Errorf("failed to decode results: %v", err)
+	}
+
+	switch o.Format {
+	case formatCSV:
+		return renderCSV(o.benchmarkName(), document, o.CSVDetail)
+	case formatPrometheus:
+		return renderPrometheus(o.benchmarkName(), document)
+	case formatMarkdown:
+		return renderMarkdown(o.benchmarkName(), document)
+	default:
+		return nil, fmt.Errorf("unknown format '%s'", o.Format)
+	}
+}
+
+// benchmarkName strips the extension and the orchestrator's "_go" suffix
+// from the binary name.
+func (o *Options) benchmarkName() string {
+	name := strings.TrimSuffix(o.Name, filepath.Ext(o.Name))
+	return strings.TrimSuffix(name, "_go")
+}
+
+// testCases returns the per-case objects of a results document. Cases keyed
+// by target get that key as a "name" field and are returned in key order.
+func testCases(document map[string]interface{}) []map[string]interface{} {
+	var cases []map[string]interface{}
+	for _, key := range caseKeys {
+		switch value := document[key].(type) {
+		case []interface{}:
+			for _, item := range value {
+				if testCase, ok := item.(map[string]interface{}); ok {
+					cases = append(cases, testCase)
+				}
+			}
+		case map[string]interface{}:
+			names := make([]string, 0, len(value))
+			for name := range value {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				if testCase, ok := value[name].(map[string]interface{}); ok {
+					named := map[string]interface{}{"name": name}
+					for k, v := range testCase {
+						named[k] = v
+					}
+					cases = append(cases, named)
+				}
+			}
+		}
+		if cases != nil {
+			break
+		}
+	}
+	return cases
+}
+
+// detailRecords returns the first per-iteration list of a test case, or
+// failing that its first list of bare measurements wrapped as records.
+// Records without an "iteration" field are numbered from 1 in one, so every
+// detail row can be told apart and ordered.
+func detailRecords(testCase map[string]interface{}) []map[string]interface{} {
+	var records []map[string]interface{}
+	for _, key := range detailKeys {
+		items, ok := testCase[key].([]interface{})
+		if !ok {
+			continue
+		}
+		for _, item := range items {
+			if record, ok := item.(map[string]interface{}); ok {
+				records = append(records, record)
+			}
+		}
+		break
+	}
+	if records == nil {
+		for key, field := range detailScalarKeys {
+			items, ok := testCase[key].([]interface{})
+			if !ok {
+				continue
+			}
+			for _, item := range items {
+				records = append(records, map[string]interface{}{field: item})
+			}
+			break
+		}
+	}
+
+	for i, record := range records {
+		if _, ok := record["iteration"]; !ok {
+			numbered := map[string]interface{}{"iteration": float64(i + 1)}
+			for k, v := range record {
+				numbered[k] = v
+			}
+			records[i] = numbered
+		}
+	}
+	return records
+}
+
+// flatten adds the scalar fields of value to into, joining nested object keys
+// with dots. Lists of scalars are joined with ";"; lists of objects are
+// skipped since they do not fit in a single row.
+func flatten(prefix string, value interface{}, into map[string]string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			flatten(prefix+key+".", item, into)
+		}
+	case []interface{}:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			switch item.(type) {
+			case map[string]interface{}, []interface{}:
+				return
+			}
+			parts = append(parts, formatScalar(item))
+		}
+		into[strings.TrimSuffix(prefix, ".")] = strings.Join(parts, ";")
+	default:
+		into[strings.TrimSuffix(prefix, ".")] = formatScalar(v)
+	}
+}
+
+func formatScalar(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// columnsOf returns the sorted union of the keys of rows.
+func columnsOf(rows []map[string]string) []string {
+	seen := make(map[string]bool)
+	var columns []string
+	for _, row := range rows {
+		for column := range row {
+			if !seen[column] {
+				seen[column] = true
+				columns = append(columns, column)
+			}
+		}
+	}
+	sort.Strings(columns)
+	return columns
+}
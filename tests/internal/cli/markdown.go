@@ -0,0 +1,134 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// renderMarkdown writes a report with an environment header, a table with one
+// row per test case (parameters first, then metrics) and the summary. Rows
+// are sorted by the primary time metric, slowest first.
+func renderMarkdown(benchmark string, document map[string]interface{}) ([]byte, error) {
+	var rows []map[string]string
+	for _, testCase := range testCases(document) {
+		row := make(map[string]string)
+		flatten("", testCase, row)
+		rows = append(rows, row)
+	}
+
+	var parameters, metrics []string
+	for _, column := range columnsOf(rows) {
+		if isMetricColumn(column, rows) {
+			metrics = append(metrics, column)
+		} else {
+			parameters = append(parameters, column)
+		}
+	}
+
+	primary := primaryTimeMetric(metrics)
+	if primary != "" {
+		sort.SliceStable(rows, func(i, j int) bool {
+			a, _ := strconv.ParseFloat(rows[i][primary], 64)
+			b, _ := strconv.ParseFloat(rows[j][primary], 64)
+			return a > b
+		})
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# %s benchmark results\n\n", benchmark)
+	fmt.Fprintf(&buf, "- Generated: %s\n", time.Now().Format(time.RFC3339))
+	fmt.Fprintf(&buf, "- Go: %s, %s/%s, %d CPUs\n", runtime.Version(), runtime.GOOS, runtime.GOARCH, runtime.NumCPU())
+	if primary != "" {
+		fmt.Fprintf(&buf, "- Sorted by: `%s` (slowest first)\n", primary)
+	}
+
+	if len(rows) > 0 {
+		buf.WriteString("\n## Test cases\n\n")
+		writeMarkdownTable(&buf, append(parameters, metrics...), rows)
+	}
+
+	if summary, ok := document["summary"].(map[string]interface{}); ok {
+		fields := make(map[string]string)
+		flatten("", summary, fields)
+		keys := make([]string, 0, len(fields))
+		for key := range fields {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		summaryRows := make([]map[string]string, 0, len(keys))
+		for _, key := range keys {
+			summaryRows = append(summaryRows, map[string]string{"metric": key, "value": fields[key]})
+		}
+		buf.WriteString("\n## Summary\n\n")
+		writeMarkdownTable(&buf, []string{"metric", "value"}, summaryRows)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// isMetricColumn reports whether column holds numeric measurements in every
+// row that has it.
+func isMetricColumn(column string, rows []map[string]string) bool {
+	if !metricPattern.MatchString(column) {
+		return false
+	}
+	for _, row := range rows {
+		if value, ok := row[column]; ok {
+			if _, err := strconv.ParseFloat(value, 64); err != nil {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// primaryMetrics are the end-to-end times of benchmarks that also report
+// per-phase times (e.g. http_request's connect and DNS times).
+var primaryMetrics = []string{"avg_response_time", "avg_resolution_time", "avg_latency"}
+
+// primaryTimeMetric picks the column rows are sorted by: a known end-to-end
+// time, then the first average time, then any time metric.
+func primaryTimeMetric(metrics []string) string {
+	for _, preferred := range primaryMetrics {
+		for _, metric := range metrics {
+			if metric == preferred {
+				return metric
+			}
+		}
+	}
+	for _, metric := range metrics {
+		if strings.HasPrefix(metric, "avg_") && strings.Contains(metric, "time") {
+			return metric
+		}
+	}
+	for _, metric := range metrics {
+		if strings.Contains(metric, "time") || strings.Contains(metric, "latency") {
+			return metric
+		}
+	}
+	return ""
+}
+
+func writeMarkdownTable(buf *bytes.Buffer, columns []string, rows []map[string]string) {
+	escaper := strings.NewReplacer("|", `\|`, "\n", " ")
+
+	buf.WriteString("| " + strings.Join(columns, " | ") + " |\n")
+	buf.WriteString("|" + strings.Repeat(" --- |", len(columns)) + "\n")
+	for _, row := range rows {
+		cells := make([]string, len(columns))
+		for i, column := range columns {
+			value := row[column]
+			if number, err := strconv.ParseFloat(value, 64); err == nil && strings.Contains(value, ".") {
+				value = strconv.FormatFloat(number, 'f', 3, 64)
+			}
+			cells[i] = escaper.Replace(value)
+		}
+		buf.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+	}
+}
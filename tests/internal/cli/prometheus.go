@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// metricPattern decides which numeric test case fields are measurements;
+// the remaining scalar fields (sizes, counts, levels, names) describe the
+// test case and become labels.
+var metricPattern = regexp.MustCompile(`^(avg|min|max|p\d+|total|successful|failed|best|worst|achieved)_|time|rate|ratio|throughput|latency|jitter|speedup|efficiency|loss|deviation|entropy`)
+
+var invalidNameChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+type sample struct {
+	labels string
+	value  float64
+}
+
+// renderPrometheus emits every numeric metric of each test case as a gauge
+// named benchmark_<field>, labelled with the benchmark name and the case's
+// parameters, followed by the summary as benchmark_summary_<field>.
+func renderPrometheus(benchmark string, document map[string]interface{}) ([]byte, error) {
+	metrics := make(map[string][]sample)
+
+	for _, testCase := range testCases(document) {
+		fields := make(map[string]string)
+		flatten("", testCase, fields)
+
+		labels := map[string]string{"test": benchmark}
+		values := make(map[string]float64)
+		for field, value := range fields {
+			number, err := strconv.ParseFloat(value, 64)
+			if err == nil && metricPattern.MatchString(field) {
+				values[field] = number
+			} else {
+				labels[metricName(field)] = value
+			}
+		}
+		for field, value := range values {
+			name := "benchmark_" + metricName(field)
+			metrics[name] = append(metrics[name], sample{formatLabels(labels), value})
+		}
+	}
+
+	if summary, ok := document["summary"].(map[string]interface{}); ok {
+		fields := make(map[string]string)
+		flatten("", summary, fields)
+		labels := formatLabels(map[string]string{"test": benchmark})
+		for field, value := range fields {
+			if number, err := strconv.ParseFloat(value, 64); err == nil {
+				name := "benchmark_summary_" + metricName(field)
+				metrics[name] = append(metrics[name], sample{labels, number})
+			}
+		}
+	}
+
+	names := make([]string, 0, len(metrics))
+	for name := range metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	for _, name := range names {
+		samples := metrics[name]
+		sort.Slice(samples, func(i, j int) bool { return samples[i].labels < samples[j].labels })
+		fmt.Fprintf(&buf, "# TYPE %s gauge\n", name)
+		for _, s := range samples {
+			fmt.Fprintf(&buf, "%s%s %s\n", name, s.labels, strconv.FormatFloat(s.value, 'g', -1, 64))
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// metricName turns a flattened field name into a valid metric or label name.
+func metricName(field string) string {
+	name := invalidNameChars.ReplaceAllString(field, "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+	return name
+}
+
+// formatLabels renders labels in sorted order with escaped values.
+func formatLabels(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	escaper := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, key, escaper.Replace(labels[key])))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
@@ -0,0 +1,145 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// defaultRegressionThreshold is the -regression-threshold default, in
+// percent.
+const defaultRegressionThreshold = 10.0
+
+// regression is one test case average that got worse than the baseline by
+// more than the threshold.
+type regression struct {
+	testCase string
+	metric   string
+	baseline float64
+	current  float64
+	change   float64 // percent, positive means worse
+}
+
+// compareWithBaseline matches the test cases of results against those of
+// the -baseline file and fails if any average regressed by more than
+// RegressionThreshold percent. Findings are reported on stderr.
+func (o *Options) compareWithBaseline(results interface{}) error {
+	data, err := json.Marshal(results)
+	if err != nil {
+		return fmt.Errorf("failed to marshal results: %v", err)
+	}
+	var current map[string]interface{}
+	if err := json.Unmarshal(data, &current); err != nil {
+		return fmt.Errorf("failed to decode results: %v", err)
+	}
+
+	data, err = os.ReadFile(o.Baseline)
+	if err != nil {
+		return fmt.Errorf("cannot read baseline file '%s': %v", o.Baseline, err)
+	}
+	var baseline map[string]interface{}
+	if err := json.Unmarshal(data, &baseline); err != nil {
+		return fmt.Errorf("baseline file '%s' is not a JSON results document: %v", o.Baseline, err)
+	}
+
+	baselineCases := make(map[string]map[string]interface{})
+	for _, testCase := range testCases(baseline) {
+		baselineCases[caseKey(testCase)] = testCase
+	}
+
+	var regressions []regression
+	matched, compared := 0, 0
+	for _, testCase := range testCases(current) {
+		key := caseKey(testCase)
+		previous, ok := baselineCases[key]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Baseline: no match for test case %s\n", key)
+			continue
+		}
+		matched++
+
+		for metric, value := range testCase {
+			lowerIsBetter, ok := metricDirection(metric)
+			if !ok {
+				continue
+			}
+			now, ok := value.(float64)
+			if !ok {
+				continue
+			}
+			before, ok := previous[metric].(float64)
+			if !ok || before == 0 {
+				continue
+			}
+			compared++
+
+			change := (now - before) / before * 100
+			if !lowerIsBetter {
+				change = -change
+			}
+			if change > o.RegressionThreshold {
+				regressions = append(regressions, regression{key, metric, before, now, change})
+			}
+		}
+	}
+
+	if len(regressions) == 0 {
+		fmt.Fprintf(os.Stderr, "Baseline: %d metric(s) in %d matched test case(s) within %.0f%% of '%s'\n",
+			compared, matched, o.RegressionThreshold, o.Baseline)
+		return nil
+	}
+
+	sort.Slice(regressions, func(i, j int) bool { return regressions[i].change > regressions[j].change })
+	for _, r := range regressions {
+		fmt.Fprintf(os.Stderr, "Regression: %s %s: %s -> %s (%.1f%% worse)\n", r.testCase, r.metric,
+			strconv.FormatFloat(r.baseline, 'g', 6, 64), strconv.FormatFloat(r.current, 'g', 6, 64), r.change)
+	}
+	return fmt.Errorf("%d metric(s) regressed by more than %.0f%% against baseline '%s'",
+		len(regressions), o.RegressionThreshold, o.Baseline)
+}
+
+// caseKey identifies a test case by its parameter tuple: its top-level
+// scalar fields that are not measurements, in key order.
+func caseKey(testCase map[string]interface{}) string {
+	var parts []string
+	for field, value := range testCase {
+		switch v := value.(type) {
+		case float64:
+			if metricPattern.MatchString(field) {
+				continue
+			}
+			parts = append(parts, field+"="+formatScalar(v))
+		case string, bool:
+			if field == "error" {
+				continue
+			}
+			parts = append(parts, field+"="+formatScalar(v))
+		}
+	}
+	sort.Strings(parts)
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+// metricDirection reports whether an average is compared against the
+// baseline and, if so, whether lower values are better. Only averages of
+// times and rates are compared; sizes, ratios and counts are not
+// performance figures.
+func metricDirection(metric string) (lowerIsBetter bool, ok bool) {
+	if !strings.HasPrefix(metric, "avg_") {
+		return false, false
+	}
+	for _, word := range []string{"throughput", "per_second", "speedup", "rps", "mbps"} {
+		if strings.Contains(metric, word) {
+			return false, true
+		}
+	}
+	for _, word := range []string{"time", "latency", "jitter", "_ms"} {
+		if strings.Contains(metric, word) {
+			return true, true
+		}
+	}
+	return false, false
+}
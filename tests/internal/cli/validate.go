@@ -0,0 +1,219 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Validator is implemented by configs that check their parameters after
+// loading. LoadConfig calls Validate and fails with its error.
+type Validator interface {
+	Validate() error
+}
+
+// FieldError is a parameters value that is missing, malformed or out of
+// range.
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("parameters.%s: %s", e.Field, e.Message)
+}
+
+// FirstError returns the first non-nil error, so a Validate method can list
+// its checks in one expression.
+func FirstError(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Required fails when a list parameter without a default is empty.
+func Required(field string, length int) error {
+	if length == 0 {
+		return &FieldError{field, "is required and must not be empty"}
+	}
+	return nil
+}
+
+// Positive fails unless value > 0.
+func Positive(field string, value int) error {
+	if value <= 0 {
+		return &FieldError{field, fmt.Sprintf("must be greater than 0, got %d", value)}
+	}
+	return nil
+}
+
+// NonNegative fails when value < 0. Use it for counts where 0 means "none"
+// or "use the default".
+func NonNegative(field string, value int) error {
+	if value < 0 {
+		return &FieldError{field, fmt.Sprintf("must not be negative, got %d", value)}
+	}
+	return nil
+}
+
+// InRange fails unless min <= value <= max.
+func InRange(field string, value, min, max int) error {
+	if value < min || value > max {
+		return &FieldError{field, fmt.Sprintf("must be between %d and %d, got %d", min, max, value)}
+	}
+	return nil
+}
+
+// EachPositive fails when any element of values is not greater than 0,
+// naming its index.
+func EachPositive(field string, values []int) error {
+	for i, value := range values {
+		if err := Positive(fmt.Sprintf("%s[%d]", field, i), value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OptionalPositive is Positive for a parameter that may be left unset.
+func OptionalPositive(field string, value *int) error {
+	if value == nil {
+		return nil
+	}
+	return Positive(field, *value)
+}
+
+// OptionalNonNegative is NonNegative for a parameter that may be left unset.
+func OptionalNonNegative(field string, value *int) error {
+	if value == nil {
+		return nil
+	}
+	return NonNegative(field, *value)
+}
+
+// checkParameters decodes the "parameters" object of data into a scratch
+// value of config's Parameters field type with unknown fields disallowed,
+// so a misspelled key is reported instead of silently ignored. Configs whose
+// parameters are a map accept any key and are not checked.
+func checkParameters(data []byte, config interface{}) error {
+	parametersType, ok := parametersFieldType(config)
+	if !ok {
+		return nil
+	}
+
+	var document struct {
+		Parameters json.RawMessage `json:"parameters"`
+	}
+	if err := json.Unmarshal(data, &document); err != nil || document.Parameters == nil {
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(document.Parameters))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(reflect.New(parametersType).Interface())
+	if err == nil {
+		return nil
+	}
+
+	message := err.Error()
+	if !strings.HasPrefix(message, "json: unknown field ") {
+		return describeDecodeError(err)
+	}
+	name, unquoteErr := strconv.Unquote(strings.TrimPrefix(message, "json: unknown field "))
+	if unquoteErr != nil {
+		return err
+	}
+	fieldErr := &FieldError{name, "unknown field"}
+	if suggestion := closestField(name, parametersType); suggestion != "" {
+		fieldErr.Message += fmt.Sprintf(" (did you mean %q?)", suggestion)
+	}
+	return fieldErr
+}
+
+// parametersFieldType returns the type of the struct field config decodes
+// "parameters" into, when that type is a struct.
+func parametersFieldType(config interface{}) (reflect.Type, bool) {
+	t := reflect.TypeOf(config)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if jsonName(field) == "parameters" && field.Type.Kind() == reflect.Struct {
+			return field.Type, true
+		}
+	}
+	return nil, false
+}
+
+func jsonName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// closestField returns the field of t whose JSON name is within two edits of
+// name, for "did you mean" hints.
+func closestField(name string, t reflect.Type) string {
+	best, bestDistance := "", 3
+	for i := 0; i < t.NumField(); i++ {
+		candidate := jsonName(t.Field(i))
+		if candidate == "-" {
+			continue
+		}
+		if d := editDistance(name, candidate); d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = previous[j-1] + cost
+			if previous[j]+1 < current[j] {
+				current[j] = previous[j] + 1
+			}
+			if current[j-1]+1 < current[j] {
+				current[j] = current[j-1] + 1
+			}
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+// describeDecodeError turns a type mismatch into a FieldError naming the
+// parameter; other errors are returned unchanged.
+func describeDecodeError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if !errors.As(err, &typeErr) || typeErr.Field == "" {
+		return err
+	}
+	field := strings.TrimPrefix(typeErr.Field, "parameters.")
+	return &FieldError{field, fmt.Sprintf("expected %s, got %s", typeErr.Type, typeErr.Value)}
+}
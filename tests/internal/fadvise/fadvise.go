@@ -0,0 +1,42 @@
+// Package fadvise passes page-cache access hints for an open file to the
+// kernel with posix_fadvise(2). Only Linux builds apply them; elsewhere
+// Apply fails with ErrUnsupported for every hint except None.
+package fadvise
+
+import (
+	"errors"
+	"os"
+)
+
+// Hint names, as accepted in benchmark parameters.
+const (
+	None       = "none"
+	Sequential = "sequential"
+	Random     = "random"
+	DontNeed   = "dontneed"
+)
+
+// Hints lists the accepted hint names.
+var Hints = []string{None, Sequential, Random, DontNeed}
+
+// ErrUnsupported is returned by Apply on platforms without posix_fadvise.
+var ErrUnsupported = errors.New("fadvise is not supported on this platform")
+
+// Valid reports whether hint is one of Hints.
+func Valid(hint string) bool {
+	for _, h := range Hints {
+		if h == hint {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply advises the kernel that f will be accessed as hint describes, for
+// the whole file. None, and the empty string, leave f untouched.
+func Apply(f *os.File, hint string) error {
+	if hint == "" || hint == None {
+		return nil
+	}
+	return apply(f, hint)
+}
@@ -0,0 +1,30 @@
+//go:build linux
+
+package fadvise
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// Supported reports whether Apply can pass hints to the kernel.
+const Supported = true
+
+var advice = map[string]int{
+	Sequential: unix.FADV_SEQUENTIAL,
+	Random:     unix.FADV_RANDOM,
+	DontNeed:   unix.FADV_DONTNEED,
+}
+
+func apply(f *os.File, hint string) error {
+	value, ok := advice[hint]
+	if !ok {
+		return fmt.Errorf("unknown fadvise hint: %s", hint)
+	}
+	if err := unix.Fadvise(int(f.Fd()), 0, 0, value); err != nil {
+		return fmt.Errorf("fadvise %s: %w", hint, err)
+	}
+	return nil
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package fadvise
+
+import "os"
+
+// Supported reports whether Apply can pass hints to the kernel.
+const Supported = false
+
+func apply(f *os.File, hint string) error {
+	return ErrUnsupported
+}
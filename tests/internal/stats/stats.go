@@ -0,0 +1,130 @@
+// Package stats provides the descriptive statistics shared by the Go
+// benchmarks. The Go runner copies this directory next to each benchmark
+// source, which imports it as "benchmark_test/internal/stats".
+//
+// Every function accepts an empty slice and returns zero values for it, so
+// callers can pass the timings of a test case whose iterations all failed.
+package stats
+
+import (
+	"math"
+	"sort"
+)
+
+// Summary holds the descriptive statistics of a set of measurements.
+type Summary struct {
+	Count  int     `json:"count"`
+	Mean   float64 `json:"mean"`
+	Median float64 `json:"median"`
+	StdDev float64 `json:"std_dev"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	P50    float64 `json:"p50"`
+	P90    float64 `json:"p90"`
+	P95    float64 `json:"p95"`
+	P99    float64 `json:"p99"`
+}
+
+// Mean returns the arithmetic mean of values.
+func Mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0.0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// Median returns the middle value of values, averaging the two middle values
+// when the count is even.
+func Median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0.0
+	}
+	sorted := sortedCopy(values)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2.0
+	}
+	return sorted[mid]
+}
+
+// StdDev returns the population standard deviation of values.
+func StdDev(values []float64) float64 {
+	if len(values) == 0 {
+		return 0.0
+	}
+	mean := Mean(values)
+	variance := 0.0
+	for _, v := range values {
+		diff := v - mean
+		variance += diff * diff
+	}
+	return math.Sqrt(variance / float64(len(values)))
+}
+
+// Percentile returns the p-th percentile (0-100) of values using the
+// nearest-rank method, so the result is always one of the measured values.
+func Percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0.0
+	}
+	return percentileSorted(sortedCopy(values), p)
+}
+
+// MinMax returns the smallest and largest of values.
+func MinMax(values []float64) (float64, float64) {
+	if len(values) == 0 {
+		return 0.0, 0.0
+	}
+	min, max := values[0], values[0]
+	for _, v := range values[1:] {
+		if v < min {
+			min = v
+		}
+		if v > max {
+			max = v
+		}
+	}
+	return min, max
+}
+
+// Summarize computes all of the statistics above for values.
+func Summarize(values []float64) Summary {
+	if len(values) == 0 {
+		return Summary{}
+	}
+	sorted := sortedCopy(values)
+	return Summary{
+		Count:  len(sorted),
+		Mean:   Mean(sorted),
+		Median: Median(sorted),
+		StdDev: StdDev(sorted),
+		Min:    sorted[0],
+		Max:    sorted[len(sorted)-1],
+		P50:    percentileSorted(sorted, 50),
+		P90:    percentileSorted(sorted, 90),
+		P95:    percentileSorted(sorted, 95),
+		P99:    percentileSorted(sorted, 99),
+	}
+}
+
+func sortedCopy(values []float64) []float64 {
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+	return sorted
+}
+
+func percentileSorted(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p / 100.0 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,205 @@
+package stats
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-9*math.Max(1, math.Abs(b))
+}
+
+func TestMean(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		want   float64
+	}{
+		{"empty", nil, 0},
+		{"single", []float64{4.5}, 4.5},
+		{"integers", []float64{1, 2, 3, 4}, 2.5},
+		{"negative", []float64{-3, 1, 5}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Mean(tt.values); !almostEqual(got, tt.want) {
+				t.Errorf("Mean(%v) = %v, want %v", tt.values, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMedian(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		want   float64
+	}{
+		{"empty", nil, 0},
+		{"single", []float64{7}, 7},
+		{"odd count", []float64{9, 1, 5}, 5},
+		{"even count", []float64{4, 1, 3, 2}, 2.5},
+		{"duplicates", []float64{2, 2, 2, 8}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Median(tt.values); !almostEqual(got, tt.want) {
+				t.Errorf("Median(%v) = %v, want %v", tt.values, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStdDev(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		want   float64
+	}{
+		{"empty", nil, 0},
+		{"single", []float64{3}, 0},
+		{"constant", []float64{5, 5, 5}, 0},
+		// The population form divides by n: variance 4 here.
+		{"population", []float64{2, 4, 4, 4, 5, 5, 7, 9}, 2},
+		{"two values", []float64{1, 3}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StdDev(tt.values); !almostEqual(got, tt.want) {
+				t.Errorf("StdDev(%v) = %v, want %v", tt.values, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		p      float64
+		want   float64
+	}{
+		{"empty", nil, 50, 0},
+		{"single p0", []float64{3}, 0, 3},
+		{"single p50", []float64{3}, 50, 3},
+		{"single p100", []float64{3}, 100, 3},
+		// Nearest rank: ceil(p/100 * n), clamped to 1..n.
+		{"p0 is the minimum", []float64{30, 10, 20}, 0, 10},
+		{"p100 is the maximum", []float64{30, 10, 20}, 100, 30},
+		{"p50 of four", []float64{4, 3, 2, 1}, 50, 2},
+		{"p51 of four rounds up", []float64{4, 3, 2, 1}, 51, 3},
+		{"p25 of five", []float64{15, 20, 35, 40, 50}, 25, 20},
+		{"p40 of five", []float64{15, 20, 35, 40, 50}, 40, 20},
+		{"p99 of five", []float64{15, 20, 35, 40, 50}, 99, 50},
+		{"above 100 clamps", []float64{1, 2}, 150, 2},
+		{"below 0 clamps", []float64{1, 2}, -10, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Percentile(tt.values, tt.p); got != tt.want {
+				t.Errorf("Percentile(%v, %v) = %v, want %v", tt.values, tt.p, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPercentileLeavesInputUnsorted(t *testing.T) {
+	values := []float64{3, 1, 2}
+	Percentile(values, 50)
+	Median(values)
+	if !reflect.DeepEqual(values, []float64{3, 1, 2}) {
+		t.Errorf("input reordered to %v", values)
+	}
+}
+
+func TestMinMax(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   []float64
+		min, max float64
+	}{
+		{"empty", nil, 0, 0},
+		{"single", []float64{-2}, -2, -2},
+		{"unsorted", []float64{3, -1, 8, 0}, -1, 8},
+		{"all equal", []float64{4, 4}, 4, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			min, max := MinMax(tt.values)
+			if min != tt.min || max != tt.max {
+				t.Errorf("MinMax(%v) = %v, %v, want %v, %v", tt.values, min, max, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	if got := Summarize(nil); got != (Summary{}) {
+		t.Errorf("Summarize(nil) = %+v, want the zero Summary", got)
+	}
+
+	single := Summarize([]float64{6})
+	want := Summary{Count: 1, Mean: 6, Median: 6, Min: 6, Max: 6, P50: 6, P90: 6, P95: 6, P99: 6}
+	if single != want {
+		t.Errorf("Summarize([6]) = %+v, want %+v", single, want)
+	}
+
+	values := []float64{5, 1, 4, 2, 3}
+	got := Summarize(values)
+	min, max := MinMax(values)
+	if got.Count != 5 || got.Mean != Mean(values) || got.Median != Median(values) ||
+		!almostEqual(got.StdDev, StdDev(values)) || got.Min != min || got.Max != max ||
+		got.P90 != Percentile(values, 90) || got.P99 != Percentile(values, 99) {
+		t.Errorf("Summarize(%v) = %+v, disagrees with the individual functions", values, got)
+	}
+}
+
+// TestPercentileKnownDistribution checks the latency percentiles the
+// benchmarks report (p50, p90, p95, p99) on distributions whose nearest-rank
+// percentiles are known exactly.
+func TestPercentileKnownDistribution(t *testing.T) {
+	// 1..n in shuffled order: the k-th percentile of 100 values is k, and of
+	// 1000 values 10k.
+	uniform := func(n int) []float64 {
+		values := make([]float64, n)
+		for i := range values {
+			values[i] = float64((i*37)%n + 1)
+		}
+		return values
+	}
+	// 95 fast responses and a slow tail of 5: p95 still lands on the fast
+	// ones, p99 on the tail.
+	tail := make([]float64, 0, 100)
+	for i := 0; i < 95; i++ {
+		tail = append(tail, 10)
+	}
+	for i := 0; i < 5; i++ {
+		tail = append(tail, 500)
+	}
+
+	tests := []struct {
+		name               string
+		values             []float64
+		p50, p90, p95, p99 float64
+	}{
+		{"uniform 1..100", uniform(100), 50, 90, 95, 99},
+		{"uniform 1..1000", uniform(1000), 500, 900, 950, 990},
+		{"slow tail", tail, 10, 10, 10, 500},
+		{"ten values", []float64{10, 9, 8, 7, 6, 5, 4, 3, 2, 1}, 5, 9, 10, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, c := range []struct{ p, want float64 }{{50, tt.p50}, {90, tt.p90}, {95, tt.p95}, {99, tt.p99}} {
+				if got := Percentile(tt.values, c.p); got != c.want {
+					t.Errorf("p%v = %v, want %v", c.p, got, c.want)
+				}
+			}
+			summary := Summarize(tt.values)
+			if summary.P50 != tt.p50 || summary.P90 != tt.p90 || summary.P95 != tt.p95 || summary.P99 != tt.p99 {
+				t.Errorf("Summarize percentiles = %v/%v/%v/%v, want %v/%v/%v/%v",
+					summary.P50, summary.P90, summary.P95, summary.P99, tt.p50, tt.p90, tt.p95, tt.p99)
+			}
+		})
+	}
+}
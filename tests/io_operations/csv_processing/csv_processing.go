@@ -2,25 +2,48 @@ package main
 
 import (
 	"encoding/csv"
-	"encoding/json"
 	"fmt"
 	"math/rand"
 	"os"
+	"path/filepath"
+	"runtime"
 	"strconv"
 	"strings"
 	"time"
+
+	"benchmark_test/internal/cli"
 )
 
 type Config struct {
 	Parameters Parameters `json:"parameters"`
+	Name       string     `json:"name"`
 }
 
 type Parameters struct {
-	RowCounts    []int    `json:"row_counts"`
-	ColumnCounts []int    `json:"column_counts"`
-	Operations   []string `json:"operations"`
-	DataTypes    []string `json:"data_types"`
-	Iterations   int      `json:"iterations"`
+	RowCounts        []int    `json:"row_counts"`
+	ColumnCounts     []int    `json:"column_counts"`
+	Operations       []string `json:"operations"`
+	DataTypes        []string `json:"data_types"`
+	Iterations       int      `json:"iterations"`
+	WarmupIterations int      `json:"warmup_iterations"`
+	Seed             *int64   `json:"seed,omitempty"`
+
+	// InferenceSampleRows is how many data rows column type inference
+	// looks at, for both the infer and aggregate operations.
+	InferenceSampleRows int      `json:"inference_sample_rows"`
+	Tags                []string `json:"tags"`
+}
+
+// Validate rejects table shapes that cannot be generated.
+func (c *Config) Validate() error {
+	p := c.Parameters
+	return cli.FirstError(
+		cli.EachPositive("row_counts", p.RowCounts),
+		cli.EachPositive("column_counts", p.ColumnCounts),
+		cli.NonNegative("iterations", p.Iterations),
+		cli.NonNegative("warmup_iterations", p.WarmupIterations),
+		cli.NonNegative("inference_sample_rows", p.InferenceSampleRows),
+	)
 }
 
 type OperationResult struct {
@@ -32,6 +55,9 @@ type OperationResult struct {
 	OriginalRows   int     `json:"original_rows,omitempty"`
 	FilteredRows   int     `json:"filtered_rows,omitempty"`
 	AggregatedCols int     `json:"aggregated_columns,omitempty"`
+	BytesAllocated uint64  `json:"bytes_allocated"`
+
+	ColumnTypes []ColumnType `json:"column_types,omitempty"`
 }
 
 type IterationResult struct {
@@ -50,6 +76,7 @@ type TestCase struct {
 	AvgWriteTime     float64           `json:"avg_write_time"`
 	AvgFilterTime    float64           `json:"avg_filter_time"`
 	AvgAggregateTime float64           `json:"avg_aggregate_time"`
+	AvgInferTime     float64           `json:"avg_infer_time,omitempty"`
 }
 
 type Summary struct {
@@ -60,18 +87,41 @@ type Summary struct {
 	AvgWriteTime     float64 `json:"avg_write_time"`
 	AvgFilterTime    float64 `json:"avg_filter_time"`
 	AvgAggregateTime float64 `json:"avg_aggregate_time"`
+	AvgInferTime     float64 `json:"avg_infer_time,omitempty"`
+	WarmupIterations int     `json:"warmup_iterations"`
 }
 
 type Results struct {
-	StartTime          float64    `json:"start_time"`
-	TestCases          []TestCase `json:"test_cases"`
-	Summary            Summary    `json:"summary"`
-	EndTime            float64    `json:"end_time"`
-	TotalExecutionTime float64    `json:"total_execution_time"`
+	SchemaVersion      string          `json:"schema_version"`
+	Environment        cli.Environment `json:"environment"`
+	Name               string          `json:"name,omitempty"`
+	Tags               []string        `json:"tags,omitempty"`
+	StartTime          float64         `json:"start_time"`
+	Seed               int64           `json:"seed"`
+	TestCases          []TestCase      `json:"test_cases"`
+	Summary            Summary         `json:"summary"`
+	EndTime            float64         `json:"end_time"`
+	TotalExecutionTime float64         `json:"total_execution_time"`
 }
 
+// ColumnType is the type inferred for a CSV column from its sampled values.
+type ColumnType string
+
+const (
+	ColumnInteger ColumnType = "integer"
+	ColumnNumeric ColumnType = "numeric"
+	ColumnBoolean ColumnType = "boolean"
+	ColumnString  ColumnType = "string"
+)
+
+// defaultInferenceSampleRows matches the sample aggregate used before
+// inference was configurable.
+const defaultInferenceSampleRows = 5
+
+// rng generates the CSV cell values, seeded from parameters.seed.
+var rng *rand.Rand
+
 func generateCSVData(rows, cols int, dataType string) [][]string {
-	rand.Seed(time.Now().UnixNano())
 	data := make([][]string, 0, rows+1)
 
 	// Generate headers
@@ -88,26 +138,26 @@ func generateCSVData(rows, cols int, dataType string) [][]string {
 			var value string
 			switch dataType {
 			case "numeric":
-				value = fmt.Sprintf("%.2f", rand.Float64()*1000)
+				value = fmt.Sprintf("%.2f", rng.Float64()*1000)
 			case "text":
-				length := rand.Intn(11) + 5 // 5-15 characters
+				length := rng.Intn(11) + 5 // 5-15 characters
 				runes := make([]rune, length)
 				for i := range runes {
-					runes[i] = rune('a' + rand.Intn(26))
+					runes[i] = rune('a' + rng.Intn(26))
 				}
 				value = string(runes)
 			default: // mixed
 				switch col % 3 {
 				case 0:
-					value = strconv.Itoa(rand.Intn(10000) + 1)
+					value = strconv.Itoa(rng.Intn(10000) + 1)
 				case 1:
 					runes := make([]rune, 10)
 					for i := range runes {
-						runes[i] = rune('a' + rand.Intn(26))
+						runes[i] = rune('a' + rng.Intn(26))
 					}
 					value = string(runes)
 				default:
-					value = fmt.Sprintf("%.2f", rand.Float64()*1000)
+					value = fmt.Sprintf("%.2f", rng.Float64()*1000)
 				}
 			}
 			rowData[col] = value
@@ -158,31 +208,69 @@ func filterCSVData(data [][]string, filterColumn int) [][]string {
 	return filtered
 }
 
-func aggregateCSVData(data [][]string) map[string]map[string]float64 {
-	if len(data) < 2 {
-		return make(map[string]map[string]float64)
+// inferColumnTypes returns the narrowest type that every sampled value of
+// each column parses as, checking integer, numeric and boolean in that
+// order. Only the first sampleRows data rows after the header are examined;
+// a column with no sampled values is a string column.
+func inferColumnTypes(data [][]string, sampleRows int) []ColumnType {
+	if len(data) == 0 {
+		return nil
 	}
 
-	headers := data[0]
-	var numericColumns []int
+	checkRows := len(data) - 1
+	if checkRows > sampleRows {
+		checkRows = sampleRows
+	}
 
-	// Find numeric columns (check first 5 rows)
-	for colIdx := 0; colIdx < len(headers); colIdx++ {
-		isNumeric := true
-		checkRows := len(data) - 1
-		if checkRows > 5 {
-			checkRows = 5
-		}
+	types := make([]ColumnType, len(data[0]))
+	for colIdx := range types {
+		isInteger, isNumeric, isBoolean := true, true, true
+		sampled := 0
 
 		for rowIdx := 1; rowIdx <= checkRows; rowIdx++ {
-			if colIdx < len(data[rowIdx]) {
-				if _, err := strconv.ParseFloat(data[rowIdx][colIdx], 64); err != nil {
-					isNumeric = false
-					break
-				}
+			if colIdx >= len(data[rowIdx]) {
+				continue
+			}
+			value := data[rowIdx][colIdx]
+			sampled++
+			if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+				isInteger = false
+			}
+			if _, err := strconv.ParseFloat(value, 64); err != nil {
+				isNumeric = false
 			}
+			if lower := strings.ToLower(value); lower != "true" && lower != "false" {
+				isBoolean = false
+			}
+		}
+
+		switch {
+		case sampled == 0:
+			types[colIdx] = ColumnString
+		case isInteger:
+			types[colIdx] = ColumnInteger
+		case isNumeric:
+			types[colIdx] = ColumnNumeric
+		case isBoolean:
+			types[colIdx] = ColumnBoolean
+		default:
+			types[colIdx] = ColumnString
 		}
-		if isNumeric {
+	}
+
+	return types
+}
+
+func aggregateCSVData(data [][]string, sampleRows int) map[string]map[string]float64 {
+	if len(data) < 2 {
+		return make(map[string]map[string]float64)
+	}
+
+	headers := data[0]
+	var numericColumns []int
+
+	for colIdx, columnType := range inferColumnTypes(data, sampleRows) {
+		if columnType == ColumnInteger || columnType == ColumnNumeric {
 			numericColumns = append(numericColumns, colIdx)
 		}
 	}
@@ -240,6 +328,42 @@ func contains(slice []string, item string) bool {
 	return false
 }
 
+// warmupCSV runs the selected operations on freshly generated data without
+// timing them, so that the first measured iteration does not pay for cold
+// caches and lazy runtime initialization.
+func warmupCSV(rows, cols int, dataType string, operations []string, sampleRows int) {
+	csvData := generateCSVData(rows, cols, dataType)
+	csvString := writeCSVToString(csvData)
+	if contains(operations, "read") {
+		readCSVFromString(csvString)
+	}
+	if contains(operations, "filter") {
+		filterCSVData(csvData, 0)
+	}
+	if contains(operations, "aggregate") {
+		aggregateCSVData(csvData, sampleRows)
+	}
+	if contains(operations, "infer") {
+		inferColumnTypes(csvData, sampleRows)
+	}
+}
+
+// timeOperation runs fn after forcing a GC and returns its duration in ms
+// and the heap bytes it allocated. The GC and MemStats reads happen outside
+// the timed span.
+func timeOperation(fn func()) (float64, uint64) {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	start := time.Now()
+	fn()
+	elapsed := float64(time.Since(start).Nanoseconds()) / 1000000.0
+
+	runtime.ReadMemStats(&after)
+	return elapsed, after.TotalAlloc - before.TotalAlloc
+}
+
 func runCSVProcessingBenchmark(config Config) Results {
 	parameters := config.Parameters
 
@@ -269,9 +393,17 @@ func runCSVProcessingBenchmark(config Config) Results {
 		iterations = 3
 	}
 
+	sampleRows := parameters.InferenceSampleRows
+	if sampleRows <= 0 {
+		sampleRows = defaultInferenceSampleRows
+	}
+
+	seed := cli.Seed(parameters.Seed)
+	rng = rand.New(rand.NewSource(seed))
+
 	startTime := time.Now()
 	var testCases []TestCase
-	var allReadTimes, allWriteTimes, allFilterTimes, allAggregateTimes []float64
+	var allReadTimes, allWriteTimes, allFilterTimes, allAggregateTimes, allInferTimes []float64
 	totalTests := 0
 	successfulTests := 0
 	failedTests := 0
@@ -281,9 +413,14 @@ func runCSVProcessingBenchmark(config Config) Results {
 			for _, dataType := range dataTypes {
 				fmt.Fprintf(os.Stderr, "Testing CSV: %d rows x %d cols, type: %s...\n", rows, cols, dataType)
 
-				var readTimes, writeTimes, filterTimes, aggregateTimes []float64
+				var readTimes, writeTimes, filterTimes, aggregateTimes, inferTimes []float64
 				var iterationsData []IterationResult
 
+				for i := 0; i < parameters.WarmupIterations; i++ {
+					fmt.Fprintf(os.Stderr, "  Warmup %d/%d...\n", i+1, parameters.WarmupIterations)
+					warmupCSV(rows, cols, dataType, operations, sampleRows)
+				}
+
 				for i := 0; i < iterations; i++ {
 					fmt.Fprintf(os.Stderr, "  Iteration %d/%d...\n", i+1, iterations)
 
@@ -301,17 +438,19 @@ func runCSVProcessingBenchmark(config Config) Results {
 
 					// Write operation
 					if contains(operations, "write") {
-						start := time.Now()
-						csvString := writeCSVToString(csvData)
-						writeTime := float64(time.Since(start).Nanoseconds()) / 1000000.0
+						var csvString string
+						writeTime, bytesAllocated := timeOperation(func() {
+							csvString = writeCSVToString(csvData)
+						})
 
 						writeTimes = append(writeTimes, writeTime)
 						allWriteTimes = append(allWriteTimes, writeTime)
 
 						iterationResult.Operations["write"] = OperationResult{
-							Success:    true,
-							TimeMs:     writeTime,
-							OutputSize: len(csvString),
+							Success:        true,
+							TimeMs:         writeTime,
+							OutputSize:     len(csvString),
+							BytesAllocated: bytesAllocated,
 						}
 					}
 
@@ -319,42 +458,47 @@ func runCSVProcessingBenchmark(config Config) Results {
 					if contains(operations, "read") {
 						csvString := writeCSVToString(csvData)
 
-						start := time.Now()
-						readData := readCSVFromString(csvString)
-						readTime := float64(time.Since(start).Nanoseconds()) / 1000000.0
+						var readData [][]string
+						readTime, bytesAllocated := timeOperation(func() {
+							readData = readCSVFromString(csvString)
+						})
 
 						readTimes = append(readTimes, readTime)
 						allReadTimes = append(allReadTimes, readTime)
 
 						iterationResult.Operations["read"] = OperationResult{
-							Success:  true,
-							TimeMs:   readTime,
-							RowsRead: len(readData),
+							Success:        true,
+							TimeMs:         readTime,
+							RowsRead:       len(readData),
+							BytesAllocated: bytesAllocated,
 						}
 					}
 
 					// Filter operation
 					if contains(operations, "filter") {
-						start := time.Now()
-						filteredData := filterCSVData(csvData, 0)
-						filterTime := float64(time.Since(start).Nanoseconds()) / 1000000.0
+						var filteredData [][]string
+						filterTime, bytesAllocated := timeOperation(func() {
+							filteredData = filterCSVData(csvData, 0)
+						})
 
 						filterTimes = append(filterTimes, filterTime)
 						allFilterTimes = append(allFilterTimes, filterTime)
 
 						iterationResult.Operations["filter"] = OperationResult{
-							Success:      true,
-							TimeMs:       filterTime,
-							OriginalRows: len(csvData),
-							FilteredRows: len(filteredData),
+							Success:        true,
+							TimeMs:         filterTime,
+							OriginalRows:   len(csvData),
+							FilteredRows:   len(filteredData),
+							BytesAllocated: bytesAllocated,
 						}
 					}
 
 					// Aggregate operation
 					if contains(operations, "aggregate") {
-						start := time.Now()
-						aggregations := aggregateCSVData(csvData)
-						aggregateTime := float64(time.Since(start).Nanoseconds()) / 1000000.0
+						var aggregations map[string]map[string]float64
+						aggregateTime, bytesAllocated := timeOperation(func() {
+							aggregations = aggregateCSVData(csvData, sampleRows)
+						})
 
 						aggregateTimes = append(aggregateTimes, aggregateTime)
 						allAggregateTimes = append(allAggregateTimes, aggregateTime)
@@ -363,6 +507,25 @@ func runCSVProcessingBenchmark(config Config) Results {
 							Success:        true,
 							TimeMs:         aggregateTime,
 							AggregatedCols: len(aggregations),
+							BytesAllocated: bytesAllocated,
+						}
+					}
+
+					// Infer operation
+					if contains(operations, "infer") {
+						var columnTypes []ColumnType
+						inferTime, bytesAllocated := timeOperation(func() {
+							columnTypes = inferColumnTypes(csvData, sampleRows)
+						})
+
+						inferTimes = append(inferTimes, inferTime)
+						allInferTimes = append(allInferTimes, inferTime)
+
+						iterationResult.Operations["infer"] = OperationResult{
+							Success:        true,
+							TimeMs:         inferTime,
+							BytesAllocated: bytesAllocated,
+							ColumnTypes:    columnTypes,
 						}
 					}
 
@@ -416,6 +579,14 @@ func runCSVProcessingBenchmark(config Config) Results {
 					testCase.AvgAggregateTime = sum / float64(len(aggregateTimes))
 				}
 
+				if len(inferTimes) > 0 {
+					sum := 0.0
+					for _, t := range inferTimes {
+						sum += t
+					}
+					testCase.AvgInferTime = sum / float64(len(inferTimes))
+				}
+
 				testCases = append(testCases, testCase)
 			}
 		}
@@ -423,9 +594,10 @@ func runCSVProcessingBenchmark(config Config) Results {
 
 	// Calculate overall summary
 	summary := Summary{
-		TotalTests:      totalTests,
-		SuccessfulTests: successfulTests,
-		FailedTests:     failedTests,
+		TotalTests:       totalTests,
+		SuccessfulTests:  successfulTests,
+		FailedTests:      failedTests,
+		WarmupIterations: parameters.WarmupIterations,
 	}
 
 	if len(allReadTimes) > 0 {
@@ -460,11 +632,22 @@ func runCSVProcessingBenchmark(config Config) Results {
 		summary.AvgAggregateTime = sum / float64(len(allAggregateTimes))
 	}
 
+	if len(allInferTimes) > 0 {
+		sum := 0.0
+		for _, t := range allInferTimes {
+			sum += t
+		}
+		summary.AvgInferTime = sum / float64(len(allInferTimes))
+	}
+
 	endTime := time.Now()
 	totalExecutionTime := endTime.Sub(startTime).Seconds()
 
 	return Results{
+		SchemaVersion:      cli.SchemaVersion,
+		Environment:        cli.CurrentEnvironment(),
 		StartTime:          float64(startTime.Unix()),
+		Seed:               seed,
 		TestCases:          testCases,
 		Summary:            summary,
 		EndTime:            float64(endTime.Unix()),
@@ -473,32 +656,24 @@ func runCSVProcessingBenchmark(config Config) Results {
 }
 
 func main() {
-	if len(os.Args) < 2 {
-		fmt.Fprintln(os.Stderr, "Usage: csv_processing <config_file>")
-		os.Exit(1)
-	}
-
-	configFile := os.Args[1]
-
-	configContent, err := os.ReadFile(configFile)
+	opts, err := cli.Parse(filepath.Base(os.Args[0]), os.Args[1:])
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: Config file '%s' not found: %v\n", configFile, err)
-		os.Exit(1)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
 	}
 
 	var config Config
-	if err := json.Unmarshal(configContent, &config); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: Invalid JSON in config file: %v\n", err)
+	if err := opts.LoadConfig(&config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
 	results := runCSVProcessingBenchmark(config)
+	results.Name = config.Name
+	results.Tags = config.Parameters.Tags
 
-	output, err := json.MarshalIndent(results, "", "  ")
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error marshaling results: %v\n", err)
+	if err := opts.WriteResults(results); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
-
-	fmt.Println(string(output))
 }
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// mixedData has one column of each inferred type, a column whose fifth row
+// breaks the integer pattern, and an integer column the short last row
+// leaves out.
+var mixedData = [][]string{
+	{"id", "price", "active", "name", "count", "late_text", "sparse"},
+	{"1", "9.99", "true", "alpha", "10", "1", "8"},
+	{"2", "10", "FALSE", "beta", "-3", "2", "9"},
+	{"3", "0.5", "True", "42", "7", "3", "10"},
+	{"4", "1e3", "false", "gamma", "0", "4", "11"},
+	{"5", "-2.25", "true", "delta", "12", "five"},
+}
+
+func TestInferColumnTypes(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       [][]string
+		sampleRows int
+		want       []ColumnType
+	}{
+		{"all rows", mixedData, 5, []ColumnType{
+			ColumnInteger, ColumnNumeric, ColumnBoolean, ColumnString, ColumnInteger, ColumnString, ColumnInteger}},
+		// The fifth row is outside the sample, so late_text still looks
+		// like integers.
+		{"first four rows", mixedData, 4, []ColumnType{
+			ColumnInteger, ColumnNumeric, ColumnBoolean, ColumnString, ColumnInteger, ColumnInteger, ColumnInteger}},
+		{"sample larger than data", mixedData, 100, []ColumnType{
+			ColumnInteger, ColumnNumeric, ColumnBoolean, ColumnString, ColumnInteger, ColumnString, ColumnInteger}},
+		{"header only", mixedData[:1], 5, []ColumnType{
+			ColumnString, ColumnString, ColumnString, ColumnString, ColumnString, ColumnString, ColumnString}},
+		{"empty", nil, 5, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := inferColumnTypes(tt.data, tt.sampleRows); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("inferColumnTypes(sampleRows=%d) = %v, want %v", tt.sampleRows, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestInferOperation runs the infer operation on generated mixed data,
+// whose columns cycle through integer, string and numeric values, and
+// checks the reported types and timings.
+func TestInferOperation(t *testing.T) {
+	seed := int64(1)
+	results := runCSVProcessingBenchmark(Config{Parameters: Parameters{
+		RowCounts:    []int{50, 100},
+		ColumnCounts: []int{6},
+		Operations:   []string{"infer"},
+		DataTypes:    []string{"mixed"},
+		Iterations:   2,
+		Seed:         &seed,
+	}})
+
+	want := []ColumnType{ColumnInteger, ColumnString, ColumnNumeric, ColumnInteger, ColumnString, ColumnNumeric}
+	var inferTimes []float64
+	for _, testCase := range results.TestCases {
+		if testCase.AvgInferTime <= 0 {
+			t.Errorf("%d rows: AvgInferTime = %v, want > 0", testCase.RowCount, testCase.AvgInferTime)
+		}
+		for _, iteration := range testCase.Iterations {
+			infer, ok := iteration.Operations["infer"]
+			if !ok {
+				t.Fatalf("%d rows, iteration %d: no infer result", testCase.RowCount, iteration.Iteration)
+			}
+			if !reflect.DeepEqual(infer.ColumnTypes, want) {
+				t.Errorf("%d rows: column types %v, want %v", testCase.RowCount, infer.ColumnTypes, want)
+			}
+			inferTimes = append(inferTimes, infer.TimeMs)
+		}
+	}
+
+	if len(inferTimes) != 4 {
+		t.Fatalf("got %d infer timings, want 4", len(inferTimes))
+	}
+	sum := 0.0
+	for _, ms := range inferTimes {
+		sum += ms
+	}
+	if avg := sum / float64(len(inferTimes)); results.Summary.AvgInferTime != avg {
+		t.Errorf("Summary.AvgInferTime = %v, want the mean of all infer timings %v", results.Summary.AvgInferTime, avg)
+	}
+}
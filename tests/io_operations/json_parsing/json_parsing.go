@@ -1,29 +1,57 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"math/rand"
 	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
 	"time"
+
+	"benchmark_test/internal/cli"
+	"benchmark_test/internal/stats"
 )
 
 type TestResult struct {
-	StartTime          int64      `json:"start_time"`
-	TestCases          []TestCase `json:"test_cases"`
-	Summary            Summary    `json:"summary"`
-	EndTime            int64      `json:"end_time"`
-	TotalExecutionTime float64    `json:"total_execution_time"`
+	SchemaVersion      string          `json:"schema_version"`
+	Environment        cli.Environment `json:"environment"`
+	Name               string          `json:"name,omitempty"`
+	Tags               []string        `json:"tags,omitempty"`
+	StartTime          int64           `json:"start_time"`
+	Seed               int64           `json:"seed"`
+	TestCases          []TestCase      `json:"test_cases"`
+	Summary            Summary         `json:"summary"`
+	EndTime            int64           `json:"end_time"`
+	TotalExecutionTime float64         `json:"total_execution_time"`
 }
 
 type TestCase struct {
-	JsonSize         int               `json:"json_size"`
-	StructureType    string            `json:"structure_type"`
-	Operations       []string          `json:"operations"`
-	Iterations       []IterationResult `json:"iterations"`
-	AvgParseTime     float64           `json:"avg_parse_time"`
-	AvgStringifyTime float64           `json:"avg_stringify_time"`
-	AvgTraverseTime  float64           `json:"avg_traverse_time"`
+	JsonSize               int               `json:"json_size"`
+	StructureType          string            `json:"structure_type"`
+	Operations             []string          `json:"operations"`
+	Iterations             []IterationResult `json:"iterations"`
+	AvgParseTime           float64           `json:"avg_parse_time"`
+	AvgStringifyTime       float64           `json:"avg_stringify_time"`
+	AvgTraverseTime        float64           `json:"avg_traverse_time"`
+	AvgParseBytesAllocated float64           `json:"avg_parse_bytes_allocated"`
+	AvgParseAllocCount     float64           `json:"avg_parse_alloc_count"`
+	AvgTokenCountTime      float64           `json:"avg_token_count_time,omitempty"`
+	AvgTokenCountBytes     float64           `json:"avg_token_count_bytes_allocated,omitempty"`
+	AvgStreamParseTime     float64           `json:"avg_stream_parse_time,omitempty"`
+	AvgRecordsPerSecond    float64           `json:"avg_records_per_second,omitempty"`
+	TotalRecordsParsed     int               `json:"total_records_parsed,omitempty"`
+	AvgSelectFieldsTime    float64           `json:"avg_select_fields_time,omitempty"`
+	AvgSelectFieldsBytes   float64           `json:"avg_select_fields_bytes_allocated,omitempty"`
+	// SelectFieldsSpeedup is AvgParseTime / AvgSelectFieldsTime, when
+	// both operations ran
+	SelectFieldsSpeedup float64 `json:"select_fields_speedup,omitempty"`
+	AvgQueryTime        float64 `json:"avg_query_time,omitempty"`
+	AvgQueryMatchCount  float64 `json:"avg_query_match_count,omitempty"`
 }
 
 type IterationResult struct {
@@ -38,6 +66,15 @@ type OperationResult struct {
 	JsonStringLength *int     `json:"json_string_length,omitempty"`
 	OutputLength     *int     `json:"output_length,omitempty"`
 	OperationsCount  *int     `json:"operations_count,omitempty"`
+	BytesAllocated   *uint64  `json:"bytes_allocated,omitempty"`
+	AllocCount       *uint64  `json:"alloc_count,omitempty"`
+	ElementCount     *int     `json:"element_count,omitempty"`
+	CountMatches     *bool    `json:"count_matches,omitempty"`
+	RecordCount      *int     `json:"record_count,omitempty"`
+	RecordsPerSecond *float64 `json:"records_per_second,omitempty"`
+	FieldsMatch      *bool    `json:"fields_match,omitempty"`
+	MatchCount       *int     `json:"match_count,omitempty"`
+	RoundTripMatches *bool    `json:"round_trip_matches,omitempty"`
 	Error            *string  `json:"error,omitempty"`
 }
 
@@ -48,15 +85,75 @@ type Summary struct {
 	AvgParseTime     float64 `json:"avg_parse_time"`
 	AvgStringifyTime float64 `json:"avg_stringify_time"`
 	AvgTraverseTime  float64 `json:"avg_traverse_time"`
+	WarmupIterations int     `json:"warmup_iterations"`
+	// The unicode structure's averages, which the overall ones above
+	// include too
+	AvgUnicodeParseTime     float64 `json:"avg_unicode_parse_time,omitempty"`
+	AvgUnicodeStringifyTime float64 `json:"avg_unicode_stringify_time,omitempty"`
 }
 
 type Config struct {
 	Parameters struct {
-		JsonSizes      []int    `json:"json_sizes"`
-		JsonStructures []string `json:"json_structures"`
-		Operations     []string `json:"operations"`
-		Iterations     int      `json:"iterations"`
+		JsonSizes        []int    `json:"json_sizes"`
+		JsonStructures   []string `json:"json_structures"`
+		Operations       []string `json:"operations"`
+		Iterations       int      `json:"iterations"`
+		WarmupIterations int      `json:"warmup_iterations"`
+		Seed             *int64   `json:"seed,omitempty"`
+		// Query is the path expression the "query" operation evaluates
+		// (see parsePath); defaultQuery when empty.
+		Query string   `json:"query"`
+		Tags  []string `json:"tags"`
 	} `json:"parameters"`
+	Name string `json:"name"`
+}
+
+// Validate rejects document sizes and counts that cannot run, and query
+// expressions that do not parse.
+func (c *Config) Validate() error {
+	p := c.Parameters
+	return cli.FirstError(
+		cli.EachPositive("json_sizes", p.JsonSizes),
+		cli.NonNegative("iterations", p.Iterations),
+		cli.NonNegative("warmup_iterations", p.WarmupIterations),
+		validQuery(p.Query),
+	)
+}
+
+func validQuery(query string) error {
+	if query == "" {
+		return nil
+	}
+	if _, err := parsePath(query); err != nil {
+		return &cli.FieldError{Field: "query", Message: err.Error()}
+	}
+	return nil
+}
+
+// rng drives the JSON generators. It is reseeded at the start of each run so
+// the same parameters.seed reproduces the same documents.
+var rng *rand.Rand
+
+// jsonLines is a JSON Lines (NDJSON) document: independent records encoded
+// one per line rather than a single JSON value.
+type jsonLines []interface{}
+
+// encodeJson serializes generated data: a single document with
+// json.Marshal, or a jsonLines one as newline-separated records.
+func encodeJson(data interface{}) ([]byte, error) {
+	records, ok := data.(jsonLines)
+	if !ok {
+		return json.Marshal(data)
+	}
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	for _, record := range records {
+		// Encode terminates each record with a newline
+		if err := encoder.Encode(record); err != nil {
+			return nil, err
+		}
+	}
+	return buffer.Bytes(), nil
 }
 
 func generateFlatJson(size int) interface{} {
@@ -64,15 +161,15 @@ func generateFlatJson(size int) interface{} {
 
 	for i := 0; i < size; i++ {
 		key := fmt.Sprintf("key_%d", i)
-		valueType := rand.Intn(3)
+		valueType := rng.Intn(3)
 
 		switch valueType {
 		case 0:
-			data[key] = fmt.Sprintf("value_%d", rand.Intn(1000))
+			data[key] = fmt.Sprintf("value_%d", rng.Intn(1000))
 		case 1:
-			data[key] = rand.Intn(1000) + 1
+			data[key] = rng.Intn(1000) + 1
 		default:
-			data[key] = rand.Float32() < 0.5
+			data[key] = rng.Float32() < 0.5
 		}
 	}
 
@@ -84,21 +181,21 @@ func generateNestedJson(size int, maxDepth int) interface{} {
 
 	createNestedObject = func(remainingSize, currentDepth int) interface{} {
 		if remainingSize <= 0 || currentDepth >= maxDepth {
-			choice := rand.Intn(3)
+			choice := rng.Intn(3)
 			switch choice {
 			case 0:
-				return fmt.Sprintf("leaf_%d", rand.Intn(100))
+				return fmt.Sprintf("leaf_%d", rng.Intn(100))
 			case 1:
-				return rand.Intn(100) + 1
+				return rng.Intn(100) + 1
 			default:
-				return rand.Float32() < 0.5
+				return rng.Float32() < 0.5
 			}
 		}
 
-		if rand.Float32() < 0.6 {
+		if rng.Float32() < 0.6 {
 			// Create object
 			obj := make(map[string]interface{})
-			keysCount := min(rand.Intn(4)+2, remainingSize)
+			keysCount := min(rng.Intn(4)+2, remainingSize)
 			remainingPerKey := remainingSize / keysCount
 
 			for i := 0; i < keysCount; i++ {
@@ -108,7 +205,7 @@ func generateNestedJson(size int, maxDepth int) interface{} {
 			return obj
 		} else {
 			// Create array
-			itemsCount := min(rand.Intn(3)+2, remainingSize)
+			itemsCount := min(rng.Intn(3)+2, remainingSize)
 			remainingPerItem := remainingSize / itemsCount
 
 			arr := make([]interface{}, itemsCount)
@@ -134,36 +231,36 @@ func generateArrayHeavyJson(size int) interface{} {
 			"id":     i,
 			"name":   fmt.Sprintf("User_%d", i),
 			"email":  fmt.Sprintf("user%d@example.com", i),
-			"active": rand.Float32() < 0.5,
+			"active": rng.Float32() < 0.5,
 		}
 	}
 
 	products := make([]interface{}, itemsPerArray)
 	for i := 0; i < itemsPerArray; i++ {
-		price := float64(rand.Intn(4900)+100) / 100.0
+		price := float64(rng.Intn(4900)+100) / 100.0
 		products[i] = map[string]interface{}{
 			"id":       i,
 			"name":     fmt.Sprintf("Product_%d", i),
 			"price":    price,
-			"category": categories[rand.Intn(len(categories))],
+			"category": categories[rng.Intn(len(categories))],
 		}
 	}
 
 	orders := make([]interface{}, itemsPerArray)
 	for i := 0; i < itemsPerArray; i++ {
-		productCount := rand.Intn(5) + 1
+		productCount := rng.Intn(5) + 1
 		productIds := make([]int, productCount)
 		for j := 0; j < productCount; j++ {
-			productIds[j] = rand.Intn(itemsPerArray)
+			productIds[j] = rng.Intn(itemsPerArray)
 		}
 
-		total := float64(rand.Intn(9800)+200) / 100.0
+		total := float64(rng.Intn(9800)+200) / 100.0
 		orders[i] = map[string]interface{}{
 			"id":          i,
-			"user_id":     rand.Intn(itemsPerArray),
+			"user_id":     rng.Intn(itemsPerArray),
 			"product_ids": productIds,
 			"total":       total,
-			"timestamp":   fmt.Sprintf("2024-%02d-%02d", rand.Intn(12)+1, rand.Intn(28)+1),
+			"timestamp":   fmt.Sprintf("2024-%02d-%02d", rng.Intn(12)+1, rng.Intn(28)+1),
 		}
 	}
 
@@ -181,21 +278,21 @@ func generateMixedJson(size int) interface{} {
 	data := make([]interface{}, size)
 
 	for i := 0; i < size; i++ {
-		recordType := types[rand.Intn(len(types))]
+		recordType := types[rng.Intn(len(types))]
 
 		// Select random tags
-		tagCount := rand.Intn(2) + 1
+		tagCount := rng.Intn(2) + 1
 		selectedTags := make([]string, tagCount)
 		for j := 0; j < tagCount; j++ {
-			selectedTags[j] = tags[rand.Intn(len(tags))]
+			selectedTags[j] = tags[rng.Intn(len(tags))]
 		}
 
 		// Create relationships
-		relationshipCount := rand.Intn(4)
+		relationshipCount := rng.Intn(4)
 		relationships := make([]interface{}, relationshipCount)
 		for j := 0; j < relationshipCount; j++ {
 			relationships[j] = map[string]interface{}{
-				"id":   rand.Intn(size),
+				"id":   rng.Intn(size),
 				"type": "related",
 			}
 		}
@@ -205,7 +302,7 @@ func generateMixedJson(size int) interface{} {
 			"type": recordType,
 			"attributes": map[string]interface{}{
 				"name":  fmt.Sprintf("Item_%d", i),
-				"value": rand.Intn(1000) + 1,
+				"value": rng.Intn(1000) + 1,
 				"tags":  selectedTags,
 			},
 			"relationships": relationships,
@@ -229,6 +326,240 @@ func generateMixedJson(size int) interface{} {
 	}
 }
 
+// unicodeRanges are the code points the "unicode" generator draws its
+// non-ASCII characters from: accented Latin, CJK ideographs and emoji,
+// which take two, three and four bytes in UTF-8.
+var unicodeRanges = [][2]rune{{0x00C0, 0x00FF}, {0x4E00, 0x9FFF}, {0x1F300, 0x1F64F}}
+
+// unicodeEscaped are characters encoding/json escapes on output, mixed
+// sparingly into "unicode" strings so the escaping path is exercised too.
+var unicodeEscaped = []rune{'"', '\\', '<', '>', '&', '\u2028', '\u2029'}
+
+// unicodeString returns length characters, mostly multibyte, with some
+// ASCII letters and the occasional escaped character.
+func unicodeString(length int) string {
+	var builder strings.Builder
+	for i := 0; i < length; i++ {
+		switch choice := rng.Intn(20); {
+		case choice == 0:
+			builder.WriteRune(unicodeEscaped[rng.Intn(len(unicodeEscaped))])
+		case choice < 5:
+			builder.WriteByte(byte('a' + rng.Intn(26)))
+		default:
+			r := unicodeRanges[rng.Intn(len(unicodeRanges))]
+			builder.WriteRune(r[0] + rune(rng.Intn(int(r[1]-r[0]+1))))
+		}
+	}
+	return builder.String()
+}
+
+// generateUnicodeJson produces size records whose keys and values are
+// multibyte UTF-8 text. Every value is a string, so a parsed document
+// compares equal to the generated one.
+func generateUnicodeJson(size int) interface{} {
+	records := make([]interface{}, size)
+	for i := 0; i < size; i++ {
+		records[i] = map[string]interface{}{
+			"id":      fmt.Sprintf("id_%d", i),
+			"名前":      unicodeString(6),
+			"città":   unicodeString(10),
+			"message": unicodeString(40),
+			"tags":    []interface{}{unicodeString(3), unicodeString(3)},
+		}
+	}
+	return map[string]interface{}{
+		"records": records,
+	}
+}
+
+// generateJsonLines produces size independent log-style records for the
+// "ndjson" structure.
+func generateJsonLines(size int) interface{} {
+	levels := []string{"debug", "info", "warn", "error"}
+	services := []string{"api", "worker", "scheduler", "auth"}
+
+	records := make(jsonLines, size)
+	for i := 0; i < size; i++ {
+		records[i] = map[string]interface{}{
+			"id":          i,
+			"timestamp":   fmt.Sprintf("2024-01-01T%02d:%02d:%02dZ", rng.Intn(24), rng.Intn(60), rng.Intn(60)),
+			"level":       levels[rng.Intn(len(levels))],
+			"service":     services[rng.Intn(len(services))],
+			"message":     fmt.Sprintf("request %d handled", rng.Intn(100000)),
+			"duration_ms": float64(rng.Intn(100000)) / 100.0,
+			"status":      200 + rng.Intn(4)*100,
+		}
+	}
+	return records
+}
+
+// defaultQuery selects every record value of a "mixed" document.
+const defaultQuery = "data[*].attributes.value"
+
+// pathStep is one step of a query path: an object key, or a wildcard that
+// matches every element of an array or every value of an object.
+type pathStep struct {
+	key      string
+	wildcard bool
+}
+
+// parsePath parses a dotted path expression such as
+// "data[*].attributes.value". A segment is an object key, "*", or a key
+// followed by "[*]" suffixes; "*" and "[*]" are wildcards.
+func parsePath(expr string) ([]pathStep, error) {
+	var steps []pathStep
+	for _, segment := range strings.Split(expr, ".") {
+		if segment == "" {
+			return nil, fmt.Errorf("empty segment in path %q", expr)
+		}
+		if segment == "*" {
+			steps = append(steps, pathStep{wildcard: true})
+			continue
+		}
+		key := segment
+		wildcards := 0
+		for strings.HasSuffix(key, "[*]") {
+			key = strings.TrimSuffix(key, "[*]")
+			wildcards++
+		}
+		if strings.ContainsAny(key, "[]*") {
+			return nil, fmt.Errorf("unsupported segment %q in path %q (only key, * and [*] are supported)", segment, expr)
+		}
+		if key != "" {
+			steps = append(steps, pathStep{key: key})
+		}
+		for i := 0; i < wildcards; i++ {
+			steps = append(steps, pathStep{wildcard: true})
+		}
+	}
+	return steps, nil
+}
+
+// evaluatePath returns the values reached by following steps from data,
+// a document parsed into interface{}. Steps that do not apply, such as a
+// key on an array, match nothing.
+func evaluatePath(data interface{}, steps []pathStep) []interface{} {
+	current := []interface{}{data}
+	for _, step := range steps {
+		var next []interface{}
+		for _, value := range current {
+			switch v := value.(type) {
+			case map[string]interface{}:
+				if step.wildcard {
+					for _, child := range v {
+						next = append(next, child)
+					}
+				} else if child, ok := v[step.key]; ok {
+					next = append(next, child)
+				}
+			case []interface{}:
+				if step.wildcard {
+					next = append(next, v...)
+				}
+			}
+		}
+		current = next
+	}
+	return current
+}
+
+// fieldSelection is a small typed struct that json.Unmarshal fills with a
+// few fields of a generated document, ignoring the rest. matches checks
+// the extracted values against a full interface{} parse of the same input.
+type fieldSelection interface {
+	matches(full interface{}) bool
+}
+
+// flatSelection picks the first keys of a flat document. Their values are
+// strings, numbers or booleans, so they stay untyped.
+type flatSelection struct {
+	Key0 interface{} `json:"key_0"`
+	Key1 interface{} `json:"key_1"`
+	Key2 interface{} `json:"key_2"`
+}
+
+func (s *flatSelection) matches(full interface{}) bool {
+	object, ok := full.(map[string]interface{})
+	return ok && object["key_0"] == s.Key0 && object["key_1"] == s.Key1 && object["key_2"] == s.Key2
+}
+
+// arrayHeavySelection picks user names and order totals.
+type arrayHeavySelection struct {
+	Users []struct {
+		Name string `json:"name"`
+	} `json:"users"`
+	Orders []struct {
+		Total float64 `json:"total"`
+	} `json:"orders"`
+}
+
+func (s *arrayHeavySelection) matches(full interface{}) bool {
+	object, ok := full.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	users, _ := object["users"].([]interface{})
+	orders, _ := object["orders"].([]interface{})
+	if len(users) != len(s.Users) || len(orders) != len(s.Orders) {
+		return false
+	}
+	for i, user := range users {
+		if field(user, "name") != s.Users[i].Name {
+			return false
+		}
+	}
+	for i, order := range orders {
+		if field(order, "total") != s.Orders[i].Total {
+			return false
+		}
+	}
+	return true
+}
+
+// mixedSelection picks the record count and each record's id and type.
+type mixedSelection struct {
+	Metadata struct {
+		TotalRecords int `json:"total_records"`
+	} `json:"metadata"`
+	Data []struct {
+		ID   int    `json:"id"`
+		Type string `json:"type"`
+	} `json:"data"`
+}
+
+func (s *mixedSelection) matches(full interface{}) bool {
+	object, ok := full.(map[string]interface{})
+	if !ok || field(object["metadata"], "total_records") != float64(s.Metadata.TotalRecords) {
+		return false
+	}
+	data, _ := object["data"].([]interface{})
+	if len(data) != len(s.Data) {
+		return false
+	}
+	for i, record := range data {
+		if field(record, "id") != float64(s.Data[i].ID) || field(record, "type") != s.Data[i].Type {
+			return false
+		}
+	}
+	return true
+}
+
+// field returns object[key] when object is a JSON object, or nil.
+func field(object interface{}, key string) interface{} {
+	if m, ok := object.(map[string]interface{}); ok {
+		return m[key]
+	}
+	return nil
+}
+
+// fieldSelections makes an empty selection for each structure that has
+// one; nested documents have no fixed fields to select.
+var fieldSelections = map[string]func() fieldSelection{
+	"flat":        func() fieldSelection { return &flatSelection{} },
+	"array_heavy": func() fieldSelection { return &arrayHeavySelection{} },
+	"mixed":       func() fieldSelection { return &mixedSelection{} },
+}
+
 // Optimized traversal function using iterative approach to avoid stack overflow
 func traverseJson(data interface{}) int {
 	count := 0
@@ -249,12 +580,188 @@ func traverseJson(data interface{}) int {
 			for _, item := range v {
 				stack = append(stack, item)
 			}
+		case jsonLines:
+			for _, record := range v {
+				stack = append(stack, record)
+			}
 		}
 	}
 
 	return count
 }
 
+// streamParse decodes data as a stream of JSON values, one per Decode call,
+// and returns how many there were. A JSON Lines document yields one value
+// per record and a single document yields one.
+func streamParse(data []byte) (int, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	count := 0
+	for {
+		var record interface{}
+		err := decoder.Decode(&record)
+		if err == io.EOF {
+			return count, nil
+		}
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+}
+
+// expectedRecords is the number of values streamParse should find in the
+// encoding of data.
+func expectedRecords(data interface{}) int {
+	if records, ok := data.(jsonLines); ok {
+		return len(records)
+	}
+	return 1
+}
+
+// measure runs fn after a GC and returns its wall time in ms along with the
+// bytes and objects it allocated. MemStats are read outside the timed region.
+func measure(fn func()) (timeMs float64, bytesAllocated, allocCount uint64) {
+	var memBefore, memAfter runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&memBefore)
+
+	start := time.Now()
+	fn()
+	timeMs = float64(time.Since(start).Nanoseconds()) / 1e6
+
+	runtime.ReadMemStats(&memAfter)
+	return timeMs, memAfter.TotalAlloc - memBefore.TotalAlloc, memAfter.Mallocs - memBefore.Mallocs
+}
+
+// countArrayElementsStreaming counts the elements of the arrays directly
+// under the top-level object using json.Decoder.Token, without building the
+// document tree.
+func countArrayElementsStreaming(data []byte) (int, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if token, err := decoder.Token(); err != nil {
+		return 0, err
+	} else if token != json.Delim('{') {
+		return 0, fmt.Errorf("top-level value is not an object")
+	}
+
+	count := 0
+	for decoder.More() {
+		if _, err := decoder.Token(); err != nil { // key
+			return 0, err
+		}
+		token, err := decoder.Token()
+		if err != nil {
+			return 0, err
+		}
+		switch token {
+		case json.Delim('['):
+			for decoder.More() {
+				if err := skipValue(decoder); err != nil {
+					return 0, err
+				}
+				count++
+			}
+			if _, err := decoder.Token(); err != nil { // ']'
+				return 0, err
+			}
+		case json.Delim('{'):
+			if err := skipContainer(decoder); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return count, nil
+}
+
+// skipValue consumes the next complete value from decoder.
+func skipValue(decoder *json.Decoder) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := token.(json.Delim); ok && (delim == '{' || delim == '[') {
+		return skipContainer(decoder)
+	}
+	return nil
+}
+
+// skipContainer consumes tokens up to and including the delimiter closing
+// the object or array whose opening delimiter was just read.
+func skipContainer(decoder *json.Decoder) error {
+	for depth := 1; depth > 0; {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+	}
+	return nil
+}
+
+// countArrayElements is countArrayElementsStreaming on an unmarshalled
+// document, used to check the streaming count.
+func countArrayElements(data interface{}) int {
+	object, ok := data.(map[string]interface{})
+	if !ok {
+		return 0
+	}
+	count := 0
+	for _, value := range object {
+		if array, ok := value.([]interface{}); ok {
+			count += len(array)
+		}
+	}
+	return count
+}
+
+// selectionFor returns the field selection of structure when select_fields
+// is among operations and the structure has one.
+func selectionFor(structure string, operations []string) (func() fieldSelection, bool) {
+	if !contains(operations, "select_fields") {
+		return nil, false
+	}
+	newSelection, ok := fieldSelections[structure]
+	return newSelection, ok
+}
+
+// warmupJson runs the selected operations on jsonData without timing them, so
+// that the first measured iteration does not pay for cold caches and the
+// encoder's lazily built type metadata.
+func warmupJson(jsonData interface{}, structure string, operations []string, query []pathStep) {
+	jsonString, err := encodeJson(jsonData)
+	if err != nil {
+		return
+	}
+	_, isJsonLines := jsonData.(jsonLines)
+	if contains(operations, "parse") && !isJsonLines {
+		var parsedData interface{}
+		json.Unmarshal(jsonString, &parsedData)
+	}
+	if contains(operations, "query") && !isJsonLines {
+		var parsedData interface{}
+		if json.Unmarshal(jsonString, &parsedData) == nil {
+			evaluatePath(parsedData, query)
+		}
+	}
+	if contains(operations, "traverse") {
+		traverseJson(jsonData)
+	}
+	if contains(operations, "token_count") && !isJsonLines {
+		countArrayElementsStreaming(jsonString)
+	}
+	if contains(operations, "stream_parse") {
+		streamParse(jsonString)
+	}
+	if newSelection, ok := selectionFor(structure, operations); ok {
+		json.Unmarshal(jsonString, newSelection())
+	}
+}
+
 func runJsonParsingBenchmark(config Config) TestResult {
 	params := config.Parameters
 
@@ -272,6 +779,15 @@ func runJsonParsingBenchmark(config Config) TestResult {
 		params.Iterations = 5
 	}
 
+	if params.Query == "" {
+		params.Query = defaultQuery
+	}
+	// Validate has already rejected expressions that do not parse
+	query, _ := parsePath(params.Query)
+
+	seed := cli.Seed(params.Seed)
+	rng = rand.New(rand.NewSource(seed))
+
 	startTime := time.Now()
 	var testCases []TestCase
 	allParseTimes := make([]float64, 0, len(params.JsonSizes)*len(params.JsonStructures)*params.Iterations)
@@ -286,7 +802,10 @@ func runJsonParsingBenchmark(config Config) TestResult {
 		"nested":      func(size int) interface{} { return generateNestedJson(size, 5) },
 		"array_heavy": generateArrayHeavyJson,
 		"mixed":       generateMixedJson,
+		"ndjson":      generateJsonLines,
+		"unicode":     generateUnicodeJson,
 	}
+	var unicodeParseTimes, unicodeStringifyTimes []float64
 
 	for _, size := range params.JsonSizes {
 		for _, structure := range params.JsonStructures {
@@ -297,19 +816,41 @@ func runJsonParsingBenchmark(config Config) TestResult {
 			}
 
 			fmt.Fprintf(os.Stderr, "Testing %s JSON, size: %d...\n", structure, size)
+			// parse, token_count and query need a single document; JSON
+			// Lines input is read with stream_parse instead
+			if structure == "ndjson" && (contains(params.Operations, "parse") ||
+				contains(params.Operations, "token_count") || contains(params.Operations, "query")) {
+				fmt.Fprintf(os.Stderr, "  Skipping parse, token_count and query for ndjson (use stream_parse)\n")
+			}
+			newSelection, selectFields := selectionFor(structure, params.Operations)
+			if contains(params.Operations, "select_fields") && !selectFields {
+				fmt.Fprintf(os.Stderr, "  Skipping select_fields: no field selection for %s\n", structure)
+			}
 
 			parseTimes := make([]float64, 0, params.Iterations)
 			stringifyTimes := make([]float64, 0, params.Iterations)
 			traverseTimes := make([]float64, 0, params.Iterations)
+			var parseBytes, parseAllocs []float64
+			var tokenCountTimes, tokenCountBytes []float64
+			var streamParseTimes, recordsPerSecond []float64
+			var selectTimes, selectBytes []float64
+			var queryTimes, queryMatches []float64
+			totalRecords := 0
 			iterationsData := make([]IterationResult, 0, params.Iterations)
 
+			for i := 0; i < params.WarmupIterations; i++ {
+				fmt.Fprintf(os.Stderr, "  Warmup %d/%d...\n", i+1, params.WarmupIterations)
+				warmupJson(generator(size), structure, params.Operations, query)
+			}
+
 			for i := 0; i < params.Iterations; i++ {
 				fmt.Fprintf(os.Stderr, "  Iteration %d/%d...\n", i+1, params.Iterations)
 
 				// Generate test data
 				jsonData := generator(size)
+				_, isJsonLines := jsonData.(jsonLines)
 
-				jsonBytes, _ := json.Marshal(jsonData)
+				jsonBytes, _ := encodeJson(jsonData)
 				dataSize := len(jsonBytes)
 
 				iterationResult := IterationResult{
@@ -322,7 +863,7 @@ func runJsonParsingBenchmark(config Config) TestResult {
 				success := true
 
 				// Parse operation
-				if contains(params.Operations, "parse") {
+				if contains(params.Operations, "parse") && !isJsonLines {
 					jsonString, err := json.Marshal(jsonData)
 					if err != nil {
 						success = false
@@ -331,10 +872,10 @@ func runJsonParsingBenchmark(config Config) TestResult {
 							Error:   stringPtr(fmt.Sprintf("Marshal failed: %v", err)),
 						}
 					} else {
-						start := time.Now()
 						var parsedData interface{}
-						err := json.Unmarshal(jsonString, &parsedData)
-						parseTime := float64(time.Since(start).Nanoseconds()) / 1e6
+						parseTime, bytesAllocated, allocCount := measure(func() {
+							err = json.Unmarshal(jsonString, &parsedData)
+						})
 
 						if err != nil {
 							success = false
@@ -345,12 +886,28 @@ func runJsonParsingBenchmark(config Config) TestResult {
 						} else {
 							parseTimes = append(parseTimes, parseTime)
 							allParseTimes = append(allParseTimes, parseTime)
+							parseBytes = append(parseBytes, float64(bytesAllocated))
+							parseAllocs = append(parseAllocs, float64(allocCount))
 
-							iterationResult.Operations["parse"] = OperationResult{
+							operation := OperationResult{
 								Success:          true,
 								TimeMs:           &parseTime,
 								JsonStringLength: intPtr(len(jsonString)),
+								BytesAllocated:   &bytesAllocated,
+								AllocCount:       &allocCount,
+							}
+							// Unicode documents hold only strings, so the
+							// parse must reproduce them exactly
+							if structure == "unicode" {
+								unicodeParseTimes = append(unicodeParseTimes, parseTime)
+								roundTrip := reflect.DeepEqual(parsedData, jsonData)
+								operation.RoundTripMatches = &roundTrip
+								if !roundTrip {
+									operation.Success = false
+									success = false
+								}
 							}
+							iterationResult.Operations["parse"] = operation
 						}
 					}
 				}
@@ -358,7 +915,7 @@ func runJsonParsingBenchmark(config Config) TestResult {
 				// Stringify operation
 				if contains(params.Operations, "stringify") {
 					start := time.Now()
-					jsonString, err := json.Marshal(jsonData)
+					jsonString, err := encodeJson(jsonData)
 					stringifyTime := float64(time.Since(start).Nanoseconds()) / 1e6
 
 					if err != nil {
@@ -370,6 +927,9 @@ func runJsonParsingBenchmark(config Config) TestResult {
 					} else {
 						stringifyTimes = append(stringifyTimes, stringifyTime)
 						allStringifyTimes = append(allStringifyTimes, stringifyTime)
+						if structure == "unicode" {
+							unicodeStringifyTimes = append(unicodeStringifyTimes, stringifyTime)
+						}
 
 						iterationResult.Operations["stringify"] = OperationResult{
 							Success:      true,
@@ -395,6 +955,146 @@ func runJsonParsingBenchmark(config Config) TestResult {
 					}
 				}
 
+				// Token count operation: count the top-level arrays'
+				// elements by streaming tokens instead of unmarshalling
+				if contains(params.Operations, "token_count") && !isJsonLines {
+					var elementCount int
+					jsonString, err := json.Marshal(jsonData)
+					if err == nil {
+						timeMs, bytesAllocated, allocCount := measure(func() {
+							elementCount, err = countArrayElementsStreaming(jsonString)
+						})
+						if err == nil {
+							var parsedData interface{}
+							json.Unmarshal(jsonString, &parsedData)
+							countMatches := elementCount == countArrayElements(parsedData)
+
+							tokenCountTimes = append(tokenCountTimes, timeMs)
+							tokenCountBytes = append(tokenCountBytes, float64(bytesAllocated))
+
+							iterationResult.Operations["token_count"] = OperationResult{
+								Success:        countMatches,
+								TimeMs:         &timeMs,
+								BytesAllocated: &bytesAllocated,
+								AllocCount:     &allocCount,
+								ElementCount:   &elementCount,
+								CountMatches:   &countMatches,
+							}
+							if !countMatches {
+								success = false
+							}
+						}
+					}
+					if err != nil {
+						success = false
+						iterationResult.Operations["token_count"] = OperationResult{
+							Success: false,
+							Error:   stringPtr(fmt.Sprintf("Token count failed: %v", err)),
+						}
+					}
+				}
+
+				// Stream parse operation: decode one value per
+				// json.Decoder.Decode call, as a JSON Lines reader does
+				if contains(params.Operations, "stream_parse") {
+					var recordCount int
+					var err error
+					timeMs, bytesAllocated, allocCount := measure(func() {
+						recordCount, err = streamParse(jsonBytes)
+					})
+					if err != nil {
+						success = false
+						iterationResult.Operations["stream_parse"] = OperationResult{
+							Success: false,
+							Error:   stringPtr(fmt.Sprintf("Stream parse failed: %v", err)),
+						}
+					} else {
+						countMatches := recordCount == expectedRecords(jsonData)
+						rate := float64(recordCount) / (timeMs / 1000)
+
+						streamParseTimes = append(streamParseTimes, timeMs)
+						recordsPerSecond = append(recordsPerSecond, rate)
+						totalRecords += recordCount
+
+						iterationResult.Operations["stream_parse"] = OperationResult{
+							Success:          countMatches,
+							TimeMs:           &timeMs,
+							BytesAllocated:   &bytesAllocated,
+							AllocCount:       &allocCount,
+							RecordCount:      &recordCount,
+							RecordsPerSecond: &rate,
+							CountMatches:     &countMatches,
+						}
+						if !countMatches {
+							success = false
+						}
+					}
+				}
+
+				// Select fields operation: unmarshal into a small typed
+				// struct instead of a full interface{} tree
+				if selectFields {
+					selection := newSelection()
+					var err error
+					timeMs, bytesAllocated, allocCount := measure(func() {
+						err = json.Unmarshal(jsonBytes, selection)
+					})
+					if err == nil {
+						var parsedData interface{}
+						err = json.Unmarshal(jsonBytes, &parsedData)
+						if err == nil {
+							fieldsMatch := selection.matches(parsedData)
+							selectTimes = append(selectTimes, timeMs)
+							selectBytes = append(selectBytes, float64(bytesAllocated))
+
+							iterationResult.Operations["select_fields"] = OperationResult{
+								Success:        fieldsMatch,
+								TimeMs:         &timeMs,
+								BytesAllocated: &bytesAllocated,
+								AllocCount:     &allocCount,
+								FieldsMatch:    &fieldsMatch,
+							}
+							if !fieldsMatch {
+								success = false
+							}
+						}
+					}
+					if err != nil {
+						success = false
+						iterationResult.Operations["select_fields"] = OperationResult{
+							Success: false,
+							Error:   stringPtr(fmt.Sprintf("Select fields failed: %v", err)),
+						}
+					}
+				}
+
+				// Query operation: evaluate the path expression over the
+				// parsed document; only the evaluation is timed
+				if contains(params.Operations, "query") && !isJsonLines {
+					var parsedData interface{}
+					if err := json.Unmarshal(jsonBytes, &parsedData); err != nil {
+						success = false
+						iterationResult.Operations["query"] = OperationResult{
+							Success: false,
+							Error:   stringPtr(fmt.Sprintf("Query parse failed: %v", err)),
+						}
+					} else {
+						start := time.Now()
+						matches := evaluatePath(parsedData, query)
+						queryTime := float64(time.Since(start).Nanoseconds()) / 1e6
+
+						matchCount := len(matches)
+						queryTimes = append(queryTimes, queryTime)
+						queryMatches = append(queryMatches, float64(matchCount))
+
+						iterationResult.Operations["query"] = OperationResult{
+							Success:    true,
+							TimeMs:     &queryTime,
+							MatchCount: &matchCount,
+						}
+					}
+				}
+
 				if success {
 					successfulTests++
 				} else {
@@ -413,13 +1113,35 @@ func runJsonParsingBenchmark(config Config) TestResult {
 			}
 
 			if len(parseTimes) > 0 {
-				testCase.AvgParseTime = average(parseTimes)
+				testCase.AvgParseTime = stats.Mean(parseTimes)
+				testCase.AvgParseBytesAllocated = stats.Mean(parseBytes)
+				testCase.AvgParseAllocCount = stats.Mean(parseAllocs)
+			}
+			if len(tokenCountTimes) > 0 {
+				testCase.AvgTokenCountTime = stats.Mean(tokenCountTimes)
+				testCase.AvgTokenCountBytes = stats.Mean(tokenCountBytes)
+			}
+			if len(streamParseTimes) > 0 {
+				testCase.AvgStreamParseTime = stats.Mean(streamParseTimes)
+				testCase.AvgRecordsPerSecond = stats.Mean(recordsPerSecond)
+				testCase.TotalRecordsParsed = totalRecords
+			}
+			if len(selectTimes) > 0 {
+				testCase.AvgSelectFieldsTime = stats.Mean(selectTimes)
+				testCase.AvgSelectFieldsBytes = stats.Mean(selectBytes)
+				if testCase.AvgParseTime > 0 {
+					testCase.SelectFieldsSpeedup = testCase.AvgParseTime / testCase.AvgSelectFieldsTime
+				}
+			}
+			if len(queryTimes) > 0 {
+				testCase.AvgQueryTime = stats.Mean(queryTimes)
+				testCase.AvgQueryMatchCount = stats.Mean(queryMatches)
 			}
 			if len(stringifyTimes) > 0 {
-				testCase.AvgStringifyTime = average(stringifyTimes)
+				testCase.AvgStringifyTime = stats.Mean(stringifyTimes)
 			}
 			if len(traverseTimes) > 0 {
-				testCase.AvgTraverseTime = average(traverseTimes)
+				testCase.AvgTraverseTime = stats.Mean(traverseTimes)
 			}
 
 			testCases = append(testCases, testCase)
@@ -428,26 +1150,36 @@ func runJsonParsingBenchmark(config Config) TestResult {
 
 	// Calculate overall summary
 	summary := Summary{
-		TotalTests:      totalTests,
-		SuccessfulTests: successfulTests,
-		FailedTests:     failedTests,
+		TotalTests:       totalTests,
+		SuccessfulTests:  successfulTests,
+		FailedTests:      failedTests,
+		WarmupIterations: params.WarmupIterations,
 	}
 
 	if len(allParseTimes) > 0 {
-		summary.AvgParseTime = average(allParseTimes)
+		summary.AvgParseTime = stats.Mean(allParseTimes)
 	}
 	if len(allStringifyTimes) > 0 {
-		summary.AvgStringifyTime = average(allStringifyTimes)
+		summary.AvgStringifyTime = stats.Mean(allStringifyTimes)
 	}
 	if len(allTraverseTimes) > 0 {
-		summary.AvgTraverseTime = average(allTraverseTimes)
+		summary.AvgTraverseTime = stats.Mean(allTraverseTimes)
+	}
+	if len(unicodeParseTimes) > 0 {
+		summary.AvgUnicodeParseTime = stats.Mean(unicodeParseTimes)
+	}
+	if len(unicodeStringifyTimes) > 0 {
+		summary.AvgUnicodeStringifyTime = stats.Mean(unicodeStringifyTimes)
 	}
 
 	endTime := time.Now()
 	executionTime := endTime.Sub(startTime).Seconds()
 
 	return TestResult{
+		SchemaVersion:      cli.SchemaVersion,
+		Environment:        cli.CurrentEnvironment(),
 		StartTime:          startTime.Unix(),
+		Seed:               seed,
 		TestCases:          testCases,
 		Summary:            summary,
 		EndTime:            endTime.Unix(),
@@ -456,34 +1188,26 @@ func runJsonParsingBenchmark(config Config) TestResult {
 }
 
 func main() {
-	if len(os.Args) < 2 {
-		fmt.Fprintf(os.Stderr, "Usage: %s <config_file>\n", os.Args[0])
-		os.Exit(1)
-	}
-
-	configFile := os.Args[1]
-
-	configData, err := os.ReadFile(configFile)
+	opts, err := cli.Parse(filepath.Base(os.Args[0]), os.Args[1:])
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: Config file '%s' not found: %v\n", configFile, err)
-		os.Exit(1)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
 	}
 
 	var config Config
-	if err := json.Unmarshal(configData, &config); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: Invalid JSON in config file: %v\n", err)
+	if err := opts.LoadConfig(&config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
 	results := runJsonParsingBenchmark(config)
+	results.Name = config.Name
+	results.Tags = config.Parameters.Tags
 
-	output, err := json.MarshalIndent(results, "", "  ")
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: Failed to marshal results: %v\n", err)
+	if err := opts.WriteResults(results); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
-
-	fmt.Println(string(output))
 }
 
 // Helper functions
@@ -503,17 +1227,6 @@ func contains(slice []string, item string) bool {
 	return false
 }
 
-func average(values []float64) float64 {
-	if len(values) == 0 {
-		return 0
-	}
-	sum := 0.0
-	for _, v := range values {
-		sum += v
-	}
-	return sum / float64(len(values))
-}
-
 func stringPtr(s string) *string {
 	return &s
 }
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// queryDocument is a small document of the mixed generator's shape, whose
+// values are known.
+const queryDocument = `{"metadata": {"total_records": 3}, "data": [
+	{"id": 0, "attributes": {"name": "Item_0", "value": 7, "tags": ["low"]}},
+	{"id": 1, "attributes": {"name": "Item_1", "value": 42, "tags": []}},
+	{"id": 2, "attributes": {"name": "Item_2", "value": 1000, "tags": ["urgent"]}}]}`
+
+func TestQuery(t *testing.T) {
+	var data interface{}
+	if err := json.Unmarshal([]byte(queryDocument), &data); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path string
+		want []interface{}
+	}{
+		{defaultQuery, []interface{}{7.0, 42.0, 1000.0}},
+		// Wildcards over objects and nested arrays
+		{"data[*].attributes.tags[*]", []interface{}{"low", "urgent"}},
+		{"metadata.*", []interface{}{3.0}},
+		{"metadata.total_records", []interface{}{3.0}},
+		{"missing.key", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			steps, err := parsePath(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := evaluatePath(data, steps); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("evaluatePath(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestUnicodeRoundTrip marshals a string mixing two-, three- and four-byte
+// characters with ones encoding/json escapes, and checks that the
+// multibyte text is written unescaped and reads back unchanged.
+func TestUnicodeRoundTrip(t *testing.T) {
+	original := map[string]interface{}{"texte": "café 中文 😀 <b>&\u2028"}
+	encoded, err := json.Marshal(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(encoded, []byte("café 中文 😀")) {
+		t.Errorf("multibyte text escaped in %s", encoded)
+	}
+	if !bytes.Contains(encoded, []byte(`\u003cb\u003e\u0026\u2028`)) {
+		t.Errorf("HTML and line separator characters not escaped in %s", encoded)
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, original) {
+		t.Errorf("round trip = %v, want %v", decoded, original)
+	}
+}
@@ -1,3 +1,5 @@
 module large_file_read
 
-go 1.21
\ No newline at end of file
+go 1.21
+
+require golang.org/x/sys v0.25.0
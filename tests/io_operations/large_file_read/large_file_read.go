@@ -1,7 +1,10 @@
 package main
 
 import (
-	"encoding/json"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
 	"fmt"
 	"io"
 	"math/rand"
@@ -9,14 +12,21 @@ import (
 	"path/filepath"
 	"runtime"
 	"time"
+
+	"benchmark_test/internal/cli"
+	"benchmark_test/internal/fadvise"
+	"benchmark_test/internal/stats"
 )
 
 type ReadResult struct {
-	ReadTime       float64  `json:"read_time"`
-	BytesRead      int64    `json:"bytes_read"`
-	ThroughputMbps float64  `json:"throughput_mbps"`
-	ChunkCount     *int     `json:"chunk_count,omitempty"`
-	AvgChunkSize   *float64 `json:"avg_chunk_size,omitempty"`
+	ReadTime            float64  `json:"read_time"`
+	BytesRead           int64    `json:"bytes_read"`
+	ThroughputMbps      float64  `json:"throughput_mbps"`
+	ChunkCount          *int     `json:"chunk_count,omitempty"`
+	AvgChunkSize        *float64 `json:"avg_chunk_size,omitempty"`
+	CompressedBytesRead *int64   `json:"compressed_bytes_read,omitempty"`
+	LineCount           *int64   `json:"line_count,omitempty"`
+	LinesPerSecond      *float64 `json:"lines_per_second,omitempty"`
 }
 
 type IterationResult struct {
@@ -29,6 +39,14 @@ type IterationResult struct {
 	ChunkCount     *int     `json:"chunk_count,omitempty"`
 	AvgChunkSize   *float64 `json:"avg_chunk_size,omitempty"`
 	Error          *string  `json:"error,omitempty"`
+
+	// CompressedBytesRead is what the compressed pattern read from disk;
+	// BytesRead and ThroughputMbps are then the decompressed figures.
+	CompressedBytesRead *int64 `json:"compressed_bytes_read,omitempty"`
+
+	// LineCount and LinesPerSecond are set by the line-counting patterns
+	LineCount      *int64   `json:"line_count,omitempty"`
+	LinesPerSecond *float64 `json:"lines_per_second,omitempty"`
 }
 
 type TestCase struct {
@@ -39,35 +57,131 @@ type TestCase struct {
 	AvgReadTime      float64           `json:"avg_read_time"`
 	AvgThroughput    float64           `json:"avg_throughput"`
 	MemoryEfficiency float64           `json:"memory_efficiency"`
+	CompressionRatio *float64          `json:"compression_ratio,omitempty"`
+	// Fadvise is the page-cache hint given before each read, when the
+	// fadvise parameter is set. ThroughputChangePercent compares the
+	// average throughput with the "none" case of the same file, buffer and
+	// pattern. FadviseUnsupported marks a hinted case skipped because the
+	// platform has no posix_fadvise.
+	Fadvise                 string   `json:"fadvise,omitempty"`
+	ThroughputChangePercent *float64 `json:"throughput_change_percent,omitempty"`
+	FadviseUnsupported      bool     `json:"fadvise_unsupported,omitempty"`
+	// AvgLinesPerSecond is set for the line-counting patterns.
+	// ScannerSpeedup is the count_lines rate over the count_lines_scanner
+	// rate for the same file, buffer and hint.
+	AvgLinesPerSecond float64  `json:"avg_lines_per_second,omitempty"`
+	ScannerSpeedup    *float64 `json:"scanner_speedup,omitempty"`
 }
 
 type Summary struct {
-	TotalTests      int     `json:"total_tests"`
-	SuccessfulTests int     `json:"successful_tests"`
-	FailedTests     int     `json:"failed_tests"`
-	AvgReadTime     float64 `json:"avg_read_time"`
-	AvgThroughput   float64 `json:"avg_throughput"`
-	PeakMemoryUsage float64 `json:"peak_memory_usage"`
+	TotalTests       int     `json:"total_tests"`
+	SuccessfulTests  int     `json:"successful_tests"`
+	FailedTests      int     `json:"failed_tests"`
+	AvgReadTime      float64 `json:"avg_read_time"`
+	AvgThroughput    float64 `json:"avg_throughput"`
+	PeakMemoryUsage  float64 `json:"peak_memory_usage"`
+	WarmupIterations int     `json:"warmup_iterations"`
+	TimedOut         bool    `json:"timed_out"`
 }
 
 type BenchmarkResult struct {
-	StartTime     float64    `json:"start_time"`
-	EndTime       float64    `json:"end_time"`
-	TotalDuration float64    `json:"total_duration"`
-	TestCases     []TestCase `json:"test_cases"`
-	Summary       Summary    `json:"summary"`
+	SchemaVersion string          `json:"schema_version"`
+	Environment   cli.Environment `json:"environment"`
+	Name          string          `json:"name,omitempty"`
+	Tags          []string        `json:"tags,omitempty"`
+	StartTime     float64         `json:"start_time"`
+	Seed          int64           `json:"seed"`
+	EndTime       float64         `json:"end_time"`
+	TotalDuration float64         `json:"total_duration"`
+	TestCases     []TestCase      `json:"test_cases"`
+	Summary       Summary         `json:"summary"`
 }
 
 type Config struct {
-	Parameters map[string]interface{} `json:"parameters"`
+	Parameters Parameters `json:"parameters"`
+	Name       string     `json:"name"`
+}
+
+type Parameters struct {
+	FileSizes         []int    `json:"file_sizes"`
+	BufferSizes       []int    `json:"buffer_sizes"`
+	ReadPatterns      []string `json:"read_patterns"`
+	Iterations        *int     `json:"iterations,omitempty"`
+	WarmupIterations  int      `json:"warmup_iterations"`
+	GenerateTestFiles *bool    `json:"generate_test_files,omitempty"`
+	DeadlineSeconds   int      `json:"deadline_seconds"`
+	Fadvise           []string `json:"fadvise"`
+	Seed              *int64   `json:"seed,omitempty"`
+	Tags              []string `json:"tags"`
+}
+
+// Validate rejects sizes and counts that cannot run. Empty lists and an
+// unset iteration count take the defaults in runLargeFileReadBenchmark.
+func (c *Config) Validate() error {
+	p := c.Parameters
+	return cli.FirstError(
+		cli.EachPositive("file_sizes", p.FileSizes),
+		cli.EachPositive("buffer_sizes", p.BufferSizes),
+		cli.OptionalPositive("iterations", p.Iterations),
+		cli.NonNegative("warmup_iterations", p.WarmupIterations),
+		cli.NonNegative("deadline_seconds", p.DeadlineSeconds),
+		validFadvise(p.Fadvise),
+	)
+}
+
+func validFadvise(hints []string) error {
+	for i, hint := range hints {
+		if !fadvise.Valid(hint) {
+			return &cli.FieldError{
+				Field:   fmt.Sprintf("fadvise[%d]", i),
+				Message: fmt.Sprintf("unknown hint %q (expected none, sequential, random or dontneed)", hint),
+			}
+		}
+	}
+	return nil
+}
+
+// rng picks the byte pattern repeated through generated test files.
+var rng *rand.Rand
+
+// fileLines is the newline count generateTestFile wrote, which the line
+// counting patterns are checked against.
+type fileLines struct {
+	newlines int64
+	// unterminated is set when the file ends in a partial line, which
+	// bufio.Scanner counts as a line of its own
+	unterminated bool
+}
+
+// scannerLines is the number of lines bufio.Scanner finds in the file
+func (f fileLines) scannerLines() int64 {
+	if f.unterminated {
+		return f.newlines + 1
+	}
+	return f.newlines
+}
+
+// contextReader fails reads with ctx's error once ctx is done, so a read of
+// a huge file stops at the run's deadline.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
 }
 
-func generateTestFile(filePath string, sizeBytes int64) error {
+func (c contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
+
+func generateTestFile(ctx context.Context, filePath string, sizeBytes int64) (fileLines, error) {
 	fmt.Fprintf(os.Stderr, "Generating test file: %d bytes...\n", sizeBytes)
 
+	var lines fileLines
 	file, err := os.Create(filePath)
 	if err != nil {
-		return err
+		return lines, err
 	}
 	defer file.Close()
 
@@ -78,11 +192,14 @@ func generateTestFile(filePath string, sizeBytes int64) error {
 	// Pre-generate a pattern to avoid repeated random generation
 	pattern := make([]byte, 1024)
 	for i := range pattern {
-		pattern[i] = chars[i%len(chars)]
+		pattern[i] = chars[rng.Intn(len(chars))]
 	}
 
 	var bytesWritten int64
 	for bytesWritten < sizeBytes {
+		if err := ctx.Err(); err != nil {
+			return lines, err
+		}
 		remaining := sizeBytes - bytesWritten
 		currentChunkSize := chunkSize
 		if remaining < chunkSize {
@@ -93,19 +210,60 @@ func generateTestFile(filePath string, sizeBytes int64) error {
 		data := make([]byte, currentChunkSize)
 		for i := 0; i < currentChunkSize; i++ {
 			data[i] = pattern[i%len(pattern)]
+			if data[i] == '\n' {
+				lines.newlines++
+			}
 		}
+		lines.unterminated = data[currentChunkSize-1] != '\n'
 
 		n, err := file.Write(data)
 		if err != nil {
-			return err
+			return lines, err
 		}
 		bytesWritten += int64(n)
 	}
 
-	return file.Sync()
+	return lines, file.Sync()
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r     io.Reader
+	count int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.count += int64(n)
+	return n, err
+}
+
+// compressTestFile writes a gzip-compressed copy of srcPath to dstPath at
+// the default compression level.
+func compressTestFile(ctx context.Context, srcPath, dstPath string) error {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.Create(dstPath)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	writer := gzip.NewWriter(dst)
+	if _, err := io.Copy(writer, contextReader{ctx, src}); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return dst.Sync()
 }
 
-func readFileSequential(filePath string, bufferSize int) (*ReadResult, error) {
+func readFileSequential(ctx context.Context, filePath string, bufferSize int, hint string) (*ReadResult, error) {
 	startTime := time.Now()
 
 	file, err := os.Open(filePath)
@@ -113,6 +271,9 @@ func readFileSequential(filePath string, bufferSize int) (*ReadResult, error) {
 		return nil, err
 	}
 	defer file.Close()
+	if err := fadvise.Apply(file, hint); err != nil {
+		return nil, err
+	}
 
 	// Use larger buffer for better performance
 	optimalBufferSize := bufferSize
@@ -124,7 +285,7 @@ func readFileSequential(filePath string, bufferSize int) (*ReadResult, error) {
 	var totalBytes int64
 
 	// Use io.CopyBuffer for more efficient reading
-	n, err := io.CopyBuffer(io.Discard, file, buffer)
+	n, err := io.CopyBuffer(io.Discard, contextReader{ctx, file}, buffer)
 	if err != nil {
 		return nil, err
 	}
@@ -147,7 +308,7 @@ func readFileSequential(filePath string, bufferSize int) (*ReadResult, error) {
 	}, nil
 }
 
-func readFileChunked(filePath string, bufferSize int) (*ReadResult, error) {
+func readFileChunked(ctx context.Context, filePath string, bufferSize int, hint string) (*ReadResult, error) {
 	startTime := time.Now()
 
 	file, err := os.Open(filePath)
@@ -155,6 +316,9 @@ func readFileChunked(filePath string, bufferSize int) (*ReadResult, error) {
 		return nil, err
 	}
 	defer file.Close()
+	if err := fadvise.Apply(file, hint); err != nil {
+		return nil, err
+	}
 
 	// Use optimal buffer size
 	optimalBufferSize := bufferSize
@@ -167,6 +331,9 @@ func readFileChunked(filePath string, bufferSize int) (*ReadResult, error) {
 	var chunkCount int
 
 	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		n, err := file.Read(buffer)
 		if err != nil {
 			if err == io.EOF {
@@ -200,73 +367,226 @@ func readFileChunked(filePath string, bufferSize int) (*ReadResult, error) {
 	}, nil
 }
 
+// readFileCompressed decompresses a gzip file on the fly through
+// gzip.NewReader. BytesRead and the throughput are for the decompressed
+// data; CompressedBytesRead is what came off disk.
+func readFileCompressed(ctx context.Context, filePath string, bufferSize int, hint string) (*ReadResult, error) {
+	startTime := time.Now()
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if err := fadvise.Apply(file, hint); err != nil {
+		return nil, err
+	}
+
+	compressed := &countingReader{r: file}
+	reader, err := gzip.NewReader(compressed)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	buffer := make([]byte, bufferSize)
+	totalBytes, err := io.CopyBuffer(io.Discard, contextReader{ctx, reader}, buffer)
+	if err != nil {
+		return nil, err
+	}
+
+	readTime := time.Since(startTime)
+	readTimeMs := float64(readTime.Nanoseconds()) / 1e6 // Convert to milliseconds
+
+	var throughputMbps float64
+	if readTime.Seconds() > 0 {
+		throughputMbps = (float64(totalBytes) / (1024 * 1024)) / readTime.Seconds()
+	}
+
+	compressedBytes := compressed.count
+	return &ReadResult{
+		ReadTime:            readTimeMs,
+		BytesRead:           totalBytes,
+		ThroughputMbps:      throughputMbps,
+		CompressedBytesRead: &compressedBytes,
+	}, nil
+}
+
+// maxScanLine caps the line length count_lines_scanner accepts. Generated
+// lines are short, but the scanner's 64KB default is not guaranteed to
+// hold one.
+const maxScanLine = 1 << 30
+
+// lineBufferSize is the read buffer of the line-counting patterns: the
+// configured size, but at least 64KB as for sequential reads.
+func lineBufferSize(bufferSize int) int {
+	if bufferSize < 64*1024 {
+		return 64 * 1024
+	}
+	return bufferSize
+}
+
+// countLines counts the newline bytes in filePath with bytes.Count over
+// each buffer read, the way wc -l does. The count is in LineCount.
+func countLines(ctx context.Context, filePath string, bufferSize int, hint string) (*ReadResult, error) {
+	return timeLineCount(filePath, hint, func(file *os.File) (int64, int64, error) {
+		buffer := make([]byte, lineBufferSize(bufferSize))
+		var lines, totalBytes int64
+		for {
+			if err := ctx.Err(); err != nil {
+				return 0, 0, err
+			}
+			n, err := file.Read(buffer)
+			lines += int64(bytes.Count(buffer[:n], []byte{'\n'}))
+			totalBytes += int64(n)
+			if err == io.EOF {
+				return lines, totalBytes, nil
+			}
+			if err != nil {
+				return 0, 0, err
+			}
+		}
+	})
+}
+
+// countLinesScanner counts lines with bufio.Scanner, which also finds the
+// bounds of each line and counts a final unterminated one.
+func countLinesScanner(ctx context.Context, filePath string, bufferSize int, hint string) (*ReadResult, error) {
+	return timeLineCount(filePath, hint, func(file *os.File) (int64, int64, error) {
+		counter := &countingReader{r: contextReader{ctx, file}}
+		scanner := bufio.NewScanner(counter)
+		scanner.Buffer(make([]byte, lineBufferSize(bufferSize)), maxScanLine)
+		var lines int64
+		for scanner.Scan() {
+			lines++
+		}
+		return lines, counter.count, scanner.Err()
+	})
+}
+
+// timeLineCount opens filePath, applies the fadvise hint and times count,
+// which returns the lines and bytes it read.
+func timeLineCount(filePath, hint string, count func(*os.File) (int64, int64, error)) (*ReadResult, error) {
+	startTime := time.Now()
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if err := fadvise.Apply(file, hint); err != nil {
+		return nil, err
+	}
+
+	lines, totalBytes, err := count(file)
+	if err != nil {
+		return nil, err
+	}
+
+	readTime := time.Since(startTime)
+	var throughputMbps, linesPerSecond float64
+	if readTime.Seconds() > 0 {
+		throughputMbps = (float64(totalBytes) / (1024 * 1024)) / readTime.Seconds()
+		linesPerSecond = float64(lines) / readTime.Seconds()
+	}
+
+	return &ReadResult{
+		ReadTime:       float64(readTime.Nanoseconds()) / 1e6,
+		BytesRead:      totalBytes,
+		ThroughputMbps: throughputMbps,
+		LineCount:      &lines,
+		LinesPerSecond: &linesPerSecond,
+	}, nil
+}
+
 func getMemoryUsage() float64 {
 	var m runtime.MemStats
 	runtime.ReadMemStats(&m)
 	return float64(m.Alloc) / (1024 * 1024) // Convert to MB
 }
 
-func performReadTest(filePath string, bufferSize int, pattern string) (*ReadResult, error) {
+// performReadTest reads filePath with pattern after giving the kernel the
+// fadvise hint, which counts towards the read time.
+func performReadTest(ctx context.Context, filePath string, bufferSize int, pattern, hint string) (*ReadResult, error) {
 	switch pattern {
 	case "sequential":
-		return readFileSequential(filePath, bufferSize)
+		return readFileSequential(ctx, filePath, bufferSize, hint)
 	case "chunked":
-		return readFileChunked(filePath, bufferSize)
+		return readFileChunked(ctx, filePath, bufferSize, hint)
+	case "compressed":
+		return readFileCompressed(ctx, filePath, bufferSize, hint)
+	case "count_lines":
+		return countLines(ctx, filePath, bufferSize, hint)
+	case "count_lines_scanner":
+		return countLinesScanner(ctx, filePath, bufferSize, hint)
 	default:
 		return nil, fmt.Errorf("unknown read pattern: %s", pattern)
 	}
 }
 
-func getIntSlice(data interface{}, defaultVal []int64) []int64 {
-	if arr, ok := data.([]interface{}); ok {
-		result := make([]int64, 0, len(arr))
-		for _, v := range arr {
-			if num, ok := v.(float64); ok {
-				result = append(result, int64(num))
-			}
-		}
-		if len(result) > 0 {
-			return result
+// runLargeFileReadBenchmark runs every file size, buffer size and read
+// pattern combination. When ctx's deadline passes, the test case in progress
+// keeps the iterations it completed and the remaining ones are skipped.
+func runLargeFileReadBenchmark(ctx context.Context, parameters Parameters) (*BenchmarkResult, error) {
+	// Parse configuration with defaults
+	fileSizes := []int64{1048576} // Default 1MB
+	if len(parameters.FileSizes) > 0 {
+		fileSizes = make([]int64, len(parameters.FileSizes))
+		for i, v := range parameters.FileSizes {
+			fileSizes[i] = int64(v)
 		}
 	}
-	return defaultVal
-}
 
-func getStringSlice(data interface{}, defaultVal []string) []string {
-	if arr, ok := data.([]interface{}); ok {
-		result := make([]string, 0, len(arr))
-		for _, v := range arr {
-			if str, ok := v.(string); ok {
-				result = append(result, str)
+	bufferSizes := parameters.BufferSizes
+	if len(bufferSizes) == 0 {
+		bufferSizes = []int{4096}
+	}
+
+	readPatterns := parameters.ReadPatterns
+	if len(readPatterns) == 0 {
+		readPatterns = []string{"sequential"}
+	}
+	// count_lines is measured against the bufio.Scanner count, so that
+	// runs right after it unless it was asked for too
+	if contains(readPatterns, "count_lines") && !contains(readPatterns, "count_lines_scanner") {
+		var expanded []string
+		for _, pattern := range readPatterns {
+			expanded = append(expanded, pattern)
+			if pattern == "count_lines" {
+				expanded = append(expanded, "count_lines_scanner")
 			}
 		}
-		if len(result) > 0 {
-			return result
-		}
+		readPatterns = expanded
 	}
-	return defaultVal
-}
-
-func runLargeFileReadBenchmark(parameters map[string]interface{}) (*BenchmarkResult, error) {
-	// Parse configuration with defaults
-	fileSizes := getIntSlice(parameters["file_sizes"], []int64{1048576}) // Default 1MB
 
-	bufferSizesInt64 := getIntSlice(parameters["buffer_sizes"], []int64{4096})
-	bufferSizes := make([]int, len(bufferSizesInt64))
-	for i, v := range bufferSizesInt64 {
-		bufferSizes[i] = int(v)
+	iterations := 3
+	if parameters.Iterations != nil {
+		iterations = *parameters.Iterations
 	}
 
-	readPatterns := getStringSlice(parameters["read_patterns"], []string{"sequential"})
-
-	iterations := 3
-	if val, ok := parameters["iterations"].(float64); ok {
-		iterations = int(val)
+	// Warmup reads also populate the page cache, so measured iterations
+	// compare read strategies rather than disk latency.
+	warmupIterations := parameters.WarmupIterations
+
+	seed := cli.Seed(parameters.Seed)
+	rng = rand.New(rand.NewSource(seed))
+
+	// Without the fadvise parameter no hint is given and test cases carry no
+	// fadvise field. With it, a "none" baseline runs first so each hint can
+	// be compared against it.
+	hints := []string{""}
+	if len(parameters.Fadvise) > 0 {
+		hints = []string{fadvise.None}
+		for _, hint := range parameters.Fadvise {
+			if hint != fadvise.None {
+				hints = append(hints, hint)
+			}
+		}
 	}
 
 	generateTestFiles := true
-	if val, ok := parameters["generate_test_files"].(bool); ok {
-		generateTestFiles = val
+	if parameters.GenerateTestFiles != nil {
+		generateTestFiles = *parameters.GenerateTestFiles
 	}
 
 	startTime := time.Now()
@@ -282,123 +602,258 @@ func runLargeFileReadBenchmark(parameters map[string]interface{}) (*BenchmarkRes
 		return nil, fmt.Errorf("failed to create temp directory: %v", err)
 	}
 	defer os.RemoveAll(tempDir)
+	// generated holds the lines written to each generated file
+	generated := make(map[string]fileLines)
 
+cases:
 	for _, fileSize := range fileSizes {
 		for _, bufferSize := range bufferSizes {
 			for _, pattern := range readPatterns {
-				fmt.Fprintf(os.Stderr, "Testing file size: %d bytes, buffer: %d, pattern: %s...\n", fileSize, bufferSize, pattern)
+				for _, hint := range hints {
+					if ctx.Err() != nil {
+						fmt.Fprintf(os.Stderr, "Deadline reached, skipping remaining test cases\n")
+						break cases
+					}
+					if hint == "" {
+						fmt.Fprintf(os.Stderr, "Testing file size: %d bytes, buffer: %d, pattern: %s...\n", fileSize, bufferSize, pattern)
+					} else {
+						fmt.Fprintf(os.Stderr, "Testing file size: %d bytes, buffer: %d, pattern: %s, fadvise: %s...\n", fileSize, bufferSize, pattern, hint)
+					}
 
-				testCase := TestCase{
-					FileSize:    fileSize,
-					BufferSize:  bufferSize,
-					ReadPattern: pattern,
-					Iterations:  []IterationResult{},
-				}
+					testCase := TestCase{
+						FileSize:    fileSize,
+						BufferSize:  bufferSize,
+						ReadPattern: pattern,
+						Iterations:  []IterationResult{},
+						Fadvise:     hint,
+					}
 
-				// Generate test file if needed
-				testFilePath := filepath.Join(tempDir, fmt.Sprintf("test_file_%d_%d.txt", fileSize, bufferSize))
-				if generateTestFiles {
-					if _, err := os.Stat(testFilePath); os.IsNotExist(err) {
-						if err := generateTestFile(testFilePath, fileSize); err != nil {
-							return nil, fmt.Errorf("failed to generate test file: %v", err)
+					if hint != "" && hint != fadvise.None && !fadvise.Supported {
+						fmt.Fprintf(os.Stderr, "  fadvise is not supported on %s, skipping\n", runtime.GOOS)
+						testCase.FadviseUnsupported = true
+						testCases = append(testCases, testCase)
+						continue
+					}
+
+					// Generate test file if needed
+					testFilePath := filepath.Join(tempDir, fmt.Sprintf("test_file_%d_%d.txt", fileSize, bufferSize))
+					if generateTestFiles {
+						if _, err := os.Stat(testFilePath); os.IsNotExist(err) {
+							lines, err := generateTestFile(ctx, testFilePath, fileSize)
+							if err != nil {
+								if ctx.Err() != nil {
+									break cases
+								}
+								return nil, fmt.Errorf("failed to generate test file: %v", err)
+							}
+							generated[testFilePath] = lines
 						}
 					}
-				}
 
-				var readTimes, throughputs []float64
+					// The compressed pattern reads a gzip copy of the same file
+					if pattern == "compressed" {
+						compressedPath := testFilePath + ".gz"
+						if _, err := os.Stat(compressedPath); os.IsNotExist(err) {
+							if err := compressTestFile(ctx, testFilePath, compressedPath); err != nil {
+								if ctx.Err() != nil {
+									break cases
+								}
+								return nil, fmt.Errorf("failed to compress test file: %v", err)
+							}
+						}
+						if info, err := os.Stat(compressedPath); err == nil && info.Size() > 0 {
+							ratio := float64(fileSize) / float64(info.Size())
+							testCase.CompressionRatio = &ratio
+						}
+						testFilePath = compressedPath
+					}
+
+					for i := 0; i < warmupIterations && ctx.Err() == nil; i++ {
+						fmt.Fprintf(os.Stderr, "  Warmup %d/%d...\n", i+1, warmupIterations)
+						performReadTest(ctx, testFilePath, bufferSize, pattern, hint)
+					}
+
+					var readTimes, throughputs, linesPerSecond []float64
+					expectedLines, linesKnown := generated[testFilePath]
+
+					for i := 0; i < iterations && ctx.Err() == nil; i++ {
+						fmt.Fprintf(os.Stderr, "  Iteration %d/%d...\n", i+1, iterations)
+
+						memoryBefore := getMemoryUsage()
 
-				for i := 0; i < iterations; i++ {
-					fmt.Fprintf(os.Stderr, "  Iteration %d/%d...\n", i+1, iterations)
-					totalTests++
+						readResult, err := performReadTest(ctx, testFilePath, bufferSize, pattern, hint)
+						if err != nil && ctx.Err() != nil {
+							break // cut short by the deadline, not a failed read
+						}
+						totalTests++
+						if err == nil && pattern == "compressed" && readResult.BytesRead != fileSize {
+							err = fmt.Errorf("decompressed %d bytes, expected %d", readResult.BytesRead, fileSize)
+						}
+						if err == nil && readResult.LineCount != nil && linesKnown {
+							expected := expectedLines.newlines
+							if pattern == "count_lines_scanner" {
+								expected = expectedLines.scannerLines()
+							}
+							if *readResult.LineCount != expected {
+								err = fmt.Errorf("counted %d lines, expected %d", *readResult.LineCount, expected)
+							}
+						}
+						if err != nil {
+							fmt.Fprintf(os.Stderr, "Error in iteration %d: %v\n", i+1, err)
+							failedTests++
+							errMsg := err.Error()
+							iteration := IterationResult{
+								Iteration:      i + 1,
+								ReadTime:       0.0,
+								ThroughputMbps: 0.0,
+								Error:          &errMsg,
+							}
+							testCase.Iterations = append(testCase.Iterations, iteration)
+							continue
+						}
 
-					memoryBefore := getMemoryUsage()
+						memoryAfter := getMemoryUsage()
+						memoryUsed := memoryAfter - memoryBefore
+						peakMemory = max(peakMemory, memoryAfter)
 
-					readResult, err := performReadTest(testFilePath, bufferSize, pattern)
-					if err != nil {
-						fmt.Fprintf(os.Stderr, "Error in iteration %d: %v\n", i+1, err)
-						failedTests++
-						errMsg := err.Error()
 						iteration := IterationResult{
 							Iteration:      i + 1,
-							ReadTime:       0.0,
-							ThroughputMbps: 0.0,
-							Error:          &errMsg,
+							ReadTime:       readResult.ReadTime,
+							BytesRead:      readResult.BytesRead,
+							ThroughputMbps: readResult.ThroughputMbps,
+							MemoryUsed:     memoryUsed,
+							IOWaitTime:     readResult.ReadTime, // Approximation
+							ChunkCount:     readResult.ChunkCount,
+							AvgChunkSize:   readResult.AvgChunkSize,
+
+							CompressedBytesRead: readResult.CompressedBytesRead,
+							LineCount:           readResult.LineCount,
+							LinesPerSecond:      readResult.LinesPerSecond,
 						}
-						testCase.Iterations = append(testCase.Iterations, iteration)
-						continue
-					}
 
-					memoryAfter := getMemoryUsage()
-					memoryUsed := memoryAfter - memoryBefore
-					peakMemory = max(peakMemory, memoryAfter)
-
-					iteration := IterationResult{
-						Iteration:      i + 1,
-						ReadTime:       readResult.ReadTime,
-						BytesRead:      readResult.BytesRead,
-						ThroughputMbps: readResult.ThroughputMbps,
-						MemoryUsed:     memoryUsed,
-						IOWaitTime:     readResult.ReadTime, // Approximation
-						ChunkCount:     readResult.ChunkCount,
-						AvgChunkSize:   readResult.AvgChunkSize,
+						testCase.Iterations = append(testCase.Iterations, iteration)
+						readTimes = append(readTimes, readResult.ReadTime)
+						throughputs = append(throughputs, readResult.ThroughputMbps)
+						if readResult.LinesPerSecond != nil {
+							linesPerSecond = append(linesPerSecond, *readResult.LinesPerSecond)
+						}
+						successfulTests++
 					}
 
-					testCase.Iterations = append(testCase.Iterations, iteration)
-					readTimes = append(readTimes, readResult.ReadTime)
-					throughputs = append(throughputs, readResult.ThroughputMbps)
-					successfulTests++
-				}
+					// Calculate averages for this test case
+					if len(readTimes) > 0 {
+						testCase.AvgReadTime = stats.Mean(readTimes)
+						testCase.AvgThroughput = stats.Mean(throughputs)
+						testCase.MemoryEfficiency = (float64(fileSize) / (1024 * 1024)) / max(1.0, peakMemory)
+						if len(linesPerSecond) > 0 {
+							testCase.AvgLinesPerSecond = stats.Mean(linesPerSecond)
+						}
 
-				// Calculate averages for this test case
-				if len(readTimes) > 0 {
-					testCase.AvgReadTime = average(readTimes)
-					testCase.AvgThroughput = average(throughputs)
-					testCase.MemoryEfficiency = (float64(fileSize) / (1024 * 1024)) / max(1.0, peakMemory)
+						allReadTimes = append(allReadTimes, readTimes...)
+						allThroughputs = append(allThroughputs, throughputs...)
+					}
 
-					allReadTimes = append(allReadTimes, readTimes...)
-					allThroughputs = append(allThroughputs, throughputs...)
+					testCases = append(testCases, testCase)
 				}
-
-				testCases = append(testCases, testCase)
 			}
 		}
 	}
 
+	if len(parameters.Fadvise) > 0 {
+		compareFadvise(testCases)
+	}
+	compareLineCounts(testCases)
+
 	endTime := time.Now()
 	totalDuration := endTime.Sub(startTime).Seconds()
 
 	// Calculate overall summary
 	var avgReadTime, avgThroughput float64
 	if len(allReadTimes) > 0 {
-		avgReadTime = average(allReadTimes)
-		avgThroughput = average(allThroughputs)
+		avgReadTime = stats.Mean(allReadTimes)
+		avgThroughput = stats.Mean(allThroughputs)
 	}
 
 	return &BenchmarkResult{
+		SchemaVersion: cli.SchemaVersion,
+		Environment:   cli.CurrentEnvironment(),
 		StartTime:     float64(startTime.Unix()),
+		Seed:          seed,
 		EndTime:       float64(endTime.Unix()),
 		TotalDuration: totalDuration,
 		TestCases:     testCases,
 		Summary: Summary{
-			TotalTests:      totalTests,
-			SuccessfulTests: successfulTests,
-			FailedTests:     failedTests,
-			AvgReadTime:     avgReadTime,
-			AvgThroughput:   avgThroughput,
-			PeakMemoryUsage: peakMemory,
+			TotalTests:       totalTests,
+			SuccessfulTests:  successfulTests,
+			FailedTests:      failedTests,
+			AvgReadTime:      avgReadTime,
+			AvgThroughput:    avgThroughput,
+			PeakMemoryUsage:  peakMemory,
+			WarmupIterations: warmupIterations,
+			TimedOut:         cli.TimedOut(ctx),
 		},
 	}, nil
 }
 
-func average(values []float64) float64 {
-	if len(values) == 0 {
-		return 0.0
+// compareFadvise sets ThroughputChangePercent on each hinted test case that
+// has a measured "none" case with the same file size, buffer size and
+// pattern.
+func compareFadvise(testCases []TestCase) {
+	type caseKey struct {
+		fileSize   int64
+		bufferSize int
+		pattern    string
+	}
+	baselines := make(map[caseKey]float64)
+	for _, testCase := range testCases {
+		if testCase.Fadvise == fadvise.None && testCase.AvgThroughput > 0 {
+			baselines[caseKey{testCase.FileSize, testCase.BufferSize, testCase.ReadPattern}] = testCase.AvgThroughput
+		}
+	}
+	for i := range testCases {
+		testCase := &testCases[i]
+		baseline, ok := baselines[caseKey{testCase.FileSize, testCase.BufferSize, testCase.ReadPattern}]
+		if testCase.Fadvise == fadvise.None || !ok || testCase.AvgThroughput == 0 {
+			continue
+		}
+		change := (testCase.AvgThroughput - baseline) / baseline * 100
+		testCase.ThroughputChangePercent = &change
+	}
+}
+
+// compareLineCounts sets ScannerSpeedup on each count_lines test case that
+// has a measured count_lines_scanner case with the same file size, buffer
+// size and fadvise hint.
+func compareLineCounts(testCases []TestCase) {
+	type caseKey struct {
+		fileSize   int64
+		bufferSize int
+		hint       string
+	}
+	scannerRates := make(map[caseKey]float64)
+	for _, testCase := range testCases {
+		if testCase.ReadPattern == "count_lines_scanner" && testCase.AvgLinesPerSecond > 0 {
+			scannerRates[caseKey{testCase.FileSize, testCase.BufferSize, testCase.Fadvise}] = testCase.AvgLinesPerSecond
+		}
 	}
-	sum := 0.0
-	for _, v := range values {
-		sum += v
+	for i := range testCases {
+		testCase := &testCases[i]
+		scannerRate, ok := scannerRates[caseKey{testCase.FileSize, testCase.BufferSize, testCase.Fadvise}]
+		if testCase.ReadPattern != "count_lines" || !ok || testCase.AvgLinesPerSecond == 0 {
+			continue
+		}
+		speedup := testCase.AvgLinesPerSecond / scannerRate
+		testCase.ScannerSpeedup = &speedup
+	}
+}
+
+func contains(slice []string, item string) bool {
+	for _, s := range slice {
+		if s == item {
+			return true
+		}
 	}
-	return sum / float64(len(values))
+	return false
 }
 
 func max(a, b float64) float64 {
@@ -409,41 +864,31 @@ func max(a, b float64) float64 {
 }
 
 func main() {
-	if len(os.Args) != 2 {
-		fmt.Fprintf(os.Stderr, "Usage: %s <input_file>\n", os.Args[0])
-		os.Exit(1)
-	}
-
-	inputFile := os.Args[1]
-
-	// Read and parse input configuration
-	configData, err := os.ReadFile(inputFile)
+	opts, err := cli.Parse(filepath.Base(os.Args[0]), os.Args[1:])
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error reading config file: %v\n", err)
-		os.Exit(1)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
 	}
 
 	var config Config
-	if err := json.Unmarshal(configData, &config); err != nil {
-		fmt.Fprintf(os.Stderr, "Error parsing config JSON: %v\n", err)
+	if err := opts.LoadConfig(&config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	// Initialize random seed
-	rand.Seed(time.Now().UnixNano())
+	ctx, cancel := cli.Deadline(config.Parameters.DeadlineSeconds)
+	defer cancel()
 
-	result, err := runLargeFileReadBenchmark(config.Parameters)
+	results, err := runLargeFileReadBenchmark(ctx, config.Parameters)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error running benchmark: %v\n", err)
 		os.Exit(1)
 	}
+	results.Name = config.Name
+	results.Tags = config.Parameters.Tags
 
-	// Output results as JSON
-	jsonOutput, err := json.MarshalIndent(result, "", "  ")
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error marshaling results: %v\n", err)
+	if err := opts.WriteResults(results); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
-
-	fmt.Println(string(jsonOutput))
 }
@@ -1,11 +1,48 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"math"
 	"math/rand"
+	"os"
+	"sort"
 	"time"
 )
 
+// Config is the optional config file. Element type "int" multiplies int64
+// matrices with entries in [0, max_element) instead of float64 ones. Verify
+// checks the multiplication against matrix identities after timing it.
+// Algorithm "sparse" compares CSR multiplication with the dense one on
+// float64 matrices of each of the given densities, and "winograd" compares
+// multiplyWinograd with the naive dense product. Precision lists the
+// float types the dense algorithm runs in, "float64" (the default) and
+// "float32", on the same inputs.
+type Config struct {
+	Parameters struct {
+		MatrixSize  int       `json:"matrix_size"`
+		ElementType string    `json:"element_type"`
+		MaxElement  int64     `json:"max_element"`
+		Verify      bool      `json:"verify"`
+		Algorithm   string    `json:"algorithm"`
+		Densities   []float64 `json:"densities"`
+		Precision   []string  `json:"precision"`
+	} `json:"parameters"`
+}
+
+// defaultDensities are the fractions of non-zero entries the sparse
+// algorithm is compared at when none are configured.
+var defaultDensities = []float64{0.001, 0.01, 0.05, 0.1, 0.25, 0.5, 0.75, 1}
+
+// verifyTolerance is the relative error allowed between float64 results
+// that are equal in exact arithmetic but summed in a different order.
+const verifyTolerance = 1e-9
+
+// float32Tolerance is the relative error allowed between a float32 product
+// and the float64 one. float32 keeps about 7 significant digits, and each
+// entry of an n x n product accumulates rounding error over n terms.
+const float32Tolerance = 1e-4
+
 func createMatrix(rows, cols int) [][]float64 {
 	matrix := make([][]float64, rows)
 	for i := range matrix {
@@ -38,9 +75,600 @@ func multiplyMatrices(a, b [][]float64) [][]float64 {
 	return result
 }
 
+func identityMatrix(n int) [][]float64 {
+	identity := make([][]float64, n)
+	for i := range identity {
+		identity[i] = make([]float64, n)
+		identity[i][i] = 1
+	}
+	return identity
+}
+
+func transpose(m [][]float64) [][]float64 {
+	result := make([][]float64, len(m[0]))
+	for j := range result {
+		result[j] = make([]float64, len(m))
+		for i := range m {
+			result[j][i] = m[i][j]
+		}
+	}
+	return result
+}
+
+func matricesClose(a, b [][]float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if len(a[i]) != len(b[i]) {
+			return false
+		}
+		for j := range a[i] {
+			diff := math.Abs(a[i][j] - b[i][j])
+			if diff > verifyTolerance*math.Max(1, math.Abs(b[i][j])) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// verifyMultiply checks multiplyMatrices on a and b against two identities:
+// A x I = A, and (A x B)^T = B^T x A^T given the product already computed.
+func verifyMultiply(a, b, product [][]float64) (identityOK, transposeOK bool) {
+	identityOK = matricesClose(multiplyMatrices(a, identityMatrix(len(a[0]))), a)
+	transposeOK = matricesClose(transpose(product), multiplyMatrices(transpose(b), transpose(a)))
+	return identityOK, transposeOK
+}
+
+func identityIntMatrix(n int) [][]int64 {
+	identity := make([][]int64, n)
+	for i := range identity {
+		identity[i] = make([]int64, n)
+		identity[i][i] = 1
+	}
+	return identity
+}
+
+func transposeInt(m [][]int64) [][]int64 {
+	result := make([][]int64, len(m[0]))
+	for j := range result {
+		result[j] = make([]int64, len(m))
+		for i := range m {
+			result[j][i] = m[i][j]
+		}
+	}
+	return result
+}
+
+func intMatricesEqual(a, b [][]int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if len(a[i]) != len(b[i]) {
+			return false
+		}
+		for j := range a[i] {
+			if a[i][j] != b[i][j] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// verifyIntMultiply is verifyMultiply for int64 matrices. Both identities
+// hold exactly, even when entries wrap around.
+func verifyIntMultiply(a, b, product [][]int64) (identityOK, transposeOK bool) {
+	identityOK = intMatricesEqual(multiplyIntMatrices(a, identityIntMatrix(len(a[0]))), a)
+	transposeOK = intMatricesEqual(transposeInt(product), multiplyIntMatrices(transposeInt(b), transposeInt(a)))
+	return identityOK, transposeOK
+}
+
+// reportVerification prints the outcome of the identity checks.
+func reportVerification(identityOK, transposeOK bool) {
+	fmt.Printf("Identity check (A x I = A): %v\n", identityOK)
+	fmt.Printf("Transpose check ((A x B)^T = B^T x A^T): %v\n", transposeOK)
+	fmt.Printf("Correct: %v\n", identityOK && transposeOK)
+}
+
+func createIntMatrix(rows, cols int, maxElement int64) [][]int64 {
+	matrix := make([][]int64, rows)
+	for i := range matrix {
+		matrix[i] = make([]int64, cols)
+		for j := range matrix[i] {
+			matrix[i][j] = rand.Int63n(maxElement)
+		}
+	}
+	return matrix
+}
+
+func multiplyIntMatrices(a, b [][]int64) [][]int64 {
+	rowsA := len(a)
+	colsA := len(a[0])
+	colsB := len(b[0])
+	
+	result := make([][]int64, rowsA)
+	for i := range result {
+		result[i] = make([]int64, colsB)
+	}
+	
+	for i := 0; i < rowsA; i++ {
+		for j := 0; j < colsB; j++ {
+			for k := 0; k < colsA; k++ {
+				result[i][j] += a[i][k] * b[k][j]
+			}
+		}
+	}
+	
+	return result
+}
+
+// intOverflowPossible reports whether an entry of the product of two n x n
+// matrices with entries below maxElement can exceed math.MaxInt64: the
+// largest possible entry is n * (maxElement-1)^2.
+func intOverflowPossible(n int, maxElement int64) bool {
+	largest := maxElement - 1
+	if largest <= 0 {
+		return false
+	}
+	if largest > math.MaxInt64/largest {
+		return true
+	}
+	return largest*largest > math.MaxInt64/int64(n)
+}
+
+func runIntMultiply(size int, maxElement int64, verify bool) {
+	fmt.Printf("Multiplying two %dx%d int64 matrices (entries below %d)...\n", size, size, maxElement)
+	if intOverflowPossible(size, maxElement) {
+		fmt.Println("Overflow risk: entries may exceed int64 and wrap around")
+	} else {
+		fmt.Println("Overflow risk: none")
+	}
+	
+	// Create matrices
+	createStart := time.Now()
+	matrixA := createIntMatrix(size, size, maxElement)
+	matrixB := createIntMatrix(size, size, maxElement)
+	createTime := time.Since(createStart)
+	
+	// Multiply matrices
+	multiplyStart := time.Now()
+	result := multiplyIntMatrices(matrixA, matrixB)
+	multiplyTime := time.Since(multiplyStart)
+	
+	totalTime := createTime + multiplyTime
+	
+	fmt.Printf("Result: %dx%d matrix\n", len(result), len(result[0]))
+	fmt.Printf("Sample result[0][0]: %d\n", result[0][0])
+	if verify {
+		reportVerification(verifyIntMultiply(matrixA, matrixB, result))
+	}
+	fmt.Println("Timing:")
+	fmt.Printf("  Matrix creation: %.6f seconds\n", createTime.Seconds())
+	fmt.Printf("  Matrix multiplication: %.6f seconds\n", multiplyTime.Seconds())
+	fmt.Printf("  Total time: %.6f seconds\n", totalTime.Seconds())
+}
+
+func toFloat32Matrix(m [][]float64) [][]float32 {
+	result := make([][]float32, len(m))
+	for i := range m {
+		result[i] = make([]float32, len(m[i]))
+		for j, value := range m[i] {
+			result[i][j] = float32(value)
+		}
+	}
+	return result
+}
+
+func multiplyFloat32Matrices(a, b [][]float32) [][]float32 {
+	rowsA := len(a)
+	colsA := len(a[0])
+	colsB := len(b[0])
+	
+	result := make([][]float32, rowsA)
+	for i := range result {
+		result[i] = make([]float32, colsB)
+	}
+	
+	for i := 0; i < rowsA; i++ {
+		for j := 0; j < colsB; j++ {
+			for k := 0; k < colsA; k++ {
+				result[i][j] += a[i][k] * b[k][j]
+			}
+		}
+	}
+	
+	return result
+}
+
+// float32Agrees reports whether every entry of the float32 product is within
+// float32Tolerance of the float64 product, and the largest relative error.
+func float32Agrees(single [][]float32, double [][]float64) (bool, float64) {
+	maxError := 0.0
+	for i := range double {
+		for j := range double[i] {
+			relative := math.Abs(float64(single[i][j])-double[i][j]) / math.Max(1, math.Abs(double[i][j]))
+			maxError = math.Max(maxError, relative)
+		}
+	}
+	return maxError <= float32Tolerance, maxError
+}
+
+// runPrecisions multiplies the same random matrices in each precision. The
+// float32 inputs are the float64 ones rounded, so the products differ only
+// by float32 rounding; memory is that of the A, B and result matrices.
+func runPrecisions(size int, precisions []string) {
+	fmt.Printf("Multiplying two %dx%d matrices in %d precision(s)...\n", size, size, len(precisions))
+	
+	matrixA := createMatrix(size, size)
+	matrixB := createMatrix(size, size)
+	
+	var double [][]float64
+	var single [][]float32
+	times := make(map[string]time.Duration, len(precisions))
+	for _, precision := range precisions {
+		elementSize := 8
+		var multiplyTime time.Duration
+		if precision == "float32" {
+			a32, b32 := toFloat32Matrix(matrixA), toFloat32Matrix(matrixB)
+			start := time.Now()
+			single = multiplyFloat32Matrices(a32, b32)
+			multiplyTime = time.Since(start)
+			elementSize = 4
+		} else {
+			start := time.Now()
+			double = multiplyMatrices(matrixA, matrixB)
+			multiplyTime = time.Since(start)
+		}
+		times[precision] = multiplyTime
+		
+		fmt.Printf("%s:\n", precision)
+		fmt.Printf("  Matrix multiplication: %.6f seconds\n", multiplyTime.Seconds())
+		fmt.Printf("  Matrix memory: %d bytes\n", 3*size*size*elementSize)
+	}
+	
+	if single != nil && double != nil {
+		agrees, maxError := float32Agrees(single, double)
+		fmt.Printf("float32 speedup over float64: %.2fx\n", times["float64"].Seconds()/times["float32"].Seconds())
+		fmt.Printf("Largest float32 relative error: %.3g\n", maxError)
+		fmt.Printf("float32 agrees with float64 (within %g): %v\n", float32Tolerance, agrees)
+		if !agrees {
+			os.Exit(1)
+		}
+	}
+}
+
+// csrMatrix is a matrix in compressed sparse row form: the non-zero
+// entries of row i are values[rowStart[i]:rowStart[i+1]], in columns
+// columns[rowStart[i]:rowStart[i+1]].
+type csrMatrix struct {
+	rows, cols int
+	rowStart   []int
+	columns    []int
+	values     []float64
+}
+
+// createSparseMatrix returns a dense matrix in which each entry is non-zero
+// with probability density.
+func createSparseMatrix(rows, cols int, density float64) [][]float64 {
+	matrix := make([][]float64, rows)
+	for i := range matrix {
+		matrix[i] = make([]float64, cols)
+		for j := range matrix[i] {
+			if rand.Float64() < density {
+				matrix[i][j] = rand.Float64()*100 + 1
+			}
+		}
+	}
+	return matrix
+}
+
+func toCSR(m [][]float64) csrMatrix {
+	csr := csrMatrix{rows: len(m), cols: len(m[0]), rowStart: make([]int, len(m)+1)}
+	for i, row := range m {
+		for j, value := range row {
+			if value != 0 {
+				csr.columns = append(csr.columns, j)
+				csr.values = append(csr.values, value)
+			}
+		}
+		csr.rowStart[i+1] = len(csr.values)
+	}
+	return csr
+}
+
+func (m csrMatrix) toDense() [][]float64 {
+	dense := make([][]float64, m.rows)
+	for i := range dense {
+		dense[i] = make([]float64, m.cols)
+		for k := m.rowStart[i]; k < m.rowStart[i+1]; k++ {
+			dense[i][m.columns[k]] = m.values[k]
+		}
+	}
+	return dense
+}
+
+// multiplyCSR multiplies two CSR matrices row by row (Gustavson's
+// algorithm): each non-zero a[i][k] scales row k of b into a dense
+// accumulator for row i, so the work is proportional to the number of
+// non-zero products rather than n^3.
+func multiplyCSR(a, b csrMatrix) csrMatrix {
+	result := csrMatrix{rows: a.rows, cols: b.cols, rowStart: make([]int, a.rows+1)}
+	accumulator := make([]float64, b.cols)
+	// occupied[j] == i+1 marks column j as written while computing row i
+	occupied := make([]int, b.cols)
+	var touched []int
+	
+	for i := 0; i < a.rows; i++ {
+		touched = touched[:0]
+		for ka := a.rowStart[i]; ka < a.rowStart[i+1]; ka++ {
+			k, scale := a.columns[ka], a.values[ka]
+			for kb := b.rowStart[k]; kb < b.rowStart[k+1]; kb++ {
+				j := b.columns[kb]
+				if occupied[j] != i+1 {
+					occupied[j] = i + 1
+					accumulator[j] = 0
+					touched = append(touched, j)
+				}
+				accumulator[j] += scale * b.values[kb]
+			}
+		}
+		sort.Ints(touched)
+		for _, j := range touched {
+			result.columns = append(result.columns, j)
+			result.values = append(result.values, accumulator[j])
+		}
+		result.rowStart[i+1] = len(result.values)
+	}
+	return result
+}
+
+// runSparseMultiply times dense and CSR multiplication of the same random
+// matrices at each density. Only the multiplications are timed; the
+// inputs are converted to CSR beforehand, as sparse data would be stored.
+func runSparseMultiply(size int, densities []float64) {
+	fmt.Printf("Comparing sparse (CSR) and dense multiplication of %dx%d matrices...\n", size, size)
+	crossover := -1.0
+	allCorrect := true
+	for _, density := range densities {
+		a := createSparseMatrix(size, size, density)
+		b := createSparseMatrix(size, size, density)
+		sparseA, sparseB := toCSR(a), toCSR(b)
+		
+		denseStart := time.Now()
+		denseResult := multiplyMatrices(a, b)
+		denseTime := time.Since(denseStart)
+		
+		sparseStart := time.Now()
+		sparseResult := multiplyCSR(sparseA, sparseB)
+		sparseTime := time.Since(sparseStart)
+		
+		correct := matricesClose(sparseResult.toDense(), denseResult)
+		allCorrect = allCorrect && correct
+		speedup := denseTime.Seconds() / sparseTime.Seconds()
+		if speedup < 1 && (crossover < 0 || density < crossover) {
+			crossover = density
+		}
+		
+		fmt.Printf("Density %g (%d and %d non-zeros):\n", density, len(sparseA.values), len(sparseB.values))
+		fmt.Printf("  Dense multiplication: %.6f seconds\n", denseTime.Seconds())
+		fmt.Printf("  Sparse multiplication: %.6f seconds\n", sparseTime.Seconds())
+		fmt.Printf("  Sparse speedup: %.2fx\n", speedup)
+		fmt.Printf("  Matches dense result: %v\n", correct)
+	}
+	
+	if crossover < 0 {
+		fmt.Println("Crossover density: none (sparse was faster at every density tested)")
+	} else {
+		fmt.Printf("Crossover density: %g (lowest tested density where dense was faster)\n", crossover)
+	}
+	if !allCorrect {
+		os.Exit(1)
+	}
+}
+
+// multiplyWinograd multiplies a and b with Winograd's inner-product
+// algorithm. Each pair of terms a[i][k]*b[k][j] + a[i][k+1]*b[k+1][j] is
+// computed as (a[i][k] + b[k+1][j]) * (a[i][k+1] + b[k][j]) less the
+// cross terms a[i][k]*a[i][k+1] and b[k][j]*b[k+1][j]. Those depend on
+// only a row or a column, so they are summed once per row and column up
+// front, which roughly halves the multiplications of the n^3 loop at the
+// cost of more additions. An odd inner dimension leaves one term per
+// entry that is added the ordinary way.
+func multiplyWinograd(a, b [][]float64) [][]float64 {
+	rowsA := len(a)
+	colsA := len(a[0])
+	colsB := len(b[0])
+	half := colsA / 2
+	
+	rowFactors := make([]float64, rowsA)
+	for i := 0; i < rowsA; i++ {
+		for k := 0; k < half; k++ {
+			rowFactors[i] += a[i][2*k] * a[i][2*k+1]
+		}
+	}
+	colFactors := make([]float64, colsB)
+	for j := 0; j < colsB; j++ {
+		for k := 0; k < half; k++ {
+			colFactors[j] += b[2*k][j] * b[2*k+1][j]
+		}
+	}
+	
+	result := make([][]float64, rowsA)
+	for i := range result {
+		result[i] = make([]float64, colsB)
+		for j := 0; j < colsB; j++ {
+			sum := -rowFactors[i] - colFactors[j]
+			for k := 0; k < half; k++ {
+				sum += (a[i][2*k] + b[2*k+1][j]) * (a[i][2*k+1] + b[2*k][j])
+			}
+			if colsA%2 == 1 {
+				sum += a[i][colsA-1] * b[colsA-1][j]
+			}
+			result[i][j] = sum
+		}
+	}
+	return result
+}
+
+// opCounts is the number of scalar multiplications and additions (counting
+// subtractions) a matrix product performs.
+type opCounts struct {
+	multiplications int
+	additions       int
+}
+
+// naiveOpCounts returns the operations multiplyMatrices performs on an
+// m x n and an n x p matrix: one multiply-add per term.
+func naiveOpCounts(m, n, p int) opCounts {
+	return opCounts{m * n * p, m * n * p}
+}
+
+// winogradOpCounts returns the operations multiplyWinograd performs on an
+// m x n and an n x p matrix, counting each accumulation as an addition as
+// naiveOpCounts does.
+func winogradOpCounts(m, n, p int) opCounts {
+	half := n / 2
+	// Row and column factors: a multiply and an accumulate per pair
+	counts := opCounts{(m + p) * half, (m + p) * half}
+	// Per entry: two subtracted factors, then two sums, a product and an
+	// accumulate per pair
+	counts.multiplications += m * p * half
+	counts.additions += m * p * (2 + 3*half)
+	if n%2 == 1 {
+		counts.multiplications += m * p
+		counts.additions += m * p
+	}
+	return counts
+}
+
+// runWinograd times multiplyWinograd against multiplyMatrices on the same
+// random matrices and reports the operations each performs.
+func runWinograd(size int) {
+	fmt.Printf("Comparing Winograd and naive multiplication of %dx%d matrices...\n", size, size)
+	a := createMatrix(size, size)
+	b := createMatrix(size, size)
+	
+	naiveStart := time.Now()
+	naiveResult := multiplyMatrices(a, b)
+	naiveTime := time.Since(naiveStart)
+	
+	winogradStart := time.Now()
+	winogradResult := multiplyWinograd(a, b)
+	winogradTime := time.Since(winogradStart)
+	
+	correct := matricesClose(winogradResult, naiveResult)
+	naive := naiveOpCounts(size, size, size)
+	winograd := winogradOpCounts(size, size, size)
+	fmt.Printf("Naive multiplication: %.6f seconds (%d multiplications, %d additions)\n",
+		naiveTime.Seconds(), naive.multiplications, naive.additions)
+	fmt.Printf("Winograd multiplication: %.6f seconds (%d multiplications, %d additions)\n",
+		winogradTime.Seconds(), winograd.multiplications, winograd.additions)
+	fmt.Printf("Winograd speedup: %.2fx\n", naiveTime.Seconds()/winogradTime.Seconds())
+	fmt.Printf("Matches naive result: %v\n", correct)
+	if !correct {
+		os.Exit(1)
+	}
+}
+
 func main() {
 	rand.Seed(time.Now().UnixNano())
 	size := 200 // Matrix size (200x200)
+	elementType := "float"
+	maxElement := int64(100)
+	verify := false
+	algorithm := "dense"
+	densities := defaultDensities
+	precisions := []string{"float64"}
+	
+	if len(os.Args) > 1 {
+		var config Config
+		data, err := os.ReadFile(os.Args[1])
+		if err == nil {
+			err = json.Unmarshal(data, &config)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading config: %v\n", err)
+			os.Exit(1)
+		}
+		if config.Parameters.MatrixSize > 0 {
+			size = config.Parameters.MatrixSize
+		}
+		if config.Parameters.ElementType != "" {
+			elementType = config.Parameters.ElementType
+		}
+		if config.Parameters.MaxElement > 0 {
+			maxElement = config.Parameters.MaxElement
+		}
+		verify = config.Parameters.Verify
+		if config.Parameters.Algorithm != "" {
+			algorithm = config.Parameters.Algorithm
+		}
+		if len(config.Parameters.Densities) > 0 {
+			densities = config.Parameters.Densities
+		}
+		if len(config.Parameters.Precision) > 0 {
+			precisions = config.Parameters.Precision
+		}
+	}
+	
+	for _, precision := range precisions {
+		if precision != "float32" && precision != "float64" {
+			fmt.Fprintf(os.Stderr, "Unknown precision '%s' (expected float32 or float64)\n", precision)
+			os.Exit(1)
+		}
+	}
+	// float64 alone is the original dense benchmark; anything else compares
+	// precisions, which only applies to dense float multiplication
+	comparePrecisions := len(precisions) != 1 || precisions[0] != "float64"
+	if comparePrecisions && (algorithm != "dense" || elementType != "float") {
+		fmt.Fprintln(os.Stderr, "precision only applies to the dense algorithm with element_type float")
+		os.Exit(1)
+	}
+	
+	switch algorithm {
+	case "dense":
+	case "sparse":
+		if elementType != "float" {
+			fmt.Fprintln(os.Stderr, "The sparse algorithm only supports element_type float")
+			os.Exit(1)
+		}
+		for _, density := range densities {
+			if density <= 0 || density > 1 {
+				fmt.Fprintf(os.Stderr, "Density %g is out of range (expected 0 < density <= 1)\n", density)
+				os.Exit(1)
+			}
+		}
+		runSparseMultiply(size, densities)
+		return
+	case "winograd":
+		if elementType != "float" {
+			fmt.Fprintln(os.Stderr, "The winograd algorithm only supports element_type float")
+			os.Exit(1)
+		}
+		runWinograd(size)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown algorithm '%s' (expected dense, sparse or winograd)\n", algorithm)
+		os.Exit(1)
+	}
+	
+	switch elementType {
+	case "float":
+	case "int":
+		runIntMultiply(size, maxElement, verify)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown element_type '%s' (expected float or int)\n", elementType)
+		os.Exit(1)
+	}
+	
+	if comparePrecisions {
+		runPrecisions(size, precisions)
+		return
+	}
 	
 	fmt.Printf("Multiplying two %dx%d matrices...\n", size, size)
 	
@@ -63,6 +691,9 @@ func main() {
 	
 	fmt.Printf("Result: %dx%d matrix\n", resultRows, resultCols)
 	fmt.Printf("Sample result[0][0]: %.6f\n", result[0][0])
+	if verify {
+		reportVerification(verifyMultiply(matrixA, matrixB, result))
+	}
 	fmt.Println("Timing:")
 	fmt.Printf("  Matrix creation: %.6f seconds\n", createTime.Seconds())
 	fmt.Printf("  Matrix multiplication: %.6f seconds\n", multiplyTime.Seconds())
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestIntMultiply compares multiplyIntMatrices against products worked out
+// by hand.
+func TestIntMultiply(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     [][]int64
+		expected [][]int64
+	}{
+		{"2x3 by 3x2", [][]int64{{1, 2, 3}, {4, 5, 6}}, [][]int64{{7, 8}, {9, 10}, {11, 12}}, [][]int64{{58, 64}, {139, 154}}},
+		{"1x1", [][]int64{{-3}}, [][]int64{{7}}, [][]int64{{-21}}},
+		{"identity", [][]int64{{1, 0}, {0, 1}}, [][]int64{{5, -6}, {7, 8}}, [][]int64{{5, -6}, {7, 8}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := multiplyIntMatrices(tt.a, tt.b); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("multiplyIntMatrices = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestSparseMultiply compares multiplyCSR against products worked out by
+// hand, on matrices with empty rows and columns.
+func TestSparseMultiply(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     [][]float64
+		expected [][]float64
+	}{
+		{"empty rows and columns", [][]float64{{0, 2, 0}, {0, 0, 0}, {1, 0, 3}}, [][]float64{{4, 0}, {0, 0}, {0, 5}}, [][]float64{{0, 0}, {0, 0}, {4, 15}}},
+		{"all zero", [][]float64{{0, 0}, {0, 0}}, [][]float64{{1, 2}, {3, 4}}, [][]float64{{0, 0}, {0, 0}}},
+		{"dense", [][]float64{{1, 2}, {3, 4}}, [][]float64{{5, 6}, {7, 8}}, [][]float64{{19, 22}, {43, 50}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := multiplyCSR(toCSR(tt.a), toCSR(tt.b)).toDense(); !matricesClose(got, tt.expected) {
+				t.Errorf("multiplyCSR = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestWinograd compares multiplyWinograd with multiplyMatrices on small
+// matrices with odd and even inner dimensions, including 1x1.
+func TestWinograd(t *testing.T) {
+	for _, dims := range [][3]int{{1, 1, 1}, {2, 3, 4}, {3, 5, 2}, {4, 4, 4}, {7, 9, 5}, {6, 8, 3}} {
+		a := createMatrix(dims[0], dims[1])
+		b := createMatrix(dims[1], dims[2])
+		if !matricesClose(multiplyWinograd(a, b), multiplyMatrices(a, b)) {
+			t.Errorf("%dx%d by %dx%d: Winograd product differs from the naive one", dims[0], dims[1], dims[1], dims[2])
+		}
+	}
+}
@@ -1,18 +1,39 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"math"
 	"math/rand"
+	"os"
 	"time"
 )
 
-func calculatePiMonteCarlo(numSamples int) float64 {
+// Config is the optional config file; without one a time-based seed is used.
+// Method "bbp" extracts the hexadecimal digit of pi at DigitPosition (1 is
+// the first digit after the point) instead of estimating pi by Monte Carlo.
+type Config struct {
+	Parameters struct {
+		Seed          *int64 `json:"seed,omitempty"`
+		Method        string `json:"method"`
+		DigitPosition int    `json:"digit_position"`
+	} `json:"parameters"`
+}
+
+// knownHexDigits are the first hexadecimal digits of pi after the point,
+// for checking the BBP digit extraction.
+const knownHexDigits = "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89"
+
+// maxDigitPosition keeps the BBP moduli 8k+6 small enough that their
+// squares fit in an int64 during modular exponentiation.
+const maxDigitPosition = 100000000
+
+func calculatePiMonteCarlo(rng *rand.Rand, numSamples int) float64 {
 	insideCircle := 0
 	
 	for i := 0; i < numSamples; i++ {
-		x := rand.Float64()
-		y := rand.Float64()
+		x := rng.Float64()
+		y := rng.Float64()
 		
 		if x*x+y*y <= 1 {
 			insideCircle++
@@ -22,13 +43,116 @@ func calculatePiMonteCarlo(numSamples int) float64 {
 	return 4.0 * float64(insideCircle) / float64(numSamples)
 }
 
+// powMod returns 16^exponent mod modulus by binary exponentiation.
+func powMod(exponent, modulus int64) int64 {
+	if modulus == 1 {
+		return 0
+	}
+	result, base := int64(1), int64(16)%modulus
+	for exponent > 0 {
+		if exponent&1 == 1 {
+			result = result * base % modulus
+		}
+		base = base * base % modulus
+		exponent >>= 1
+	}
+	return result
+}
+
+// bbpSeries returns the fractional part of 16^n * sum over k of
+// 1/(16^k (8k+j)). Terms up to k = n are reduced mod 8k+j so they stay
+// small; the tail is summed until its terms no longer matter.
+func bbpSeries(j int, n int64) float64 {
+	sum := 0.0
+	for k := int64(0); k <= n; k++ {
+		denominator := 8*k + int64(j)
+		sum += float64(powMod(n-k, denominator)) / float64(denominator)
+		sum -= math.Floor(sum)
+	}
+	for k := n + 1; ; k++ {
+		term := math.Pow(16, float64(n-k)) / float64(8*k+int64(j))
+		if term < 1e-17 {
+			break
+		}
+		sum += term
+	}
+	return sum - math.Floor(sum)
+}
+
+// bbpHexDigit returns the hexadecimal digit of pi at position (1-based,
+// after the point) using the Bailey-Borwein-Plouffe formula
+// pi = sum 1/16^k (4/(8k+1) - 2/(8k+4) - 1/(8k+5) - 1/(8k+6)),
+// without computing any of the preceding digits.
+func bbpHexDigit(position int) int {
+	n := int64(position - 1)
+	x := 4*bbpSeries(1, n) - 2*bbpSeries(4, n) - bbpSeries(5, n) - bbpSeries(6, n)
+	x -= math.Floor(x)
+	return int(16 * x)
+}
+
+func runBBP(position int) {
+	fmt.Printf("Extracting hex digit %d of pi with the BBP formula...\n", position)
+	start := time.Now()
+	
+	digit := bbpHexDigit(position)
+	
+	duration := time.Since(start)
+	
+	fmt.Printf("Result: %X\n", digit)
+	if position <= len(knownHexDigits) {
+		fmt.Printf("Matches known digit: %v\n", fmt.Sprintf("%X", digit) == knownHexDigits[position-1:position])
+	}
+	fmt.Printf("Execution time: %.6f seconds\n", duration.Seconds())
+}
+
 func main() {
 	numSamples := 1000000
 	
-	fmt.Printf("Calculating pi with %d samples...\n", numSamples)
+	// Time-based seeds are kept to 53 bits so they can be passed back in
+	// parameters.seed to reproduce the estimate.
+	seed := time.Now().UnixNano() & (1<<53 - 1)
+	method := "monte_carlo"
+	digitPosition := 1
+	if len(os.Args) > 1 {
+		var config Config
+		data, err := os.ReadFile(os.Args[1])
+		if err == nil {
+			err = json.Unmarshal(data, &config)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading config: %v\n", err)
+			os.Exit(1)
+		}
+		if config.Parameters.Seed != nil {
+			seed = *config.Parameters.Seed
+		}
+		if config.Parameters.Method != "" {
+			method = config.Parameters.Method
+		}
+		if config.Parameters.DigitPosition != 0 {
+			digitPosition = config.Parameters.DigitPosition
+		}
+	}
+	
+	switch method {
+	case "monte_carlo":
+	case "bbp":
+		if digitPosition < 1 || digitPosition > maxDigitPosition {
+			fmt.Fprintf(os.Stderr, "digit_position must be between 1 and %d, got %d\n", maxDigitPosition, digitPosition)
+			os.Exit(1)
+		}
+		runBBP(digitPosition)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown method '%s' (expected monte_carlo or bbp)\n", method)
+		os.Exit(1)
+	}
+	rng := rand.New(rand.NewSource(seed))
+	
+	fmt.Printf("Calculating pi with %d samples (seed %d)...\n", numSamples, seed)
 	start := time.Now()
 	
-	piEstimate := calculatePiMonteCarlo(numSamples)
+	piEstimate := calculatePiMonteCarlo(rng, numSamples)
 	
 	duration := time.Since(start)
 	
@@ -0,0 +1,15 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestBBP compares bbpHexDigit against the known leading hex digits.
+func TestBBP(t *testing.T) {
+	for i := 0; i < len(knownHexDigits); i++ {
+		if got, want := fmt.Sprintf("%X", bbpHexDigit(i+1)), knownHexDigits[i:i+1]; got != want {
+			t.Errorf("bbpHexDigit(%d) = %s, want %s", i+1, got, want)
+		}
+	}
+}
@@ -1,14 +1,26 @@
 package main
 
 import (
+	"bytes"
 	"context"
-	"encoding/json"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"io"
 	"net"
+	"net/http"
 	"os"
+	"path/filepath"
 	"sort"
+	"strings"
 	"sync"
+	"syscall"
 	"time"
+
+	"benchmark_test/internal/cli"
+	"benchmark_test/internal/stats"
+
+	"golang.org/x/net/dns/dnsmessage"
 )
 
 type DnsResult struct {
@@ -16,7 +28,16 @@ type DnsResult struct {
 	Success        bool     `json:"success"`
 	ResponseTimeMs float64  `json:"response_time_ms"`
 	IPAddresses    []string `json:"ip_addresses"`
+	IPv4Count      int      `json:"ipv4_count"`
+	IPv6Count      int      `json:"ipv6_count"`
+	Hostnames      []string `json:"hostnames,omitempty"`
+	EmptyResult    bool     `json:"empty_result,omitempty"`
 	Error          *string  `json:"error,omitempty"`
+	ErrorType      string   `json:"error_type,omitempty"`
+
+	// TTLs holds the lowest TTL, in seconds, of each record type in the
+	// answers, e.g. {"A": 300, "CNAME": 3600}.
+	TTLs map[string]uint32 `json:"ttls,omitempty"`
 }
 
 type IterationResult struct {
@@ -29,11 +50,18 @@ type IterationResult struct {
 	DomainResults         []DnsResult `json:"domain_results"`
 }
 
+// TestCase averages cover successful lookups only, except AvgFailureTime:
+// failed lookups often end at the timeout or an NXDOMAIN answer and take
+// very different times, so they are averaged separately.
 type TestCase struct {
 	ResolutionMode    string            `json:"resolution_mode"`
+	Nameserver        string            `json:"nameserver"`
+	Network           string            `json:"network"`
 	DomainsCount      int               `json:"domains_count"`
 	Iterations        []IterationResult `json:"iterations"`
 	AvgResolutionTime float64           `json:"avg_resolution_time"`
+	AvgSuccessTime    float64           `json:"avg_success_time"`
+	AvgFailureTime    float64           `json:"avg_failure_time"`
 	FastestResolution float64           `json:"fastest_resolution"`
 	SlowestResolution float64           `json:"slowest_resolution"`
 	SuccessRate       float64           `json:"success_rate"`
@@ -42,21 +70,45 @@ type TestCase struct {
 }
 
 type Summary struct {
-	TotalDomains          int     `json:"total_domains"`
-	TotalIterations       int     `json:"total_iterations"`
-	SuccessfulResolutions int     `json:"successful_resolutions"`
-	FailedResolutions     int     `json:"failed_resolutions"`
-	AvgResolutionTime     float64 `json:"avg_resolution_time"`
-	FastestResolution     float64 `json:"fastest_resolution"`
-	SlowestResolution     float64 `json:"slowest_resolution"`
+	Nameservers           []string `json:"nameservers"`
+	TotalDomains          int      `json:"total_domains"`
+	TotalIterations       int      `json:"total_iterations"`
+	SuccessfulResolutions int      `json:"successful_resolutions"`
+	FailedResolutions     int      `json:"failed_resolutions"`
+	AvgResolutionTime     float64  `json:"avg_resolution_time"`
+	AvgSuccessTime        float64  `json:"avg_success_time"`
+	AvgFailureTime        float64  `json:"avg_failure_time"`
+	FastestResolution     float64  `json:"fastest_resolution"`
+	SlowestResolution     float64  `json:"slowest_resolution"`
+	WarmupIterations      int      `json:"warmup_iterations"`
+	TimedOut              bool     `json:"timed_out"`
+
+	// FailuresByType counts failed resolutions by classifyDNSError category.
+	FailuresByType map[string]int `json:"failures_by_type"`
+}
+
+type CacheTestResult struct {
+	Domain        string    `json:"domain"`
+	Nameserver    string    `json:"nameserver"`
+	ColdTimeMs    float64   `json:"cold_time_ms"`
+	WarmTimesMs   []float64 `json:"warm_times_ms"`
+	AvgWarmTimeMs float64   `json:"avg_warm_time_ms"`
+	SpeedupRatio  float64   `json:"speedup_ratio"`
+	FailedLookups int       `json:"failed_lookups"`
+	Error         *string   `json:"error,omitempty"`
 }
 
 type BenchmarkResult struct {
-	StartTime          int64      `json:"start_time"`
-	TestCases          []TestCase `json:"test_cases"`
-	Summary            Summary    `json:"summary"`
-	EndTime            int64      `json:"end_time"`
-	TotalExecutionTime float64    `json:"total_execution_time"`
+	SchemaVersion      string            `json:"schema_version"`
+	Environment        cli.Environment   `json:"environment"`
+	Name               string            `json:"name,omitempty"`
+	Tags               []string          `json:"tags,omitempty"`
+	StartTime          int64             `json:"start_time"`
+	TestCases          []TestCase        `json:"test_cases"`
+	CacheTests         []CacheTestResult `json:"cache_tests,omitempty"`
+	Summary            Summary           `json:"summary"`
+	EndTime            int64             `json:"end_time"`
+	TotalExecutionTime float64           `json:"total_execution_time"`
 }
 
 type Config struct {
@@ -66,7 +118,34 @@ type Config struct {
 		Iterations        int      `json:"iterations"`
 		TimeoutSeconds    int      `json:"timeout_seconds"`
 		ConcurrentWorkers int      `json:"concurrent_workers"`
+		Nameserver        string   `json:"nameserver"`
+		Nameservers       []string `json:"nameservers"`
+		Targets           []string `json:"targets"`
+		Network           string   `json:"network"`
+		CacheTest         bool     `json:"cache_test"`
+		RepeatCount       int      `json:"repeat_count"`
+		WarmupIterations  int      `json:"warmup_iterations"`
+		DeadlineSeconds   int      `json:"deadline_seconds"`
+		DohEndpoint       string   `json:"doh_endpoint"`
+		GOMAXPROCS        int      `json:"gomaxprocs"`
+		Tags              []string `json:"tags"`
 	} `json:"parameters"`
+	Name string `json:"name"`
+}
+
+// Validate rejects negative counts and durations. Zero leaves each at its
+// default (see runDnsBenchmark).
+func (c *Config) Validate() error {
+	p := c.Parameters
+	return cli.FirstError(
+		cli.NonNegative("iterations", p.Iterations),
+		cli.NonNegative("timeout_seconds", p.TimeoutSeconds),
+		cli.NonNegative("concurrent_workers", p.ConcurrentWorkers),
+		cli.NonNegative("repeat_count", p.RepeatCount),
+		cli.NonNegative("warmup_iterations", p.WarmupIterations),
+		cli.NonNegative("deadline_seconds", p.DeadlineSeconds),
+		cli.NonNegative("gomaxprocs", p.GOMAXPROCS),
+	)
 }
 
 // Simple DNS cache
@@ -75,15 +154,187 @@ var (
 	cacheMutex sync.RWMutex
 )
 
-func resolveDomainWithCache(domain string, timeoutSecs int) DnsResult {
-	// Check cache first
-	cacheMutex.RLock()
-	if cachedResult, exists := dnsCache[domain]; exists {
-		cacheMutex.RUnlock()
-		return cachedResult
+// systemNameserver labels results produced by the system default resolver.
+const systemNameserver = "system"
+
+// Failure categories reported as DnsResult.ErrorType.
+const (
+	failureTimeout            = "timeout"
+	failureNXDomain           = "nxdomain"
+	failureServerFailure      = "server_failure"
+	failureNetworkUnreachable = "network_unreachable"
+	failureOther              = "other"
+)
+
+// classifyDNSError sorts a failed lookup into one of the failure categories.
+// The resolver reports most failures as a *net.DNSError whose flags say what
+// happened; a refused or unroutable connection to the nameserver only shows
+// up in the wrapped error or, on older Go versions, its message.
+func classifyDNSError(err error) string {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		switch {
+		case dnsErr.IsNotFound:
+			return failureNXDomain
+		case dnsErr.IsTimeout:
+			return failureTimeout
+		case strings.HasPrefix(dnsErr.Err, "server misbehaving"):
+			return failureServerFailure
+		}
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return failureTimeout
+	}
+	if errors.Is(err, syscall.ENETUNREACH) || errors.Is(err, syscall.EHOSTUNREACH) || errors.Is(err, syscall.ECONNREFUSED) {
+		return failureNetworkUnreachable
+	}
+	message := err.Error()
+	for _, unreachable := range []string{"network is unreachable", "no route to host", "connection refused"} {
+		if strings.Contains(message, unreachable) {
+			return failureNetworkUnreachable
+		}
+	}
+	return failureOther
+}
+
+// normalizeNameserver returns nameserver as host:port, defaulting the port
+// to 53 when only a host is given.
+func normalizeNameserver(nameserver string) string {
+	if nameserver == "" || nameserver == systemNameserver {
+		return systemNameserver
+	}
+	if _, _, err := net.SplitHostPort(nameserver); err != nil {
+		return net.JoinHostPort(nameserver, "53")
+	}
+	return nameserver
+}
+
+// newResolver builds a resolver that queries nameserver, or the system
+// default DNS server when nameserver is systemNameserver. The responses it
+// reads are passed to ttls, since net.Resolver itself does not return TTLs.
+func newResolver(nameserver string, timeoutSecs int, ttls *ttlRecorder) *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			d := net.Dialer{
+				Timeout: time.Duration(timeoutSecs) * time.Second,
+			}
+			if nameserver != systemNameserver {
+				address = nameserver
+			}
+			conn, err := d.DialContext(ctx, network, address)
+			if err != nil {
+				return nil, err
+			}
+			return ttls.wrap(conn), nil
+		},
+	}
+}
+
+// ttlRecorder collects the lowest TTL per record type from the answer
+// sections of raw DNS responses. It is safe for concurrent use, as the
+// resolver sends its A and AAAA queries in parallel.
+type ttlRecorder struct {
+	mu   sync.Mutex
+	ttls map[string]uint32
+}
+
+func newTTLRecorder() *ttlRecorder {
+	return &ttlRecorder{ttls: make(map[string]uint32)}
+}
+
+// record parses msg and notes the TTL of every answer record. Anything that
+// does not parse is ignored; the resolver reports its own errors.
+func (r *ttlRecorder) record(msg []byte) {
+	var parser dnsmessage.Parser
+	if _, err := parser.Start(msg); err != nil {
+		return
+	}
+	if err := parser.SkipAllQuestions(); err != nil {
+		return
 	}
-	cacheMutex.RUnlock()
 
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for {
+		header, err := parser.AnswerHeader()
+		if err != nil {
+			return
+		}
+		if err := parser.SkipAnswer(); err != nil {
+			return
+		}
+		recordType := strings.TrimPrefix(header.Type.String(), "Type")
+		if current, ok := r.ttls[recordType]; !ok || header.TTL < current {
+			r.ttls[recordType] = header.TTL
+		}
+	}
+}
+
+// result returns the recorded TTLs, or nil if no answers were seen.
+func (r *ttlRecorder) result() map[string]uint32 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.ttls) == 0 {
+		return nil
+	}
+	ttls := make(map[string]uint32, len(r.ttls))
+	for recordType, ttl := range r.ttls {
+		ttls[recordType] = ttl
+	}
+	return ttls
+}
+
+// wrap returns conn with its reads copied to the recorder. The resolver
+// picks UDP or TCP framing by whether the conn is a net.PacketConn, so a UDP
+// conn must stay one.
+func (r *ttlRecorder) wrap(conn net.Conn) net.Conn {
+	if udp, ok := conn.(*net.UDPConn); ok {
+		return &ttlPacketConn{UDPConn: udp, recorder: r}
+	}
+	return &ttlStreamConn{Conn: conn, recorder: r}
+}
+
+// ttlPacketConn records each datagram read, one DNS message per read.
+type ttlPacketConn struct {
+	*net.UDPConn
+	recorder *ttlRecorder
+}
+
+func (c *ttlPacketConn) Read(b []byte) (int, error) {
+	n, err := c.UDPConn.Read(b)
+	if n > 0 {
+		c.recorder.record(b[:n])
+	}
+	return n, err
+}
+
+// ttlStreamConn reassembles the two-byte length prefixed messages of DNS
+// over TCP before recording them.
+type ttlStreamConn struct {
+	net.Conn
+	recorder *ttlRecorder
+	pending  []byte
+}
+
+func (c *ttlStreamConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.pending = append(c.pending, b[:n]...)
+	for len(c.pending) >= 2 {
+		length := int(binary.BigEndian.Uint16(c.pending))
+		if len(c.pending) < 2+length {
+			break
+		}
+		c.recorder.record(c.pending[2 : 2+length])
+		c.pending = c.pending[2+length:]
+	}
+	return n, err
+}
+
+// lookupDomain performs a single DNS lookup, bypassing the in-process cache.
+func lookupDomain(ctx context.Context, domain, nameserver, network string, timeoutSecs int) DnsResult {
 	start := time.Now()
 	result := DnsResult{
 		Domain:         domain,
@@ -92,52 +343,306 @@ func resolveDomainWithCache(domain string, timeoutSecs int) DnsResult {
 		IPAddresses:    []string{},
 	}
 
-	// Create context with timeout for DNS resolution
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeoutSecs)*time.Second)
+	// Each lookup gets its own timeout within the run's deadline
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(timeoutSecs)*time.Second)
 	defer cancel()
 
-	// Set timeout for DNS resolution
-	resolver := &net.Resolver{
-		PreferGo: true,
-		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
-			d := net.Dialer{
-				Timeout: time.Duration(timeoutSecs) * time.Second,
-			}
-			return d.DialContext(ctx, network, address)
+	ttls := newTTLRecorder()
+	resolver := newResolver(nameserver, timeoutSecs, ttls)
+
+	ips, err := resolver.LookupIP(ctx, network, domain)
+	elapsed := time.Since(start)
+	result.ResponseTimeMs = float64(elapsed.Nanoseconds()) / 1e6
+
+	if err != nil {
+		errMsg := fmt.Sprintf("DNS resolution failed: %v", err)
+		result.Error = &errMsg
+		result.ErrorType = classifyDNSError(err)
+	} else {
+		result.Success = true
+		addIPAddresses(&result, ips)
+		result.TTLs = ttls.result()
+	}
+
+	return result
+}
+
+func addIPAddresses(result *DnsResult, ips []net.IP) {
+	for _, ip := range ips {
+		result.IPAddresses = append(result.IPAddresses, ip.String())
+		if ip.To4() != nil {
+			result.IPv4Count++
+		} else {
+			result.IPv6Count++
+		}
+	}
+}
+
+// defaultDohEndpoint is queried by the "doh" mode unless
+// parameters.doh_endpoint names another server.
+const defaultDohEndpoint = "https://cloudflare-dns.com/dns-query"
+
+// dohMediaType is the RFC 8484 content type for DNS wireformat messages.
+const dohMediaType = "application/dns-message"
+
+// dohClient is shared by every DoH query so that, like a real stub resolver,
+// lookups reuse one HTTPS connection instead of paying for a TLS handshake
+// each time. Per-lookup timeouts come from the request context.
+var dohClient = &http.Client{}
+
+// dohQuery sends one question for domain to endpoint as an RFC 8484 POST
+// and returns the addresses in the answer. Error responses are reported as
+// *net.DNSError, matching what net.Resolver returns for the same answers.
+func dohQuery(ctx context.Context, endpoint, domain string, qtype dnsmessage.Type, ttls *ttlRecorder) ([]net.IP, error) {
+	fqdn := domain
+	if !strings.HasSuffix(fqdn, ".") {
+		fqdn += "."
+	}
+	name, err := dnsmessage.NewName(fqdn)
+	if err != nil {
+		return nil, err
+	}
+
+	// RFC 8484 asks for ID 0 so identical queries are cacheable over HTTP.
+	query := dnsmessage.Message{
+		Header: dnsmessage.Header{RecursionDesired: true},
+		Questions: []dnsmessage.Question{
+			{Name: name, Type: qtype, Class: dnsmessage.ClassINET},
 		},
 	}
+	packed, err := query.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(packed))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", dohMediaType)
+	req.Header.Set("Accept", dohMediaType)
+
+	resp, err := dohClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DoH server returned %s", resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 65535))
+	if err != nil {
+		return nil, err
+	}
+
+	var reply dnsmessage.Message
+	if err := reply.Unpack(body); err != nil {
+		return nil, fmt.Errorf("invalid DoH response: %v", err)
+	}
+	ttls.record(body)
+
+	switch reply.RCode {
+	case dnsmessage.RCodeSuccess:
+	case dnsmessage.RCodeNameError:
+		return nil, &net.DNSError{Err: "no such host", Name: domain, Server: endpoint, IsNotFound: true}
+	default:
+		return nil, &net.DNSError{Err: "server misbehaving: " + reply.RCode.String(), Name: domain, Server: endpoint}
+	}
+
+	var ips []net.IP
+	for _, answer := range reply.Answers {
+		switch body := answer.Body.(type) {
+		case *dnsmessage.AResource:
+			ips = append(ips, net.IP(body.A[:]))
+		case *dnsmessage.AAAAResource:
+			ips = append(ips, net.IP(body.AAAA[:]))
+		}
+	}
+	return ips, nil
+}
+
+// dohLookup resolves domain over DNS-over-HTTPS. Like net.Resolver it asks
+// for A and AAAA records in parallel for the "ip" network and succeeds if
+// either returns addresses.
+func dohLookup(ctx context.Context, domain, endpoint, network string, timeoutSecs int) DnsResult {
+	start := time.Now()
+	result := DnsResult{
+		Domain:         domain,
+		Success:        false,
+		ResponseTimeMs: 0.0,
+		IPAddresses:    []string{},
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(timeoutSecs)*time.Second)
+	defer cancel()
+
+	var qtypes []dnsmessage.Type
+	switch network {
+	case "ip4":
+		qtypes = []dnsmessage.Type{dnsmessage.TypeA}
+	case "ip6":
+		qtypes = []dnsmessage.Type{dnsmessage.TypeAAAA}
+	default:
+		qtypes = []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA}
+	}
+
+	ttls := newTTLRecorder()
+	answers := make([][]net.IP, len(qtypes))
+	errs := make([]error, len(qtypes))
+	var wg sync.WaitGroup
+	for i, qtype := range qtypes {
+		wg.Add(1)
+		go func(i int, qtype dnsmessage.Type) {
+			defer wg.Done()
+			answers[i], errs[i] = dohQuery(ctx, endpoint, domain, qtype, ttls)
+		}(i, qtype)
+	}
+	wg.Wait()
 
-	ips, err := resolver.LookupIPAddr(ctx, domain)
 	elapsed := time.Since(start)
 	result.ResponseTimeMs = float64(elapsed.Nanoseconds()) / 1e6
 
-	if err != nil {
+	var ips []net.IP
+	var err error
+	for i := range qtypes {
+		ips = append(ips, answers[i]...)
+		if err == nil {
+			err = errs[i]
+		}
+	}
+	if len(ips) == 0 && err == nil {
+		err = &net.DNSError{Err: "no such host", Name: domain, Server: endpoint, IsNotFound: true}
+	}
+
+	if len(ips) == 0 {
 		errMsg := fmt.Sprintf("DNS resolution failed: %v", err)
 		result.Error = &errMsg
+		result.ErrorType = classifyDNSError(err)
+	} else {
+		result.Success = true
+		addIPAddresses(&result, ips)
+		result.TTLs = ttls.result()
+	}
+
+	return result
+}
+
+// resolveDomainsDoH resolves domains one at a time over DoH. Results are not
+// cached, so every iteration measures a real round trip to the endpoint.
+func resolveDomainsDoH(ctx context.Context, domains []string, endpoint, network string, timeoutSecs int) []DnsResult {
+	var results []DnsResult
+
+	for _, domain := range domains {
+		if ctx.Err() != nil {
+			break
+		}
+		result := dohLookup(ctx, domain, endpoint, network, timeoutSecs)
+		status := "✗"
+		if result.Success {
+			status = "✓"
+		}
+		fmt.Fprintf(os.Stderr, "  Resolved %s over DoH: %s (%.2fms)\n",
+			domain, status, result.ResponseTimeMs)
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// reverseLookup resolves the PTR records for address. An address without a
+// PTR record is a successful lookup with an empty result, not a failure.
+func reverseLookup(ctx context.Context, address, nameserver string, timeoutSecs int) DnsResult {
+	start := time.Now()
+	result := DnsResult{
+		Domain:         address,
+		Success:        false,
+		ResponseTimeMs: 0.0,
+		IPAddresses:    []string{},
+		Hostnames:      []string{},
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(timeoutSecs)*time.Second)
+	defer cancel()
+
+	ttls := newTTLRecorder()
+	resolver := newResolver(nameserver, timeoutSecs, ttls)
+
+	names, err := resolver.LookupAddr(ctx, address)
+	elapsed := time.Since(start)
+	result.ResponseTimeMs = float64(elapsed.Nanoseconds()) / 1e6
+
+	var dnsErr *net.DNSError
+	if err != nil && errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		result.Success = true
+		result.EmptyResult = true
+	} else if err != nil {
+		errMsg := fmt.Sprintf("Reverse DNS lookup failed: %v", err)
+		result.Error = &errMsg
+		result.ErrorType = classifyDNSError(err)
 	} else {
 		result.Success = true
-		for _, ip := range ips {
-			result.IPAddresses = append(result.IPAddresses, ip.IP.String())
+		result.Hostnames = append(result.Hostnames, names...)
+		result.EmptyResult = len(names) == 0
+		result.TTLs = ttls.result()
+	}
+
+	return result
+}
+
+func resolveAddressesReverse(ctx context.Context, addresses []string, nameserver string, timeoutSecs int) []DnsResult {
+	var results []DnsResult
+
+	for _, address := range addresses {
+		if ctx.Err() != nil {
+			break
 		}
+		result := reverseLookup(ctx, address, nameserver, timeoutSecs)
+		status := "✗"
+		if result.Success {
+			status = "✓"
+		}
+		fmt.Fprintf(os.Stderr, "  Reverse resolved %s: %s %v (%.2fms)\n",
+			address, status, result.Hostnames, result.ResponseTimeMs)
+		results = append(results, result)
 	}
 
+	return results
+}
+
+func resolveDomainWithCache(ctx context.Context, domain, nameserver, network string, timeoutSecs int) DnsResult {
+	cacheKey := nameserver + "|" + network + "|" + domain
+
+	// Check cache first
+	cacheMutex.RLock()
+	if cachedResult, exists := dnsCache[cacheKey]; exists {
+		cacheMutex.RUnlock()
+		return cachedResult
+	}
+	cacheMutex.RUnlock()
+
+	result := lookupDomain(ctx, domain, nameserver, network, timeoutSecs)
+
 	// Cache the result
 	cacheMutex.Lock()
-	dnsCache[domain] = result
+	dnsCache[cacheKey] = result
 	cacheMutex.Unlock()
 
 	return result
 }
 
-func resolveDomain(domain string, timeoutSecs int) DnsResult {
-	return resolveDomainWithCache(domain, timeoutSecs)
+func resolveDomain(ctx context.Context, domain, nameserver, network string, timeoutSecs int) DnsResult {
+	return resolveDomainWithCache(ctx, domain, nameserver, network, timeoutSecs)
 }
 
-func resolveDomainsSequential(domains []string, timeoutSecs int) []DnsResult {
+func resolveDomainsSequential(ctx context.Context, domains []string, nameserver, network string, timeoutSecs int) []DnsResult {
 	var results []DnsResult
 
 	for _, domain := range domains {
-		result := resolveDomain(domain, timeoutSecs)
+		if ctx.Err() != nil {
+			break
+		}
+		result := resolveDomain(ctx, domain, nameserver, network, timeoutSecs)
 		status := "✗"
 		if result.Success {
 			status = "✓"
@@ -150,18 +655,25 @@ func resolveDomainsSequential(domains []string, timeoutSecs int) []DnsResult {
 	return results
 }
 
-func resolveDomainsConcurrent(domains []string, maxWorkers, timeoutSecs int) []DnsResult {
+func resolveDomainsConcurrent(ctx context.Context, domains []string, nameserver, network string, maxWorkers, timeoutSecs int) []DnsResult {
 	var wg sync.WaitGroup
 	resultsChan := make(chan DnsResult, len(domains))
 	semaphore := make(chan struct{}, maxWorkers)
 
 	for _, domain := range domains {
+		select {
+		case semaphore <- struct{}{}: // acquire
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
 		wg.Add(1)
 		go func(d string) {
 			defer wg.Done()
-			semaphore <- struct{}{} // acquire
 
-			result := resolveDomain(d, timeoutSecs)
+			result := resolveDomain(ctx, d, nameserver, network, timeoutSecs)
 			status := "✗"
 			if result.Success {
 				status = "✓"
@@ -190,9 +702,80 @@ func resolveDomainsConcurrent(domains []string, maxWorkers, timeoutSecs int) []D
 	return results
 }
 
-func runDnsBenchmark(config Config) BenchmarkResult {
+// warmupNameserver resolves every domain (and reverse target, when that mode
+// is selected) once, bypassing the local cache, so that measured iterations
+// do not include the nameserver's own cold-cache recursion.
+func warmupNameserver(ctx context.Context, domains, targets, modes []string, nameserver, network string, timeoutSecs int) {
+	for _, domain := range domains {
+		lookupDomain(ctx, domain, nameserver, network, timeoutSecs)
+	}
+	for _, mode := range modes {
+		if mode == "reverse" {
+			resolveAddressesReverse(ctx, targets, nameserver, timeoutSecs)
+			break
+		}
+	}
+}
+
+// warmupDoh opens the HTTPS connection to endpoint and lets it cache the
+// domains, so the first measured DoH query does not include the handshake.
+func warmupDoh(ctx context.Context, domains []string, endpoint, network string, timeoutSecs int) {
+	for _, domain := range domains {
+		dohLookup(ctx, domain, endpoint, network, timeoutSecs)
+	}
+}
+
+// runCacheTest resolves domain repeatCount times against nameserver without
+// the in-process cache, separating the first (cold) lookup from the
+// subsequent (warm) ones to expose resolver or OS level caching.
+func runCacheTest(ctx context.Context, domain, nameserver, network string, repeatCount, timeoutSecs int) CacheTestResult {
+	result := CacheTestResult{
+		Domain:      domain,
+		Nameserver:  nameserver,
+		WarmTimesMs: []float64{},
+	}
+
+	cold := lookupDomain(ctx, domain, nameserver, network, timeoutSecs)
+	if !cold.Success {
+		result.FailedLookups++
+		result.Error = cold.Error
+		return result
+	}
+	result.ColdTimeMs = cold.ResponseTimeMs
+
+	for i := 1; i < repeatCount && ctx.Err() == nil; i++ {
+		warm := lookupDomain(ctx, domain, nameserver, network, timeoutSecs)
+		if !warm.Success {
+			result.FailedLookups++
+			continue
+		}
+		result.WarmTimesMs = append(result.WarmTimesMs, warm.ResponseTimeMs)
+	}
+
+	if len(result.WarmTimesMs) > 0 {
+		sum := 0.0
+		for _, t := range result.WarmTimesMs {
+			sum += t
+		}
+		result.AvgWarmTimeMs = sum / float64(len(result.WarmTimesMs))
+		if result.AvgWarmTimeMs > 0 {
+			result.SpeedupRatio = result.ColdTimeMs / result.AvgWarmTimeMs
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "  Cache test %s: cold %.2fms, warm avg %.2fms (%.2fx)\n",
+		domain, result.ColdTimeMs, result.AvgWarmTimeMs, result.SpeedupRatio)
+
+	return result
+}
+
+func runDnsBenchmark(ctx context.Context, config Config) BenchmarkResult {
 	params := config.Parameters
 
+	// The concurrent mode's worker goroutines share GOMAXPROCS threads;
+	// environment.gomaxprocs records the value used.
+	defer cli.SetGOMAXPROCS(params.GOMAXPROCS)()
+
 	// Set defaults
 	if len(params.Domains) == 0 {
 		params.Domains = []string{"google.com", "github.com", "stackoverflow.com"}
@@ -209,119 +792,208 @@ func runDnsBenchmark(config Config) BenchmarkResult {
 	if params.ConcurrentWorkers == 0 {
 		params.ConcurrentWorkers = 5
 	}
+	// "ip4" or "ip6" restricts forward lookups to A or AAAA records so each
+	// address family's latency can be measured on its own.
+	switch params.Network {
+	case "ip", "ip4", "ip6":
+	case "":
+		params.Network = "ip"
+	default:
+		fmt.Fprintf(os.Stderr, "Warning: Unknown network '%s', using ip\n", params.Network)
+		params.Network = "ip"
+	}
+	if len(params.Targets) == 0 {
+		params.Targets = []string{"8.8.8.8", "1.1.1.1"}
+	}
+	if params.RepeatCount < 2 {
+		params.RepeatCount = 5
+	}
+	if params.DohEndpoint == "" {
+		params.DohEndpoint = defaultDohEndpoint
+	}
+
+	// A nameservers list runs every mode once per server for a head-to-head
+	// comparison; otherwise the single nameserver (or system default) is used.
+	nameservers := params.Nameservers
+	if len(nameservers) == 0 {
+		nameservers = []string{params.Nameserver}
+	}
+	for i, nameserver := range nameservers {
+		nameservers[i] = normalizeNameserver(nameserver)
+	}
 
 	startTime := time.Now()
 	var testCases []TestCase
-	var allResolutionTimes []float64
+	var allResolutionTimes, allFailureTimes []float64
 	totalIterations := 0
+	totalAttempts := 0
+	failuresByType := make(map[string]int)
 
-	for _, mode := range params.ResolutionModes {
-		fmt.Fprintf(os.Stderr, "Testing DNS resolution mode: %s...\n", mode)
+	for n, nameserver := range nameservers {
+		if ctx.Err() != nil {
+			break
+		}
+		for i := 0; i < params.WarmupIterations && ctx.Err() == nil; i++ {
+			fmt.Fprintf(os.Stderr, "Warmup %d/%d, nameserver: %s...\n", i+1, params.WarmupIterations, nameserver)
+			warmupNameserver(ctx, params.Domains, params.Targets, params.ResolutionModes, nameserver, params.Network, params.TimeoutSeconds)
+		}
 
-		var modeResolutionTimes []float64
-		modeSuccessful := 0
-		modeTotal := 0
-		var iterationsData []IterationResult
+		for _, mode := range params.ResolutionModes {
+			if ctx.Err() != nil {
+				break
+			}
 
-		for i := 0; i < params.Iterations; i++ {
-			fmt.Fprintf(os.Stderr, "  Iteration %d/%d...\n", i+1, params.Iterations)
+			// DoH goes to its endpoint whatever the nameserver, so it only
+			// runs once, as its own test case.
+			caseNameserver := nameserver
+			if mode == "doh" {
+				if n > 0 {
+					continue
+				}
+				caseNameserver = params.DohEndpoint
+				for i := 0; i < params.WarmupIterations && ctx.Err() == nil; i++ {
+					fmt.Fprintf(os.Stderr, "Warmup %d/%d, DoH endpoint: %s...\n", i+1, params.WarmupIterations, params.DohEndpoint)
+					warmupDoh(ctx, params.Domains, params.DohEndpoint, params.Network, params.TimeoutSeconds)
+				}
+			}
+			fmt.Fprintf(os.Stderr, "Testing DNS resolution mode: %s, nameserver: %s...\n", mode, caseNameserver)
 
-			iterationStart := time.Now()
+			var modeResolutionTimes, modeFailureTimes []float64
+			modeSuccessful := 0
+			modeTotal := 0
+			var iterationsData []IterationResult
 
-			var domainResults []DnsResult
-			switch mode {
-			case "sequential":
-				domainResults = resolveDomainsSequential(params.Domains, params.TimeoutSeconds)
-			case "concurrent":
-				domainResults = resolveDomainsConcurrent(params.Domains, params.ConcurrentWorkers, params.TimeoutSeconds)
-			default:
-				fmt.Fprintf(os.Stderr, "Warning: Unknown resolution mode '%s', using sequential\n", mode)
-				domainResults = resolveDomainsSequential(params.Domains, params.TimeoutSeconds)
-			}
+			for i := 0; i < params.Iterations && ctx.Err() == nil; i++ {
+				fmt.Fprintf(os.Stderr, "  Iteration %d/%d...\n", i+1, params.Iterations)
 
-			iterationTotalTime := float64(time.Since(iterationStart).Nanoseconds()) / 1e6
+				iterationStart := time.Now()
 
-			iterationSuccessful := 0
-			var iterationTimes []float64
-			for _, result := range domainResults {
-				if result.Success {
-					iterationSuccessful++
-					iterationTimes = append(iterationTimes, result.ResponseTimeMs)
-					modeResolutionTimes = append(modeResolutionTimes, result.ResponseTimeMs)
-					allResolutionTimes = append(allResolutionTimes, result.ResponseTimeMs)
+				var domainResults []DnsResult
+				switch mode {
+				case "sequential":
+					domainResults = resolveDomainsSequential(ctx, params.Domains, nameserver, params.Network, params.TimeoutSeconds)
+				case "concurrent":
+					domainResults = resolveDomainsConcurrent(ctx, params.Domains, nameserver, params.Network, params.ConcurrentWorkers, params.TimeoutSeconds)
+				case "reverse":
+					domainResults = resolveAddressesReverse(ctx, params.Targets, nameserver, params.TimeoutSeconds)
+				case "doh":
+					domainResults = resolveDomainsDoH(ctx, params.Domains, params.DohEndpoint, params.Network, params.TimeoutSeconds)
+				default:
+					fmt.Fprintf(os.Stderr, "Warning: Unknown resolution mode '%s', using sequential\n", mode)
+					domainResults = resolveDomainsSequential(ctx, params.Domains, nameserver, params.Network, params.TimeoutSeconds)
 				}
+
+				iterationTotalTime := float64(time.Since(iterationStart).Nanoseconds()) / 1e6
+
+				iterationSuccessful := 0
+				var iterationTimes []float64
+				for _, result := range domainResults {
+					if result.Success {
+						iterationSuccessful++
+						iterationTimes = append(iterationTimes, result.ResponseTimeMs)
+						modeResolutionTimes = append(modeResolutionTimes, result.ResponseTimeMs)
+						allResolutionTimes = append(allResolutionTimes, result.ResponseTimeMs)
+					} else {
+						failuresByType[result.ErrorType]++
+						modeFailureTimes = append(modeFailureTimes, result.ResponseTimeMs)
+						allFailureTimes = append(allFailureTimes, result.ResponseTimeMs)
+					}
+				}
+
+				iterationFailed := len(domainResults) - iterationSuccessful
+
+				var iterationAvgTime float64
+				if len(iterationTimes) > 0 {
+					sum := 0.0
+					for _, t := range iterationTimes {
+						sum += t
+					}
+					iterationAvgTime = sum / float64(len(iterationTimes))
+				}
+
+				modeSuccessful += iterationSuccessful
+				modeTotal += len(domainResults)
+				totalIterations++
+				totalAttempts += len(domainResults)
+
+				iterationResult := IterationResult{
+					Iteration:             i + 1,
+					TotalTimeMs:           iterationTotalTime,
+					DomainsResolved:       len(domainResults),
+					SuccessfulResolutions: iterationSuccessful,
+					FailedResolutions:     iterationFailed,
+					AvgResolutionTimeMs:   iterationAvgTime,
+					DomainResults:         domainResults,
+				}
+
+				iterationsData = append(iterationsData, iterationResult)
 			}
 
-			iterationFailed := len(domainResults) - iterationSuccessful
+			domainsCount := len(params.Domains)
+			if mode == "reverse" {
+				domainsCount = len(params.Targets)
+			}
 
-			var iterationAvgTime float64
-			if len(iterationTimes) > 0 {
+			// Calculate test case averages
+			var avgResolutionTime, fastestResolution, slowestResolution float64
+			if len(modeResolutionTimes) > 0 {
 				sum := 0.0
-				for _, t := range iterationTimes {
+				fastestResolution = modeResolutionTimes[0]
+				slowestResolution = modeResolutionTimes[0]
+
+				for _, t := range modeResolutionTimes {
 					sum += t
+					if t < fastestResolution {
+						fastestResolution = t
+					}
+					if t > slowestResolution {
+						slowestResolution = t
+					}
 				}
-				iterationAvgTime = sum / float64(len(iterationTimes))
+				avgResolutionTime = sum / float64(len(modeResolutionTimes))
 			}
 
-			modeSuccessful += iterationSuccessful
-			modeTotal += len(domainResults)
-			totalIterations++
-
-			iterationResult := IterationResult{
-				Iteration:             i + 1,
-				TotalTimeMs:           iterationTotalTime,
-				DomainsResolved:       len(domainResults),
-				SuccessfulResolutions: iterationSuccessful,
-				FailedResolutions:     iterationFailed,
-				AvgResolutionTimeMs:   iterationAvgTime,
-				DomainResults:         domainResults,
+			var successRate float64
+			if modeTotal > 0 {
+				successRate = (float64(modeSuccessful) / float64(modeTotal)) * 100.0
 			}
 
-			iterationsData = append(iterationsData, iterationResult)
-		}
-
-		// Calculate test case averages
-		var avgResolutionTime, fastestResolution, slowestResolution float64
-		if len(modeResolutionTimes) > 0 {
-			sum := 0.0
-			fastestResolution = modeResolutionTimes[0]
-			slowestResolution = modeResolutionTimes[0]
-
-			for _, t := range modeResolutionTimes {
-				sum += t
-				if t < fastestResolution {
-					fastestResolution = t
-				}
-				if t > slowestResolution {
-					slowestResolution = t
-				}
+			testCase := TestCase{
+				ResolutionMode:    mode,
+				Nameserver:        caseNameserver,
+				Network:           params.Network,
+				DomainsCount:      domainsCount,
+				Iterations:        iterationsData,
+				AvgResolutionTime: avgResolutionTime,
+				AvgSuccessTime:    avgResolutionTime,
+				AvgFailureTime:    stats.Mean(modeFailureTimes),
+				FastestResolution: fastestResolution,
+				SlowestResolution: slowestResolution,
+				SuccessRate:       successRate,
+				TotalSuccessful:   modeSuccessful,
+				TotalAttempts:     modeTotal,
 			}
-			avgResolutionTime = sum / float64(len(modeResolutionTimes))
-		}
 
-		var successRate float64
-		if modeTotal > 0 {
-			successRate = (float64(modeSuccessful) / float64(modeTotal)) * 100.0
+			testCases = append(testCases, testCase)
 		}
+	}
 
-		testCase := TestCase{
-			ResolutionMode:    mode,
-			DomainsCount:      len(params.Domains),
-			Iterations:        iterationsData,
-			AvgResolutionTime: avgResolutionTime,
-			FastestResolution: fastestResolution,
-			SlowestResolution: slowestResolution,
-			SuccessRate:       successRate,
-			TotalSuccessful:   modeSuccessful,
-			TotalAttempts:     modeTotal,
+	var cacheTests []CacheTestResult
+	if params.CacheTest && ctx.Err() == nil {
+		for _, nameserver := range nameservers {
+			fmt.Fprintf(os.Stderr, "Testing DNS cache behavior, nameserver: %s...\n", nameserver)
+			for _, domain := range params.Domains {
+				if ctx.Err() != nil {
+					break
+				}
+				cacheTests = append(cacheTests, runCacheTest(ctx, domain, nameserver, params.Network, params.RepeatCount, params.TimeoutSeconds))
+			}
 		}
-
-		testCases = append(testCases, testCase)
 	}
 
 	// Calculate overall summary
 	successfulResolutions := len(allResolutionTimes)
-	failedResolutions := (totalIterations * len(params.Domains)) - successfulResolutions
+	failedResolutions := totalAttempts - successfulResolutions
 
 	var avgResolutionTime, fastestResolution, slowestResolution float64
 	if len(allResolutionTimes) > 0 {
@@ -345,16 +1017,25 @@ func runDnsBenchmark(config Config) BenchmarkResult {
 	executionTime := endTime.Sub(startTime).Seconds()
 
 	return BenchmarkResult{
-		StartTime: startTime.Unix(),
-		TestCases: testCases,
+		SchemaVersion: cli.SchemaVersion,
+		Environment:   cli.CurrentEnvironment(),
+		StartTime:     startTime.Unix(),
+		TestCases:     testCases,
+		CacheTests:    cacheTests,
 		Summary: Summary{
+			Nameservers:           nameservers,
 			TotalDomains:          len(params.Domains),
 			TotalIterations:       totalIterations,
 			SuccessfulResolutions: successfulResolutions,
 			FailedResolutions:     failedResolutions,
 			AvgResolutionTime:     avgResolutionTime,
+			AvgSuccessTime:        avgResolutionTime,
+			AvgFailureTime:        stats.Mean(allFailureTimes),
 			FastestResolution:     fastestResolution,
 			SlowestResolution:     slowestResolution,
+			WarmupIterations:      params.WarmupIterations,
+			TimedOut:              cli.TimedOut(ctx),
+			FailuresByType:        failuresByType,
 		},
 		EndTime:            endTime.Unix(),
 		TotalExecutionTime: executionTime,
@@ -362,32 +1043,27 @@ func runDnsBenchmark(config Config) BenchmarkResult {
 }
 
 func main() {
-	if len(os.Args) < 2 {
-		fmt.Fprintf(os.Stderr, "Usage: %s <config_file>\n", os.Args[0])
-		os.Exit(1)
-	}
-
-	configFile := os.Args[1]
-
-	configData, err := os.ReadFile(configFile)
+	opts, err := cli.Parse(filepath.Base(os.Args[0]), os.Args[1:])
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: Cannot read config file '%s': %v\n", configFile, err)
-		os.Exit(1)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
 	}
 
 	var config Config
-	if err := json.Unmarshal(configData, &config); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: Invalid JSON in config file: %v\n", err)
+	if err := opts.LoadConfig(&config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	results := runDnsBenchmark(config)
+	ctx, cancel := cli.Deadline(config.Parameters.DeadlineSeconds)
+	defer cancel()
 
-	output, err := json.MarshalIndent(results, "", "  ")
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: Failed to marshal results: %v\n", err)
+	results := runDnsBenchmark(ctx, config)
+	results.Name = config.Name
+	results.Tags = config.Parameters.Tags
+
+	if err := opts.WriteResults(results); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
-
-	fmt.Println(string(output))
 }
@@ -1,3 +1,5 @@
 module dns_lookup
 
-go 1.19
\ No newline at end of file
+go 1.19
+
+require golang.org/x/net v0.29.0
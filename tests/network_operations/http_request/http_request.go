@@ -1,18 +1,27 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"crypto/tls"
-	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptrace"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"benchmark_test/internal/cli"
+	"benchmark_test/internal/stats"
 )
 
 type Config struct {
 	Parameters Parameters `json:"parameters"`
+	Name       string     `json:"name"`
 }
 
 type Parameters struct {
@@ -21,47 +30,365 @@ type Parameters struct {
 	Timeout            *int      `json:"timeout,omitempty"`
 	Methods            *[]string `json:"methods,omitempty"`
 	ConcurrentRequests *int      `json:"concurrent_requests,omitempty"`
+	Body               *string   `json:"body,omitempty"`
+	BodySize           *int      `json:"body_size,omitempty"`
+	ContentType        *string   `json:"content_type,omitempty"`
+	FollowRedirects    *bool     `json:"follow_redirects,omitempty"`
+	DisableKeepAlive   *bool     `json:"disable_keepalive,omitempty"`
+	WarmupIterations   *int      `json:"warmup_iterations,omitempty"`
+	DeadlineSeconds    *int      `json:"deadline_seconds,omitempty"`
+	ForceHTTP2         *bool     `json:"force_http2,omitempty"`
+	DisableHTTP2       *bool     `json:"disable_http2,omitempty"`
+	RateLimitRPS       *int      `json:"rate_limit_rps,omitempty"`
+	GOMAXPROCS         *int      `json:"gomaxprocs,omitempty"`
+	Tags               []string  `json:"tags"`
+}
+
+// Validate checks the parameters that are set; unset ones take the defaults
+// in runHTTPBenchmark.
+func (c *Config) Validate() error {
+	p := c.Parameters
+	return cli.FirstError(
+		cli.Required("urls", len(p.URLs)),
+		cli.OptionalPositive("request_count", p.RequestCount),
+		cli.OptionalPositive("timeout", p.Timeout),
+		cli.OptionalPositive("concurrent_requests", p.ConcurrentRequests),
+		cli.OptionalNonNegative("body_size", p.BodySize),
+		cli.OptionalNonNegative("warmup_iterations", p.WarmupIterations),
+		cli.OptionalNonNegative("deadline_seconds", p.DeadlineSeconds),
+		validRateLimit(p.RateLimitRPS),
+		cli.OptionalPositive("gomaxprocs", p.GOMAXPROCS),
+	)
+}
+
+// maxRateLimitRPS is the highest rate the dispatch ticker can pace: above
+// it the tick interval rounds down to zero, which time.NewTicker rejects.
+const maxRateLimitRPS = int(time.Second)
+
+// validRateLimit allows an unset or zero rate_limit_rps (no pacing) and
+// rates up to maxRateLimitRPS.
+func validRateLimit(rps *int) error {
+	if rps == nil {
+		return nil
+	}
+	return cli.InRange("rate_limit_rps", *rps, 0, maxRateLimitRPS)
 }
 
 type RequestResult struct {
-	Success       bool    `json:"success"`
-	ResponseTime  float64 `json:"response_time"`
-	StatusCode    int     `json:"status_code"`
-	ContentLength int     `json:"content_length"`
-	Error         *string `json:"error,omitempty"`
+	Success          bool     `json:"success"`
+	ResponseTime     float64  `json:"response_time"`
+	StatusCode       int      `json:"status_code"`
+	Protocol         string   `json:"protocol,omitempty"`
+	ContentLength    int      `json:"content_length"`
+	UploadedBytes    int      `json:"uploaded_bytes"`
+	RedirectCount    int      `json:"redirect_count"`
+	RedirectTarget   *string  `json:"redirect_target,omitempty"`
+	ConnectionReused bool     `json:"connection_reused"`
+	DNSTimeMs        *float64 `json:"dns_time_ms,omitempty"`
+	ConnectTimeMs    *float64 `json:"connect_time_ms,omitempty"`
+	TLSHandshakeMs   *float64 `json:"tls_handshake_ms,omitempty"`
+	TLSVersion       string   `json:"tls_version,omitempty"`
+	TLSCipherSuite   string   `json:"tls_cipher_suite,omitempty"`
+	TTFBMs           *float64 `json:"ttfb_ms,omitempty"`
+	DownloadMs       *float64 `json:"download_ms,omitempty"`
+	Error            *string  `json:"error,omitempty"`
 }
 
 type URLResults struct {
-	Requests           []RequestResult `json:"requests"`
-	AvgResponseTime    float64         `json:"avg_response_time"`
-	SuccessRate        float64         `json:"success_rate"`
-	TotalRequests      int             `json:"total_requests"`
-	SuccessfulRequests int             `json:"successful_requests"`
+	Requests            []RequestResult `json:"requests"`
+	AvgResponseTime     float64         `json:"avg_response_time"`
+	SuccessRate         float64         `json:"success_rate"`
+	TotalRequests       int             `json:"total_requests"`
+	SuccessfulRequests  int             `json:"successful_requests"`
+	AchievedConcurrency int             `json:"achieved_concurrency"`
+	WallClockTime       float64         `json:"wall_clock_time"`
+	SummedResponseTime  float64         `json:"summed_response_time"`
+	Speedup             float64         `json:"speedup"`
+	P50ResponseTime     float64         `json:"p50_response_time"`
+	P90ResponseTime     float64         `json:"p90_response_time"`
+	P95ResponseTime     float64         `json:"p95_response_time"`
+	P99ResponseTime     float64         `json:"p99_response_time"`
+	ConnectionReuseRate float64         `json:"connection_reuse_rate"`
+	AvgDNSTime          float64         `json:"avg_dns_time"`
+	AvgConnectTime      float64         `json:"avg_connect_time"`
+	AvgTLSHandshakeTime float64         `json:"avg_tls_handshake_time"`
+	Protocols           map[string]int  `json:"protocols,omitempty"`
+	TargetRPS           int             `json:"target_rps,omitempty"`
+	AchievedRPS         float64         `json:"achieved_rps"`
+
+	TotalBytesDownloaded  int          `json:"total_bytes_downloaded"`
+	DownloadThroughputMbS float64      `json:"download_throughput_mb_s"`
+	ResponseSizeHistogram []SizeBucket `json:"response_size_histogram"`
 }
 
 type Summary struct {
-	TotalRequests      int     `json:"total_requests"`
-	SuccessfulRequests int     `json:"successful_requests"`
-	FailedRequests     int     `json:"failed_requests"`
-	AvgResponseTime    float64 `json:"avg_response_time"`
-	MinResponseTime    float64 `json:"min_response_time"`
-	MaxResponseTime    float64 `json:"max_response_time"`
-	SuccessRate        float64 `json:"success_rate"`
+	TotalRequests       int     `json:"total_requests"`
+	SuccessfulRequests  int     `json:"successful_requests"`
+	FailedRequests      int     `json:"failed_requests"`
+	AvgResponseTime     float64 `json:"avg_response_time"`
+	MinResponseTime     float64 `json:"min_response_time"`
+	MaxResponseTime     float64 `json:"max_response_time"`
+	P50ResponseTime     float64 `json:"p50_response_time"`
+	P90ResponseTime     float64 `json:"p90_response_time"`
+	P95ResponseTime     float64 `json:"p95_response_time"`
+	P99ResponseTime     float64 `json:"p99_response_time"`
+	SuccessRate         float64 `json:"success_rate"`
+	ConnectionReuseRate float64 `json:"connection_reuse_rate"`
+	AvgDNSTime          float64 `json:"avg_dns_time"`
+	AvgConnectTime      float64 `json:"avg_connect_time"`
+	AvgTLSHandshakeTime float64 `json:"avg_tls_handshake_time"`
+	WarmupIterations    int     `json:"warmup_iterations"`
+	TimedOut            bool    `json:"timed_out"`
+	TargetRPS           int     `json:"target_rps,omitempty"`
+	AchievedRPS         float64 `json:"achieved_rps"`
+
+	TotalBytesDownloaded  int          `json:"total_bytes_downloaded"`
+	DownloadThroughputMbS float64      `json:"download_throughput_mb_s"`
+	ResponseSizeHistogram []SizeBucket `json:"response_size_histogram"`
+}
+
+// SizeBucket counts responses whose body size falls in [MinBytes, MaxBytes).
+// The last bucket has no upper bound.
+type SizeBucket struct {
+	Label    string `json:"label"`
+	MinBytes int    `json:"min_bytes"`
+	MaxBytes *int   `json:"max_bytes,omitempty"`
+	Count    int    `json:"count"`
 }
 
 type Results struct {
-	StartTime           float64                `json:"start_time"`
-	URLs                map[string]URLResults  `json:"urls"`
-	Summary             Summary                `json:"summary"`
-	EndTime             float64                `json:"end_time"`
-	TotalExecutionTime  float64                `json:"total_execution_time"`
+	SchemaVersion      string                `json:"schema_version"`
+	Environment        cli.Environment       `json:"environment"`
+	Name               string                `json:"name,omitempty"`
+	Tags               []string              `json:"tags,omitempty"`
+	StartTime          float64               `json:"start_time"`
+	URLs               map[string]URLResults `json:"urls"`
+	Summary            Summary               `json:"summary"`
+	EndTime            float64               `json:"end_time"`
+	TotalExecutionTime float64               `json:"total_execution_time"`
 }
 
-func makeHTTPRequest(client *http.Client, url, method string) RequestResult {
+// generatePayload builds a body of size bytes from a repeating printable
+// pattern, used when body_size is configured instead of a literal body.
+func generatePayload(size int) []byte {
+	const pattern = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+	payload := make([]byte, size)
+	for i := range payload {
+		payload[i] = pattern[i%len(pattern)]
+	}
+	return payload
+}
+
+// methodAllowsBody reports whether a request body is sent for method. GET,
+// HEAD and friends are always benchmarked without a payload.
+func methodAllowsBody(method string) bool {
+	switch strings.ToUpper(method) {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// requestTiming collects httptrace callbacks for a single request. The
+// transport may dial several addresses in parallel, so access is locked.
+type requestTiming struct {
+	mu           sync.Mutex
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	firstByte    time.Time
+	reused       bool
+	dnsTime      *float64
+	connectTime  *float64
+	tlsTime      *float64
+}
+
+func (t *requestTiming) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mu.Lock()
+			t.dnsStart = time.Now()
+			t.mu.Unlock()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.mu.Lock()
+			elapsed := float64(time.Since(t.dnsStart).Nanoseconds()) / 1e6
+			t.dnsTime = &elapsed
+			t.mu.Unlock()
+		},
+		ConnectStart: func(network, addr string) {
+			t.mu.Lock()
+			t.connectStart = time.Now()
+			t.mu.Unlock()
+		},
+		ConnectDone: func(network, addr string, err error) {
+			t.mu.Lock()
+			if err == nil {
+				elapsed := float64(time.Since(t.connectStart).Nanoseconds()) / 1e6
+				t.connectTime = &elapsed
+			}
+			t.mu.Unlock()
+		},
+		TLSHandshakeStart: func() {
+			t.mu.Lock()
+			t.tlsStart = time.Now()
+			t.mu.Unlock()
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			t.mu.Lock()
+			if err == nil {
+				elapsed := float64(time.Since(t.tlsStart).Nanoseconds()) / 1e6
+				t.tlsTime = &elapsed
+			}
+			t.mu.Unlock()
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			t.reused = info.Reused
+			t.mu.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			t.mu.Lock()
+			t.firstByte = time.Now()
+			t.mu.Unlock()
+		},
+	}
+}
+
+// connectionStats aggregates connection reuse and setup timings over a set
+// of requests.
+type connectionStats struct {
+	requests     int
+	reused       int
+	dnsTimes     []float64
+	connectTimes []float64
+	tlsTimes     []float64
+}
+
+func (c *connectionStats) add(result RequestResult) {
+	c.requests++
+	if result.ConnectionReused {
+		c.reused++
+	}
+	if result.DNSTimeMs != nil {
+		c.dnsTimes = append(c.dnsTimes, *result.DNSTimeMs)
+	}
+	if result.ConnectTimeMs != nil {
+		c.connectTimes = append(c.connectTimes, *result.ConnectTimeMs)
+	}
+	if result.TLSHandshakeMs != nil {
+		c.tlsTimes = append(c.tlsTimes, *result.TLSHandshakeMs)
+	}
+}
+
+// tlsVersionName names a negotiated TLS version for the results.
+func tlsVersionName(version uint16) string {
+	switch version {
+	case tls.VersionTLS10:
+		return "TLS 1.0"
+	case tls.VersionTLS11:
+		return "TLS 1.1"
+	case tls.VersionTLS12:
+		return "TLS 1.2"
+	case tls.VersionTLS13:
+		return "TLS 1.3"
+	default:
+		return fmt.Sprintf("0x%04X", version)
+	}
+}
+
+func (c *connectionStats) reuseRate() float64 {
+	if c.requests == 0 {
+		return 0.0
+	}
+	return float64(c.reused) / float64(c.requests) * 100.0
+}
+
+// sizeBucketBounds are the upper bounds of the response size buckets.
+var sizeBucketBounds = []int{1 << 10, 10 << 10, 100 << 10, 1 << 20, 10 << 20}
+
+func formatBytes(size int) string {
+	switch {
+	case size >= 1<<20:
+		return fmt.Sprintf("%dMiB", size>>20)
+	case size >= 1<<10:
+		return fmt.Sprintf("%dKiB", size>>10)
+	default:
+		return fmt.Sprintf("%dB", size)
+	}
+}
+
+// downloadStats tracks the body bytes received over a set of requests and
+// how they are distributed over the size buckets.
+type downloadStats struct {
+	totalBytes int
+	histogram  []SizeBucket
+}
+
+func newDownloadStats() *downloadStats {
+	d := &downloadStats{}
+	lower := 0
+	for i := range sizeBucketBounds {
+		upper := sizeBucketBounds[i]
+		d.histogram = append(d.histogram, SizeBucket{
+			Label:    formatBytes(lower) + "-" + formatBytes(upper),
+			MinBytes: lower,
+			MaxBytes: &upper,
+		})
+		lower = upper
+	}
+	d.histogram = append(d.histogram, SizeBucket{
+		Label:    ">=" + formatBytes(lower),
+		MinBytes: lower,
+	})
+	return d
+}
+
+// add counts the body of result. Requests that never got a response
+// downloaded nothing and are left out.
+func (d *downloadStats) add(result RequestResult) {
+	if result.StatusCode == 0 {
+		return
+	}
+	d.totalBytes += result.ContentLength
+	for i := range d.histogram {
+		bucket := &d.histogram[i]
+		if bucket.MaxBytes == nil || result.ContentLength < *bucket.MaxBytes {
+			bucket.Count++
+			return
+		}
+	}
+}
+
+// throughputMbS returns the download rate over elapsedMs of wall clock time.
+func (d *downloadStats) throughputMbS(elapsedMs float64) float64 {
+	if elapsedMs <= 0 {
+		return 0.0
+	}
+	return float64(d.totalBytes) / (elapsedMs / 1000.0) / (1024.0 * 1024.0)
+}
+
+// requestsPerSecond is the rate of count requests over elapsedMs.
+func requestsPerSecond(count int, elapsedMs float64) float64 {
+	if elapsedMs <= 0 {
+		return 0.0
+	}
+	return float64(count) / (elapsedMs / 1000.0)
+}
+
+func makeHTTPRequest(ctx context.Context, client *http.Client, url, method string, body []byte, contentType string) RequestResult {
 	start := time.Now()
 
+	var bodyReader io.Reader
+	uploadedBytes := 0
+	if body != nil && methodAllowsBody(method) {
+		bodyReader = bytes.NewReader(body)
+		uploadedBytes = len(body)
+	}
+
 	// Create request
-	req, err := http.NewRequest(strings.ToUpper(method), url, nil)
+	req, err := http.NewRequestWithContext(ctx, strings.ToUpper(method), url, bodyReader)
 	if err != nil {
 		responseTime := float64(time.Since(start).Nanoseconds()) / 1e6
 		errMsg := fmt.Sprintf("Request creation error: %v", err)
@@ -74,7 +401,13 @@ func makeHTTPRequest(client *http.Client, url, method string) RequestResult {
 		}
 	}
 
+	timing := &requestTiming{}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), timing.trace()))
+
 	req.Header.Set("User-Agent", "BenchmarkTool/1.0")
+	if bodyReader != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
 
 	// Make the request
 	resp, err := client.Do(req)
@@ -93,7 +426,8 @@ func makeHTTPRequest(client *http.Client, url, method string) RequestResult {
 	defer resp.Body.Close()
 
 	// Read response body
-	body, err := io.ReadAll(resp.Body)
+	responseBody, err := io.ReadAll(resp.Body)
+	bodyDone := time.Now()
 	if err != nil {
 		errMsg := fmt.Sprintf("Content read error: %v", err)
 		return RequestResult{
@@ -105,23 +439,137 @@ func makeHTTPRequest(client *http.Client, url, method string) RequestResult {
 		}
 	}
 
-	isSuccess := resp.StatusCode >= 200 && resp.StatusCode < 300
+	// Each followed redirect links the final request back to the response
+	// that caused it.
+	redirectCount := 0
+	for r := resp.Request; r != nil && r.Response != nil; r = r.Response.Request {
+		redirectCount++
+	}
+
+	// A 3xx that reaches us with a Location header was deliberately not
+	// followed, so it is the expected outcome rather than an error.
+	var redirectTarget *string
+	isRedirect := resp.StatusCode >= 300 && resp.StatusCode < 400
+	if location := resp.Header.Get("Location"); isRedirect && location != "" {
+		redirectTarget = &location
+	}
+
+	isSuccess := (resp.StatusCode >= 200 && resp.StatusCode < 300) || redirectTarget != nil
 	var errorMsg *string
 	if !isSuccess {
 		msg := fmt.Sprintf("HTTP Error %d", resp.StatusCode)
 		errorMsg = &msg
 	}
 
+	// Only HTTPS responses carry a connection state; a reused connection
+	// reports the version and suite it was set up with but no handshake.
+	var tlsVersion, tlsCipherSuite string
+	if resp.TLS != nil {
+		tlsVersion = tlsVersionName(resp.TLS.Version)
+		tlsCipherSuite = tls.CipherSuiteName(resp.TLS.CipherSuite)
+	}
+
+	timing.mu.Lock()
+	defer timing.mu.Unlock()
+
+	// Split server latency (time to first byte) from body transfer time.
+	var ttfb, download *float64
+	if !timing.firstByte.IsZero() {
+		ttfbMs := float64(timing.firstByte.Sub(start).Nanoseconds()) / 1e6
+		downloadMs := float64(bodyDone.Sub(timing.firstByte).Nanoseconds()) / 1e6
+		ttfb = &ttfbMs
+		download = &downloadMs
+	}
+
 	return RequestResult{
-		Success:       isSuccess,
-		ResponseTime:  responseTime,
-		StatusCode:    resp.StatusCode,
-		ContentLength: len(body),
-		Error:         errorMsg,
+		Success:          isSuccess,
+		ResponseTime:     responseTime,
+		StatusCode:       resp.StatusCode,
+		Protocol:         resp.Proto,
+		ContentLength:    len(responseBody),
+		UploadedBytes:    uploadedBytes,
+		RedirectCount:    redirectCount,
+		RedirectTarget:   redirectTarget,
+		ConnectionReused: timing.reused,
+		DNSTimeMs:        timing.dnsTime,
+		ConnectTimeMs:    timing.connectTime,
+		TLSHandshakeMs:   timing.tlsTime,
+		TLSVersion:       tlsVersion,
+		TLSCipherSuite:   tlsCipherSuite,
+		TTFBMs:           ttfb,
+		DownloadMs:       download,
+		Error:            errorMsg,
+	}
+}
+
+// runConcurrentRequests issues count requests to url with at most concurrency
+// in flight at once. Results keep request order, and the highest number of
+// simultaneously in-flight requests is returned alongside them. Once ctx is
+// done no further requests are started, so fewer than count results may be
+// returned.
+//
+// A positive rps paces dispatch: each request waits for a tick before taking
+// a worker slot. When every worker is busy the ticker drops the ticks it
+// cannot deliver, so a slow server lowers the rate instead of causing a
+// burst once workers free up.
+func runConcurrentRequests(ctx context.Context, client *http.Client, url, method string, body []byte, contentType string, count, concurrency, rps int) ([]RequestResult, int) {
+	results := make([]RequestResult, count)
+	semaphore := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	var inFlight, maxInFlight int64
+
+	var ticks <-chan time.Time
+	if rps > 0 {
+		ticker := time.NewTicker(time.Second / time.Duration(rps))
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+
+	launched := 0
+	for ; launched < count; launched++ {
+		if ticks != nil {
+			select {
+			case <-ticks:
+			case <-ctx.Done():
+			}
+		}
+		select {
+		case semaphore <- struct{}{}: // acquire
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+
+			current := atomic.AddInt64(&inFlight, 1)
+			for {
+				observed := atomic.LoadInt64(&maxInFlight)
+				if current <= observed || atomic.CompareAndSwapInt64(&maxInFlight, observed, current) {
+					break
+				}
+			}
+
+			results[index] = makeHTTPRequest(ctx, client, url, method, body, contentType)
+
+			atomic.AddInt64(&inFlight, -1)
+			<-semaphore // release
+		}(launched)
 	}
+
+	wg.Wait()
+
+	return results[:launched], int(maxInFlight)
 }
 
-func runHTTPBenchmark(params Parameters) Results {
+func runHTTPBenchmark(ctx context.Context, params Parameters) Results {
+	if params.GOMAXPROCS != nil {
+		defer cli.SetGOMAXPROCS(*params.GOMAXPROCS)()
+	}
+
 	startTime := float64(time.Now().UnixNano()) / 1e9
 
 	requestCount := 5
@@ -139,21 +587,81 @@ func runHTTPBenchmark(params Parameters) Results {
 		methods = *params.Methods
 	}
 
+	var body []byte
+	if params.Body != nil {
+		body = []byte(*params.Body)
+	} else if params.BodySize != nil {
+		body = generatePayload(*params.BodySize)
+	}
+
+	contentType := "text/plain; charset=utf-8"
+	if params.ContentType != nil {
+		contentType = *params.ContentType
+	}
+
+	warmupIterations := 0
+	if params.WarmupIterations != nil {
+		warmupIterations = *params.WarmupIterations
+	}
+
+	concurrency := 1
+	if params.ConcurrentRequests != nil && *params.ConcurrentRequests > 1 {
+		concurrency = *params.ConcurrentRequests
+	}
+
+	rateLimit := 0
+	if params.RateLimitRPS != nil && *params.RateLimitRPS > 0 {
+		rateLimit = *params.RateLimitRPS
+	}
+
 	urlsResults := make(map[string]URLResults)
 	totalRequests := 0
 	successfulRequests := 0
 	var totalResponseTime float64
 	minResponseTime := float64(^uint(0) >> 1) // Max float64
 	var maxResponseTime float64
+	var allResponseTimes []float64
+	var allConnectionStats connectionStats
+	allDownloads := newDownloadStats()
+	var measuredTime float64
+
+	forceHTTP2 := params.ForceHTTP2 != nil && *params.ForceHTTP2
+	disableHTTP2 := params.DisableHTTP2 != nil && *params.DisableHTTP2
+	if forceHTTP2 && disableHTTP2 {
+		fmt.Fprintf(os.Stderr, "Warning: force_http2 and disable_http2 both set, disabling HTTP/2\n")
+		forceHTTP2 = false
+	}
+
+	transport := &http.Transport{
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+		DisableKeepAlives: params.DisableKeepAlive != nil && *params.DisableKeepAlive,
+		// A custom TLS config turns off the transport's automatic HTTP/2,
+		// so HTTPS requests use HTTP/1.1 unless force_http2 asks for h2.
+		// HTTP/2 is only ever negotiated over TLS; plain http:// URLs
+		// always use HTTP/1.1.
+		ForceAttemptHTTP2: forceHTTP2,
+	}
+	if disableHTTP2 {
+		// A non-nil, empty TLSNextProto keeps h2 out of ALPN for good.
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
 
 	client := &http.Client{
-		Timeout: time.Duration(timeout) * time.Millisecond,
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		},
+		Timeout:   time.Duration(timeout) * time.Millisecond,
+		Transport: transport,
+	}
+
+	if params.FollowRedirects != nil && !*params.FollowRedirects {
+		client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
 	}
 
 	for _, url := range params.URLs {
+		if ctx.Err() != nil {
+			fmt.Fprintf(os.Stderr, "Deadline reached, skipping remaining URLs\n")
+			break
+		}
 		fmt.Fprintf(os.Stderr, "Testing %s...\n", url)
 
 		urlResults := URLResults{
@@ -161,14 +669,45 @@ func runHTTPBenchmark(params Parameters) Results {
 		}
 
 		var urlResponseTimes []float64
+		var urlConnectionStats connectionStats
+		urlDownloads := newDownloadStats()
 		urlSuccessful := 0
 
+		// Warmup requests prime DNS, TLS sessions and pooled connections;
+		// they are sent before urlStart and their results are discarded.
+		if warmupIterations > 0 {
+			for _, method := range methods {
+				fmt.Fprintf(os.Stderr, "  %d %s warmup requests...\n", warmupIterations, method)
+				runConcurrentRequests(ctx, client, url, method, body, contentType, warmupIterations, 1, 0)
+			}
+		}
+
+		urlStart := time.Now()
+
 		for _, method := range methods {
-			for i := 0; i < requestCount; i++ {
-				fmt.Fprintf(os.Stderr, "  Request %d/%d (%s)...\n", i+1, requestCount, method)
+			if rateLimit > 0 {
+				fmt.Fprintf(os.Stderr, "  %d %s requests, concurrency %d, %d requests/s...\n", requestCount, method, concurrency, rateLimit)
+			} else {
+				fmt.Fprintf(os.Stderr, "  %d %s requests, concurrency %d...\n", requestCount, method, concurrency)
+			}
 
-				requestResult := makeHTTPRequest(client, url, method)
+			methodResults, methodMaxInFlight := runConcurrentRequests(ctx, client, url, method, body, contentType, requestCount, concurrency, rateLimit)
+			if methodMaxInFlight > urlResults.AchievedConcurrency {
+				urlResults.AchievedConcurrency = methodMaxInFlight
+			}
 
+			for _, requestResult := range methodResults {
+				if requestResult.Protocol != "" {
+					if urlResults.Protocols == nil {
+						urlResults.Protocols = make(map[string]int)
+					}
+					urlResults.Protocols[requestResult.Protocol]++
+				}
+				urlResults.SummedResponseTime += requestResult.ResponseTime
+				urlConnectionStats.add(requestResult)
+				allConnectionStats.add(requestResult)
+				urlDownloads.add(requestResult)
+				allDownloads.add(requestResult)
 				totalRequests++
 				urlResults.TotalRequests++
 
@@ -178,6 +717,7 @@ func runHTTPBenchmark(params Parameters) Results {
 
 					responseTime := requestResult.ResponseTime
 					urlResponseTimes = append(urlResponseTimes, responseTime)
+					allResponseTimes = append(allResponseTimes, responseTime)
 					totalResponseTime += responseTime
 
 					if responseTime < minResponseTime {
@@ -192,17 +732,34 @@ func runHTTPBenchmark(params Parameters) Results {
 			}
 		}
 
+		urlResults.WallClockTime = float64(time.Since(urlStart).Nanoseconds()) / 1e6
+		if urlResults.WallClockTime > 0 {
+			urlResults.Speedup = urlResults.SummedResponseTime / urlResults.WallClockTime
+		}
+		measuredTime += urlResults.WallClockTime
+		urlResults.TargetRPS = rateLimit
+		urlResults.AchievedRPS = requestsPerSecond(urlResults.TotalRequests, urlResults.WallClockTime)
+
+		urlResults.TotalBytesDownloaded = urlDownloads.totalBytes
+		urlResults.DownloadThroughputMbS = urlDownloads.throughputMbS(urlResults.WallClockTime)
+		urlResults.ResponseSizeHistogram = urlDownloads.histogram
+
+		urlResults.ConnectionReuseRate = urlConnectionStats.reuseRate()
+		urlResults.AvgDNSTime = stats.Mean(urlConnectionStats.dnsTimes)
+		urlResults.AvgConnectTime = stats.Mean(urlConnectionStats.connectTimes)
+		urlResults.AvgTLSHandshakeTime = stats.Mean(urlConnectionStats.tlsTimes)
+
 		urlResults.SuccessfulRequests = urlSuccessful
 		if urlResults.TotalRequests > 0 {
 			urlResults.SuccessRate = float64(urlSuccessful) / float64(urlResults.TotalRequests) * 100.0
 		}
 
 		if len(urlResponseTimes) > 0 {
-			sum := 0.0
-			for _, rt := range urlResponseTimes {
-				sum += rt
-			}
-			urlResults.AvgResponseTime = sum / float64(len(urlResponseTimes))
+			urlResults.AvgResponseTime = stats.Mean(urlResponseTimes)
+			urlResults.P50ResponseTime = stats.Percentile(urlResponseTimes, 50)
+			urlResults.P90ResponseTime = stats.Percentile(urlResponseTimes, 90)
+			urlResults.P95ResponseTime = stats.Percentile(urlResponseTimes, 95)
+			urlResults.P99ResponseTime = stats.Percentile(urlResponseTimes, 99)
 		}
 
 		urlsResults[url] = urlResults
@@ -225,16 +782,36 @@ func runHTTPBenchmark(params Parameters) Results {
 	endTime := float64(time.Now().UnixNano()) / 1e9
 
 	return Results{
-		StartTime: startTime,
-		URLs:      urlsResults,
+		SchemaVersion: cli.SchemaVersion,
+		Environment:   cli.CurrentEnvironment(),
+		StartTime:     startTime,
+		URLs:          urlsResults,
 		Summary: Summary{
-			TotalRequests:      totalRequests,
-			SuccessfulRequests: successfulRequests,
-			FailedRequests:     totalRequests - successfulRequests,
-			AvgResponseTime:    avgResponseTime,
-			MinResponseTime:    minResponseTime,
-			MaxResponseTime:    maxResponseTime,
-			SuccessRate:        successRate,
+			TotalRequests:       totalRequests,
+			SuccessfulRequests:  successfulRequests,
+			FailedRequests:      totalRequests - successfulRequests,
+			AvgResponseTime:     avgResponseTime,
+			MinResponseTime:     minResponseTime,
+			MaxResponseTime:     maxResponseTime,
+			P50ResponseTime:     stats.Percentile(allResponseTimes, 50),
+			P90ResponseTime:     stats.Percentile(allResponseTimes, 90),
+			P95ResponseTime:     stats.Percentile(allResponseTimes, 95),
+			P99ResponseTime:     stats.Percentile(allResponseTimes, 99),
+			SuccessRate:         successRate,
+			ConnectionReuseRate: allConnectionStats.reuseRate(),
+			AvgDNSTime:          stats.Mean(allConnectionStats.dnsTimes),
+			AvgConnectTime:      stats.Mean(allConnectionStats.connectTimes),
+			AvgTLSHandshakeTime: stats.Mean(allConnectionStats.tlsTimes),
+			WarmupIterations:    warmupIterations,
+			TimedOut:            cli.TimedOut(ctx),
+			TargetRPS:           rateLimit,
+			AchievedRPS:         requestsPerSecond(totalRequests, measuredTime),
+
+			// Warmup requests are excluded: measuredTime only spans the
+			// measured requests of each URL.
+			TotalBytesDownloaded:  allDownloads.totalBytes,
+			DownloadThroughputMbS: allDownloads.throughputMbS(measuredTime),
+			ResponseSizeHistogram: allDownloads.histogram,
 		},
 		EndTime:            endTime,
 		TotalExecutionTime: endTime - startTime,
@@ -242,32 +819,32 @@ func runHTTPBenchmark(params Parameters) Results {
 }
 
 func main() {
-	if len(os.Args) < 2 {
-		fmt.Fprintf(os.Stderr, "Usage: %s <config_file>\n", os.Args[0])
-		os.Exit(1)
-	}
-
-	configFile := os.Args[1]
-
-	configData, err := os.ReadFile(configFile)
+	opts, err := cli.Parse(filepath.Base(os.Args[0]), os.Args[1:])
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error reading config file: %v\n", err)
-		os.Exit(1)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
 	}
+	opts.IterationsKey = "request_count"
 
 	var config Config
-	if err := json.Unmarshal(configData, &config); err != nil {
-		fmt.Fprintf(os.Stderr, "Error parsing config JSON: %v\n", err)
+	if err := opts.LoadConfig(&config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	results := runHTTPBenchmark(config.Parameters)
+	deadline := 0
+	if config.Parameters.DeadlineSeconds != nil {
+		deadline = *config.Parameters.DeadlineSeconds
+	}
+	ctx, cancel := cli.Deadline(deadline)
+	defer cancel()
+
+	results := runHTTPBenchmark(ctx, config.Parameters)
+	results.Name = config.Name
+	results.Tags = config.Parameters.Tags
 
-	output, err := json.MarshalIndent(results, "", "  ")
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error marshaling results: %v\n", err)
+	if err := opts.WriteResults(results); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
-
-	fmt.Println(string(output))
-}
\ No newline at end of file
+}
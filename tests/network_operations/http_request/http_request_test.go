@@ -0,0 +1,327 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func boolPtr(v bool) *bool {
+	return &v
+}
+
+// runSingleURL runs the benchmark against url alone and returns its results.
+func runSingleURL(t *testing.T, url string, params Parameters) URLResults {
+	t.Helper()
+	params.URLs = []string{url}
+	results := runHTTPBenchmark(context.Background(), params)
+	urlResults, ok := results.URLs[url]
+	if !ok {
+		t.Fatalf("no results for %s", url)
+	}
+	return urlResults
+}
+
+func TestMakeHTTPRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing":
+			http.NotFound(w, r)
+		case "/echo":
+			// Reply with the size and type of the uploaded body
+			body, _ := io.ReadAll(r.Body)
+			fmt.Fprintf(w, "%d %s", len(body), r.Header.Get("Content-Type"))
+		default:
+			io.WriteString(w, "hello")
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		path, method string
+		body         []byte
+		success      bool
+		status       int
+		response     string
+		uploaded     int
+	}{
+		{"ok", "/", "GET", nil, true, 200, "hello", 0},
+		{"not found", "/missing", "GET", nil, false, 404, "", 0},
+		{"post body", "/echo", "post", []byte("payload"), true, 200, "7 text/plain", 7},
+		{"get drops body", "/echo", "GET", []byte("payload"), true, 200, "0 ", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := makeHTTPRequest(context.Background(), server.Client(), server.URL+tt.path, tt.method, tt.body, "text/plain")
+			if result.Success != tt.success || result.StatusCode != tt.status || result.UploadedBytes != tt.uploaded {
+				t.Errorf("success %v, status %d, uploaded %d; want %v, %d, %d",
+					result.Success, result.StatusCode, result.UploadedBytes, tt.success, tt.status, tt.uploaded)
+			}
+			if tt.response != "" && result.ContentLength != len(tt.response) {
+				t.Errorf("ContentLength = %d, want %d", result.ContentLength, len(tt.response))
+			}
+			if tt.success != (result.Error == nil) {
+				t.Errorf("Error = %v with success %v", result.Error, tt.success)
+			}
+			if result.Protocol != "HTTP/1.1" || result.ResponseTime <= 0 || result.TTFBMs == nil || result.DownloadMs == nil {
+				t.Errorf("protocol %q, response time %v, TTFB %v, download %v", result.Protocol, result.ResponseTime, result.TTFBMs, result.DownloadMs)
+			}
+		})
+	}
+
+	t.Run("connection refused", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		url := "http://" + listener.Addr().String()
+		listener.Close()
+
+		result := makeHTTPRequest(context.Background(), http.DefaultClient, url, "GET", nil, "")
+		if result.Success || result.StatusCode != 0 || result.Error == nil {
+			t.Errorf("success %v, status %d, error %v; want a failed request", result.Success, result.StatusCode, result.Error)
+		}
+	})
+}
+
+func TestRedirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/start" {
+			http.Redirect(w, r, "/end", http.StatusFound)
+			return
+		}
+		io.WriteString(w, "done")
+	}))
+	defer server.Close()
+
+	t.Run("not followed", func(t *testing.T) {
+		results := runSingleURL(t, server.URL+"/start", Parameters{RequestCount: intPtr(1), FollowRedirects: boolPtr(false)})
+		result := results.Requests[0]
+		if result.StatusCode != http.StatusFound || !result.Success || result.Error != nil {
+			t.Errorf("status %d, success %v, error %v; want an unfollowed 302 counted as a success",
+				result.StatusCode, result.Success, result.Error)
+		}
+		if result.RedirectTarget == nil || *result.RedirectTarget != "/end" || result.RedirectCount != 0 {
+			t.Errorf("redirect target %v, count %d; want /end, 0", result.RedirectTarget, result.RedirectCount)
+		}
+	})
+
+	t.Run("followed", func(t *testing.T) {
+		results := runSingleURL(t, server.URL+"/start", Parameters{RequestCount: intPtr(1)})
+		result := results.Requests[0]
+		if result.StatusCode != http.StatusOK || result.RedirectTarget != nil || result.RedirectCount != 1 {
+			t.Errorf("status %d, target %v, count %d; want 200, none, 1", result.StatusCode, result.RedirectTarget, result.RedirectCount)
+		}
+	})
+}
+
+// TestConnectionReuse counts the connections the server accepts for five
+// sequential requests, with and without keep-alive.
+func TestConnectionReuse(t *testing.T) {
+	tests := []struct {
+		name             string
+		disableKeepAlive bool
+		connections      int64
+		reuseRate        float64
+	}{
+		{"keep-alive", false, 1, 80},
+		{"keep-alive disabled", true, 5, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var connections int64
+			server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				io.WriteString(w, "ok")
+			}))
+			server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+				if state == http.StateNew {
+					atomic.AddInt64(&connections, 1)
+				}
+			}
+			server.Start()
+			defer server.Close()
+
+			results := runSingleURL(t, server.URL, Parameters{RequestCount: intPtr(5), DisableKeepAlive: boolPtr(tt.disableKeepAlive)})
+			if got := atomic.LoadInt64(&connections); got != tt.connections {
+				t.Errorf("server accepted %d connections, want %d", got, tt.connections)
+			}
+			if results.ConnectionReuseRate != tt.reuseRate {
+				t.Errorf("ConnectionReuseRate = %v, want %v", results.ConnectionReuseRate, tt.reuseRate)
+			}
+			for i, result := range results.Requests {
+				if wantReused := i > 0 && !tt.disableKeepAlive; result.ConnectionReused != wantReused {
+					t.Errorf("request %d: ConnectionReused = %v, want %v", i, result.ConnectionReused, wantReused)
+				}
+			}
+		})
+	}
+}
+
+// TestSlowStreamedBody sends the headers at once and the body in delayed
+// chunks: the delay shows up in the download time, not the time to first
+// byte.
+func TestSlowStreamedBody(t *testing.T) {
+	const chunks, delay = 4, 50 * time.Millisecond
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		for i := 0; i < chunks; i++ {
+			time.Sleep(delay)
+			io.WriteString(w, strings.Repeat("x", 1000))
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer server.Close()
+
+	result := makeHTTPRequest(context.Background(), server.Client(), server.URL, "GET", nil, "")
+	if !result.Success || result.ContentLength != chunks*1000 {
+		t.Fatalf("success %v, %d bytes; want %d bytes", result.Success, result.ContentLength, chunks*1000)
+	}
+	streaming := float64(chunks*delay) / float64(time.Millisecond)
+	if *result.TTFBMs >= streaming/2 {
+		t.Errorf("TTFB %.1f ms includes the streamed body", *result.TTFBMs)
+	}
+	if *result.DownloadMs < streaming*0.9 {
+		t.Errorf("download %.1f ms, want at least the %.0f ms the body took to stream", *result.DownloadMs, streaming)
+	}
+}
+
+// TestHTTPProtocols checks which protocol the transport settings negotiate
+// with a server offering both HTTP/1.1 and h2 over TLS.
+func TestHTTPProtocols(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Proto)
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		forceHTTP2   bool
+		disableHTTP2 bool
+		want         string
+	}{
+		{"default", false, false, "HTTP/1.1"},
+		{"force_http2", true, false, "HTTP/2.0"},
+		{"disable_http2", false, true, "HTTP/1.1"},
+		{"both set", true, true, "HTTP/1.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := runSingleURL(t, server.URL, Parameters{
+				RequestCount: intPtr(2),
+				ForceHTTP2:   boolPtr(tt.forceHTTP2),
+				DisableHTTP2: boolPtr(tt.disableHTTP2),
+			})
+			if results.Protocols[tt.want] != 2 || len(results.Protocols) != 1 {
+				t.Errorf("protocols %v, want both requests on %s", results.Protocols, tt.want)
+			}
+		})
+	}
+}
+
+func TestResponseSizeHistogram(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		size, _ := strconv.Atoi(r.URL.Query().Get("size"))
+		w.Write(make([]byte, size))
+	}))
+	defer server.Close()
+
+	sizes := []int{0, 1023, 1024, 50 << 10, 2 << 20}
+	params := Parameters{RequestCount: intPtr(1)}
+	total := 0
+	for _, size := range sizes {
+		params.URLs = append(params.URLs, fmt.Sprintf("%s/?size=%d", server.URL, size))
+		total += size
+	}
+	summary := runHTTPBenchmark(context.Background(), params).Summary
+
+	want := []struct {
+		label string
+		count int
+	}{
+		{"0B-1KiB", 2},
+		{"1KiB-10KiB", 1},
+		{"10KiB-100KiB", 1},
+		{"100KiB-1MiB", 0},
+		{"1MiB-10MiB", 1},
+		{">=10MiB", 0},
+	}
+	if len(summary.ResponseSizeHistogram) != len(want) {
+		t.Fatalf("%d buckets, want %d", len(summary.ResponseSizeHistogram), len(want))
+	}
+	for i, bucket := range summary.ResponseSizeHistogram {
+		if bucket.Label != want[i].label || bucket.Count != want[i].count {
+			t.Errorf("bucket %d = %s: %d, want %s: %d", i, bucket.Label, bucket.Count, want[i].label, want[i].count)
+		}
+	}
+	if summary.TotalBytesDownloaded != total {
+		t.Errorf("TotalBytesDownloaded = %d, want %d", summary.TotalBytesDownloaded, total)
+	}
+}
+
+// TestRateLimit paces a fast server at 50 requests/s: the achieved rate
+// must not exceed the limit and should come close to it.
+func TestRateLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	const rps = 50
+	results := runSingleURL(t, server.URL, Parameters{
+		RequestCount:       intPtr(20),
+		ConcurrentRequests: intPtr(4),
+		RateLimitRPS:       intPtr(rps),
+	})
+	if results.TargetRPS != rps || results.TotalRequests != 20 {
+		t.Fatalf("target %d rps, %d requests; want %d, 20", results.TargetRPS, results.TotalRequests, rps)
+	}
+	if results.AchievedRPS > rps*1.05 || results.AchievedRPS < rps*0.7 {
+		t.Errorf("achieved %.1f requests/s at a %d requests/s limit", results.AchievedRPS, rps)
+	}
+
+	config := Config{Parameters: Parameters{URLs: []string{server.URL}, RateLimitRPS: intPtr(maxRateLimitRPS + 1)}}
+	if err := config.Validate(); err == nil {
+		t.Errorf("rate_limit_rps %d accepted above the %d maximum", maxRateLimitRPS+1, maxRateLimitRPS)
+	}
+}
+
+// TestTLSHandshake times the handshake of the first request to a TLS server;
+// the second reuses the connection and has none.
+func TestTLSHandshake(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	results := runSingleURL(t, server.URL, Parameters{RequestCount: intPtr(2)})
+	first, second := results.Requests[0], results.Requests[1]
+	if first.TLSHandshakeMs == nil || *first.TLSHandshakeMs <= 0 {
+		t.Errorf("first request TLSHandshakeMs = %v, want > 0", first.TLSHandshakeMs)
+	}
+	if first.TLSVersion == "" || first.TLSCipherSuite == "" {
+		t.Errorf("TLS version %q, cipher suite %q; want both reported", first.TLSVersion, first.TLSCipherSuite)
+	}
+	if !second.ConnectionReused || second.TLSHandshakeMs != nil {
+		t.Errorf("second request reused %v, handshake %v; want a reused connection without a handshake",
+			second.ConnectionReused, second.TLSHandshakeMs)
+	}
+	if results.AvgTLSHandshakeTime <= 0 {
+		t.Errorf("AvgTLSHandshakeTime = %v, want > 0", results.AvgTLSHandshakeTime)
+	}
+}
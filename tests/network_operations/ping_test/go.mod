@@ -1,3 +1,5 @@
 module ping_test
 
 go 1.19
+
+require golang.org/x/net v0.29.0
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"runtime"
+	"testing"
+)
+
+// pingLoopback pings 127.0.0.1 and skips the test when neither an ICMP
+// socket nor the system ping binary is available.
+func pingLoopback(t *testing.T, count, packetSize int) PingResult {
+	t.Helper()
+	result := pingHost(context.Background(), "127.0.0.1", count, 1000, packetSize)
+	if result.Error != nil && result.Method == "exec" {
+		t.Skipf("cannot ping loopback: %s", *result.Error)
+	}
+	return result
+}
+
+// checkCounts checks that result reports one RTT per received echo and a
+// loss rate that agrees with its packet counts.
+func checkCounts(t *testing.T, result PingResult) {
+	t.Helper()
+	if len(result.RTTs) != result.PacketsReceived {
+		t.Errorf("%d RTTs for %d packets received", len(result.RTTs), result.PacketsReceived)
+	}
+	if result.PacketsSent > 0 {
+		want := float64(result.PacketsSent-result.PacketsReceived) / float64(result.PacketsSent) * 100
+		if math.Abs(result.PacketLoss-want) > 1e-9 {
+			t.Errorf("PacketLoss = %v with %d sent and %d received, want %v",
+				result.PacketLoss, result.PacketsSent, result.PacketsReceived, want)
+		}
+	}
+}
+
+func TestPingLoopback(t *testing.T) {
+	const count = 3
+	result := pingLoopback(t, count, defaultPacketSize)
+	if result.Error != nil {
+		t.Fatalf("loopback ping failed: %s", *result.Error)
+	}
+	if result.PacketsSent != count || result.PacketsReceived != count || result.PacketLoss != 0 {
+		t.Errorf("sent %d, received %d, loss %v%%; want %d, %d, 0%%",
+			result.PacketsSent, result.PacketsReceived, result.PacketLoss, count, count)
+	}
+	if result.MinLatency <= 0 || result.MinLatency > result.AvgLatency || result.AvgLatency > result.MaxLatency {
+		t.Errorf("latencies min %v, avg %v, max %v are not ordered and positive",
+			result.MinLatency, result.AvgLatency, result.MaxLatency)
+	}
+	if result.MaxLatency >= 1000 {
+		t.Errorf("loopback RTT %v ms reached the timeout", result.MaxLatency)
+	}
+	checkCounts(t, result)
+}
+
+func TestJitter(t *testing.T) {
+	tests := []struct {
+		name               string
+		rtts               []float64
+		stdDev, meanAbsDev float64
+	}{
+		{"empty", nil, 0, 0},
+		{"single", []float64{5}, 0, 0},
+		{"constant", []float64{2, 2, 2}, 0, 0},
+		// Mean 5: deviations 3, 1, 1, 1, 0, 0, 2, 4.
+		{"known sequence", []float64{2, 4, 4, 4, 5, 5, 7, 9}, 2, 1.5},
+		{"alternating", []float64{1, 3, 1, 3}, 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdDev, meanAbsDev := jitter(tt.rtts)
+			if math.Abs(stdDev-tt.stdDev) > 1e-9 || math.Abs(meanAbsDev-tt.meanAbsDev) > 1e-9 {
+				t.Errorf("jitter(%v) = %v, %v, want %v, %v", tt.rtts, stdDev, meanAbsDev, tt.stdDev, tt.meanAbsDev)
+			}
+		})
+	}
+}
+
+// TestWorkerBound pings 100 loopback addresses with 4 workers; every target
+// gets a result and no more than 4 are pinged at once.
+func TestWorkerBound(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("only Linux routes all of 127.0.0.0/8 to loopback")
+	}
+	pingLoopback(t, 1, defaultPacketSize)
+
+	const targets, workers = 100, 4
+	params := Parameters{
+		PacketCount:       intPtr(1),
+		Timeout:           intPtr(1000),
+		ConcurrentWorkers: intPtr(workers),
+	}
+	for i := 1; i <= targets; i++ {
+		params.Targets = append(params.Targets, fmt.Sprintf("127.0.0.%d", i))
+	}
+
+	results := runPingBenchmark(context.Background(), params)
+	if len(results.Targets) != targets || results.Summary.TotalTargets != targets {
+		t.Errorf("got %d results for %d targets, want %d", len(results.Targets), results.Summary.TotalTargets, targets)
+	}
+	if c := results.Summary.AchievedConcurrency; c < 1 || c > workers {
+		t.Errorf("AchievedConcurrency = %d, want 1..%d", c, workers)
+	}
+	for target, result := range results.Targets {
+		if result.Error != nil {
+			t.Errorf("%s: %s", target, *result.Error)
+		}
+		checkCounts(t, result)
+	}
+}
+
+// TestPacketSizes pings loopback with payloads from empty to larger than an
+// Ethernet MTU. Loopback's MTU (65536 on Linux) leaves all of them
+// unfragmented.
+func TestPacketSizes(t *testing.T) {
+	for _, size := range []int{0, 8, defaultPacketSize, 1472, 1473, 8192} {
+		t.Run(fmt.Sprintf("size=%d", size), func(t *testing.T) {
+			result := pingLoopback(t, 2, size)
+			if result.Error != nil {
+				t.Fatalf("loopback ping failed: %s", *result.Error)
+			}
+			if result.PacketSize != size {
+				t.Errorf("PacketSize = %d, want %d", result.PacketSize, size)
+			}
+			if result.PacketsReceived != 2 {
+				t.Errorf("received %d of 2 echoes", result.PacketsReceived)
+			}
+			checkCounts(t, result)
+			if result.Fragmented != nil && result.InterfaceMTU >= size+echoOverhead && *result.Fragmented {
+				t.Errorf("%d-byte payload reported fragmented on a %d-byte MTU", size, result.InterfaceMTU)
+			}
+		})
+	}
+
+	for _, size := range []int{0, 1, 13, 14, 15, 1000} {
+		if payload := echoPayload(size); len(payload) != size {
+			t.Errorf("echoPayload(%d) has %d bytes", size, len(payload))
+		}
+	}
+}
+
+func TestParsePingOutput(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the sample is Linux ping output")
+	}
+	output := `PING 10.0.0.1 (10.0.0.1) 56(84) bytes of data.
+64 bytes from 10.0.0.1: icmp_seq=1 ttl=64 time=1.20 ms
+64 bytes from 10.0.0.1: icmp_seq=3 ttl=64 time=1.60 ms
+
+--- 10.0.0.1 ping statistics ---
+4 packets transmitted, 2 received, 50% packet loss, time 3004ms
+rtt min/avg/max/mdev = 1.200/1.400/1.600/0.200 ms
+`
+	result := parsePingOutput(output)
+	if result.PacketsSent != 4 || result.PacketsReceived != 2 || result.PacketLoss != 50 {
+		t.Errorf("sent %d, received %d, loss %v%%; want 4, 2, 50%%", result.PacketsSent, result.PacketsReceived, result.PacketLoss)
+	}
+	if result.MinLatency != 1.2 || result.AvgLatency != 1.4 || result.MaxLatency != 1.6 {
+		t.Errorf("latencies %v/%v/%v, want 1.2/1.4/1.6", result.MinLatency, result.AvgLatency, result.MaxLatency)
+	}
+	checkCounts(t, result)
+}
+
+func intPtr(v int) *int {
+	return &v
+}
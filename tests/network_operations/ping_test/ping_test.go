@@ -1,44 +1,97 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
+	"math"
+	"net"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
 	"runtime"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"benchmark_test/internal/cli"
+	"benchmark_test/internal/stats"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
 )
 
 type Config struct {
 	Parameters Parameters `json:"parameters"`
+	Name       string     `json:"name"`
 }
 
 type Parameters struct {
-	Targets     []string `json:"targets"`
-	PacketCount *int     `json:"packet_count,omitempty"`
-	Timeout     *int     `json:"timeout,omitempty"`
+	Targets           []string `json:"targets"`
+	PacketCount       *int     `json:"packet_count,omitempty"`
+	Timeout           *int     `json:"timeout,omitempty"`
+	ConcurrentWorkers *int     `json:"concurrent_workers,omitempty"`
+	WarmupIterations  *int     `json:"warmup_iterations,omitempty"`
+	DeadlineSeconds   *int     `json:"deadline_seconds,omitempty"`
+	PacketSize        *int     `json:"packet_size,omitempty"`
+	GOMAXPROCS        *int     `json:"gomaxprocs,omitempty"`
+	Tags              []string `json:"tags"`
+}
+
+// Validate checks the parameters that are set; unset ones take the defaults
+// in runPingBenchmark.
+func (c *Config) Validate() error {
+	p := c.Parameters
+	err := cli.FirstError(
+		cli.Required("targets", len(p.Targets)),
+		cli.OptionalPositive("packet_count", p.PacketCount),
+		cli.OptionalPositive("timeout", p.Timeout),
+		cli.OptionalNonNegative("concurrent_workers", p.ConcurrentWorkers),
+		cli.OptionalNonNegative("warmup_iterations", p.WarmupIterations),
+		cli.OptionalNonNegative("deadline_seconds", p.DeadlineSeconds),
+		cli.OptionalPositive("gomaxprocs", p.GOMAXPROCS),
+	)
+	if err == nil && p.PacketSize != nil {
+		err = cli.InRange("packet_size", *p.PacketSize, 0, maxPacketSize)
+	}
+	return err
 }
 
 type PingResult struct {
-	AvgLatency    float64 `json:"avg_latency"`
-	MinLatency    float64 `json:"min_latency"`
-	MaxLatency    float64 `json:"max_latency"`
-	PacketLoss    float64 `json:"packet_loss"`
-	ExecutionTime float64 `json:"execution_time"`
-	Error         *string `json:"error,omitempty"`
+	Method          string    `json:"method"`
+	AvgLatency      float64   `json:"avg_latency"`
+	MinLatency      float64   `json:"min_latency"`
+	MaxLatency      float64   `json:"max_latency"`
+	PacketLoss      float64   `json:"packet_loss"`
+	PacketsSent     int       `json:"packets_sent"`
+	PacketsReceived int       `json:"packets_received"`
+	SendErrors      int       `json:"send_errors,omitempty"`
+	RTTs            []float64 `json:"rtts"`
+	Jitter          float64   `json:"jitter"`
+	MeanAbsDev      float64   `json:"mean_abs_deviation"`
+	ExecutionTime   float64   `json:"execution_time"`
+	PacketSize      int       `json:"packet_size"`
+	InterfaceMTU    int       `json:"interface_mtu,omitempty"`
+	Fragmented      *bool     `json:"fragmented,omitempty"`
+	Error           *string   `json:"error,omitempty"`
 }
 
 type Summary struct {
-	TotalTargets      int     `json:"total_targets"`
-	SuccessfulTargets int     `json:"successful_targets"`
-	FailedTargets     int     `json:"failed_targets"`
-	OverallAvgLatency float64 `json:"overall_avg_latency"`
+	TotalTargets        int     `json:"total_targets"`
+	SuccessfulTargets   int     `json:"successful_targets"`
+	FailedTargets       int     `json:"failed_targets"`
+	OverallAvgLatency   float64 `json:"overall_avg_latency"`
+	AchievedConcurrency int     `json:"achieved_concurrency"`
+	WarmupIterations    int     `json:"warmup_iterations"`
+	TimedOut            bool    `json:"timed_out"`
 }
 
 type Results struct {
+	SchemaVersion      string                `json:"schema_version"`
+	Environment        cli.Environment       `json:"environment"`
+	Name               string                `json:"name,omitempty"`
+	Tags               []string              `json:"tags,omitempty"`
 	StartTime          float64               `json:"start_time"`
 	Targets            map[string]PingResult `json:"targets"`
 	Summary            Summary               `json:"summary"`
@@ -46,15 +99,267 @@ type Results struct {
 	TotalExecutionTime float64               `json:"total_execution_time"`
 }
 
-func pingHost(host string, count int, timeout int) PingResult {
+// protocolICMP is the IANA protocol number for ICMP over IPv4.
+const protocolICMP = 1
+
+// defaultPacketSize is the echo payload size in bytes, the same as the
+// system ping's default.
+const defaultPacketSize = 56
+
+// maxPacketSize is the largest payload that fits in one IPv4 datagram.
+const maxPacketSize = 65507
+
+// echoOverhead is the IPv4 and ICMP header bytes added to each payload.
+const echoOverhead = 20 + 8
+
+// echoPayload returns size bytes of a repeating marker pattern.
+func echoPayload(size int) []byte {
+	const pattern = "polyglot-bench"
+	payload := make([]byte, size)
+	for i := range payload {
+		payload[i] = pattern[i%len(pattern)]
+	}
+	return payload
+}
+
+// outgoingMTU returns the MTU of the interface the kernel routes host through,
+// or 0 when it cannot be determined. Connecting a UDP socket only selects the
+// route; nothing is sent. Traffic to one of our own addresses never leaves
+// the machine, so it is matched to the loopback interface.
+func outgoingMTU(host string) int {
+	conn, err := net.Dial("udp4", net.JoinHostPort(host, "9"))
+	if err != nil {
+		return 0
+	}
+	local := conn.LocalAddr().(*net.UDPAddr).IP
+	remote := conn.RemoteAddr().(*net.UDPAddr).IP
+	conn.Close()
+
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return 0
+	}
+	for _, iface := range interfaces {
+		if local.Equal(remote) {
+			if iface.Flags&net.FlagLoopback != 0 {
+				return iface.MTU
+			}
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(local) {
+				return iface.MTU
+			}
+		}
+	}
+	return 0
+}
+
+// listenICMP opens a native ICMP socket, preferring a raw socket and falling
+// back to an unprivileged datagram socket where the kernel allows it.
+func listenICMP() (*icmp.PacketConn, bool, error) {
+	if conn, err := icmp.ListenPacket("ip4:icmp", "0.0.0.0"); err == nil {
+		return conn, true, nil
+	}
+	conn, err := icmp.ListenPacket("udp4", "0.0.0.0")
+	if err != nil {
+		return nil, false, err
+	}
+	return conn, false, nil
+}
+
+// addrIP extracts the IP address from a raw or datagram ICMP peer address.
+func addrIP(addr net.Addr) net.IP {
+	switch a := addr.(type) {
+	case *net.IPAddr:
+		return a.IP
+	case *net.UDPAddr:
+		return a.IP
+	default:
+		return nil
+	}
+}
+
+// pingHostICMP sends echo requests directly over an ICMP socket and times the
+// replies, avoiding any dependence on the ping binary or its output language.
+// It returns an error when no ICMP socket can be opened. Once ctx is done no
+// further echoes are sent.
+func pingHostICMP(ctx context.Context, host string, count int, timeout int, packetSize int) (PingResult, error) {
+	start := time.Now()
+
+	ipAddr, err := net.ResolveIPAddr("ip4", host)
+	if err != nil {
+		return PingResult{}, err
+	}
+
+	conn, privileged, err := listenICMP()
+	if err != nil {
+		return PingResult{}, err
+	}
+	defer conn.Close()
+
+	// Datagram sockets address peers by UDP address and let the kernel
+	// assign the echo identifier; raw sockets need the IP address.
+	var dst net.Addr = ipAddr
+	if !privileged {
+		dst = &net.UDPAddr{IP: ipAddr.IP}
+	}
+	id := os.Getpid() & 0xffff
+
+	var rtts []float64
+	sentPackets := 0
+	sendErrors := 0
+	payload := echoPayload(packetSize)
+	readBuf := make([]byte, packetSize+echoOverhead+1500)
+
+	for seq := 1; seq <= count && ctx.Err() == nil; seq++ {
+		msg := icmp.Message{
+			Type: ipv4.ICMPTypeEcho,
+			Code: 0,
+			Body: &icmp.Echo{ID: id, Seq: seq, Data: payload},
+		}
+		packet, err := msg.Marshal(nil)
+		if err != nil {
+			return PingResult{}, err
+		}
+
+		sent := time.Now()
+		if _, err := conn.WriteTo(packet, dst); err != nil {
+			sendErrors++
+			continue
+		}
+		sentPackets++
+
+		deadline := sent.Add(time.Duration(timeout) * time.Millisecond)
+		if runDeadline, ok := ctx.Deadline(); ok && runDeadline.Before(deadline) {
+			deadline = runDeadline
+		}
+		if err := conn.SetReadDeadline(deadline); err != nil {
+			return PingResult{}, err
+		}
+
+		for {
+			n, peer, err := conn.ReadFrom(readBuf)
+			if err != nil {
+				break // timed out: packet lost
+			}
+			if !addrIP(peer).Equal(ipAddr.IP) {
+				continue // reply to a concurrent ping of another target
+			}
+			reply, err := icmp.ParseMessage(protocolICMP, readBuf[:n])
+			if err != nil || reply.Type != ipv4.ICMPTypeEchoReply {
+				continue
+			}
+			echo, ok := reply.Body.(*icmp.Echo)
+			if !ok || echo.Seq != seq || (privileged && echo.ID != id) {
+				continue
+			}
+			if len(echo.Data) != len(payload) {
+				continue // truncated on the way: not a full round trip
+			}
+			rtts = append(rtts, float64(time.Since(sent).Nanoseconds())/1e6)
+			break
+		}
+	}
+
+	// Echoes whose write failed never left the host, so they count as send
+	// errors rather than as lost packets.
+	packetLoss := 100.0
+	if sentPackets > 0 {
+		packetLoss = float64(sentPackets-len(rtts)) / float64(sentPackets) * 100.0
+	}
+
+	result := PingResult{
+		Method:          "icmp",
+		PacketLoss:      packetLoss,
+		PacketsSent:     sentPackets,
+		PacketsReceived: len(rtts),
+		SendErrors:      sendErrors,
+		RTTs:            rtts,
+		ExecutionTime:   time.Since(start).Seconds(),
+	}
+
+	if len(rtts) == 0 {
+		errMsg := "No echo replies received"
+		result.Error = &errMsg
+		return result, nil
+	}
+
+	result.MinLatency = rtts[0]
+	result.MaxLatency = rtts[0]
+	sum := 0.0
+	for _, rtt := range rtts {
+		if rtt < result.MinLatency {
+			result.MinLatency = rtt
+		}
+		if rtt > result.MaxLatency {
+			result.MaxLatency = rtt
+		}
+		sum += rtt
+	}
+	result.AvgLatency = sum / float64(len(rtts))
+
+	return result, nil
+}
+
+// jitter returns the population standard deviation of the per-packet RTTs and
+// their mean absolute deviation from the mean.
+func jitter(rtts []float64) (stdDev float64, meanAbsDev float64) {
+	if len(rtts) == 0 {
+		return 0.0, 0.0
+	}
+
+	mean := stats.Mean(rtts)
+	for _, rtt := range rtts {
+		meanAbsDev += math.Abs(rtt - mean)
+	}
+
+	return stats.StdDev(rtts), meanAbsDev / float64(len(rtts))
+}
+
+// pingHost pings host natively over ICMP, falling back to the system ping
+// binary when ICMP sockets are not permitted (e.g. without root).
+//
+// Fragmentation is judged locally: an echo request larger than the MTU of
+// the outgoing interface is sent in fragments. Fragmentation further along
+// the path is not visible to the sender.
+func pingHost(ctx context.Context, host string, count int, timeout int, packetSize int) PingResult {
+	var result PingResult
+	var err error
+	if count > 0 {
+		result, err = pingHostICMP(ctx, host, count, timeout, packetSize)
+	}
+	if count <= 0 || err != nil {
+		result = pingHostExec(ctx, host, count, timeout, packetSize)
+	}
+
+	if result.RTTs == nil {
+		result.RTTs = []float64{}
+	}
+	result.Jitter, result.MeanAbsDev = jitter(result.RTTs)
+
+	result.PacketSize = packetSize
+	if mtu := outgoingMTU(host); mtu > 0 {
+		fragmented := packetSize+echoOverhead > mtu
+		result.InterfaceMTU = mtu
+		result.Fragmented = &fragmented
+	}
+	return result
+}
+
+func pingHostExec(ctx context.Context, host string, count int, timeout int, packetSize int) PingResult {
 	start := time.Now()
 
 	var cmd *exec.Cmd
 	if runtime.GOOS == "windows" {
-		cmd = exec.Command("ping", "-n", strconv.Itoa(count), "-w", strconv.Itoa(timeout), host)
+		cmd = exec.CommandContext(ctx, "ping", "-n", strconv.Itoa(count), "-w", strconv.Itoa(timeout), "-l", strconv.Itoa(packetSize), host)
 	} else {
 		timeoutSec := timeout / 1000
-		cmd = exec.Command("ping", "-c", strconv.Itoa(count), "-W", strconv.Itoa(timeoutSec), host)
+		cmd = exec.CommandContext(ctx, "ping", "-c", strconv.Itoa(count), "-W", strconv.Itoa(timeoutSec), "-s", strconv.Itoa(packetSize), host)
 	}
 
 	output, err := cmd.CombinedOutput()
@@ -63,6 +368,7 @@ func pingHost(host string, count int, timeout int) PingResult {
 	if err != nil {
 		errMsg := err.Error()
 		return PingResult{
+			Method:        "exec",
 			AvgLatency:    float64(^uint(0) >> 1), // Max float64
 			MinLatency:    float64(^uint(0) >> 1),
 			MaxLatency:    float64(^uint(0) >> 1),
@@ -73,6 +379,7 @@ func pingHost(host string, count int, timeout int) PingResult {
 	}
 
 	result := parsePingOutput(string(output))
+	result.Method = "exec"
 	result.ExecutionTime = executionTime
 	return result
 }
@@ -105,6 +412,14 @@ func parsePingOutput(output string) PingResult {
 				times = append(times, time)
 			}
 		}
+		result.RTTs = times
+
+		// Packet counts: "Sent = 4, Received = 4" / "Envoyés = 4, Reçus = 4"
+		countsRegex := regexp.MustCompile(`(?:Sent|Envoyés) = (\d+), (?:Received|Reçus) = (\d+)`)
+		if matches := countsRegex.FindStringSubmatch(output); matches != nil {
+			result.PacketsSent, _ = strconv.Atoi(matches[1])
+			result.PacketsReceived, _ = strconv.Atoi(matches[2])
+		}
 
 		if len(times) > 0 {
 			result.MinLatency = times[0]
@@ -154,6 +469,21 @@ func parsePingOutput(output string) PingResult {
 			}
 		}
 
+		// Extract individual ping times
+		timeRegex := regexp.MustCompile(`time=([\d.]+) ms`)
+		for _, match := range timeRegex.FindAllStringSubmatch(output, -1) {
+			if rtt, err := strconv.ParseFloat(match[1], 64); err == nil {
+				result.RTTs = append(result.RTTs, rtt)
+			}
+		}
+
+		// Packet counts: "3 packets transmitted, 3 received"
+		countsRegex := regexp.MustCompile(`(\d+) packets transmitted, (\d+) (?:packets )?received`)
+		if matches := countsRegex.FindStringSubmatch(output); matches != nil {
+			result.PacketsSent, _ = strconv.Atoi(matches[1])
+			result.PacketsReceived, _ = strconv.Atoi(matches[2])
+		}
+
 		// Parse rtt statistics
 		rttRegex := regexp.MustCompile(`rtt min/avg/max/mdev = ([\d.]+)/([\d.]+)/([\d.]+)/([\d.]+) ms`)
 		if matches := rttRegex.FindStringSubmatch(output); matches != nil {
@@ -169,6 +499,11 @@ func parsePingOutput(output string) PingResult {
 		}
 	}
 
+	// Prefer explicit packet counts over the rounded loss percentage
+	if result.PacketsSent > 0 {
+		result.PacketLoss = float64(result.PacketsSent-result.PacketsReceived) / float64(result.PacketsSent) * 100.0
+	}
+
 	// If no valid latency was parsed, mark as error
 	if result.AvgLatency == 0.0 && result.PacketLoss == 100.0 {
 		errMsg := "Failed to parse ping output"
@@ -178,7 +513,13 @@ func parsePingOutput(output string) PingResult {
 	return result
 }
 
-func runPingBenchmark(params Parameters) Results {
+func runPingBenchmark(ctx context.Context, params Parameters) Results {
+	// Targets are pinged by concurrent_workers goroutines, which GOMAXPROCS
+	// spreads over that many threads at most.
+	if params.GOMAXPROCS != nil {
+		defer cli.SetGOMAXPROCS(*params.GOMAXPROCS)()
+	}
+
 	startTime := float64(time.Now().UnixNano()) / 1e9
 
 	packetCount := 3 // Reduced for better performance
@@ -191,6 +532,21 @@ func runPingBenchmark(params Parameters) Results {
 		timeout = *params.Timeout
 	}
 
+	concurrentWorkers := runtime.NumCPU()
+	if params.ConcurrentWorkers != nil && *params.ConcurrentWorkers > 0 {
+		concurrentWorkers = *params.ConcurrentWorkers
+	}
+
+	warmupIterations := 0
+	if params.WarmupIterations != nil {
+		warmupIterations = *params.WarmupIterations
+	}
+
+	packetSize := defaultPacketSize
+	if params.PacketSize != nil {
+		packetSize = *params.PacketSize
+	}
+
 	targets := make(map[string]PingResult)
 	successfulTargets := 0
 	failedTargets := 0
@@ -203,14 +559,46 @@ func runPingBenchmark(params Parameters) Results {
 		target string
 		result PingResult
 	}, len(params.Targets))
+	semaphore := make(chan struct{}, concurrentWorkers)
+	var inFlight, maxInFlight int64
 
-	// Execute pings concurrently for better performance
+	// Execute pings concurrently, bounded by the worker count so large target
+	// lists don't exhaust sockets or spawn unbounded ping processes. Targets
+	// still waiting for a worker when the deadline passes are not pinged.
 	for _, target := range params.Targets {
+		select {
+		case semaphore <- struct{}{}: // acquire
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
 		wg.Add(1)
 		go func(t string) {
 			defer wg.Done()
+
+			current := atomic.AddInt64(&inFlight, 1)
+			for {
+				observed := atomic.LoadInt64(&maxInFlight)
+				if current <= observed || atomic.CompareAndSwapInt64(&maxInFlight, observed, current) {
+					break
+				}
+			}
+
+			// Warmup echoes resolve the host and prime ARP/neighbor caches
+			// so the first measured packet isn't an outlier
+			if warmupIterations > 0 {
+				fmt.Fprintf(os.Stderr, "Warming up %s (%d packets)...\n", t, warmupIterations)
+				pingHost(ctx, t, warmupIterations, timeout, packetSize)
+			}
+
 			fmt.Fprintf(os.Stderr, "Pinging %s...\n", t)
-			pingResult := pingHost(t, packetCount, timeout)
+			pingResult := pingHost(ctx, t, packetCount, timeout, packetSize)
+
+			atomic.AddInt64(&inFlight, -1)
+			<-semaphore // release
+
 			resultsChan <- struct {
 				target string
 				result PingResult
@@ -247,13 +635,18 @@ func runPingBenchmark(params Parameters) Results {
 	endTime := float64(time.Now().UnixNano()) / 1e9
 
 	return Results{
-		StartTime: startTime,
-		Targets:   targets,
+		SchemaVersion: cli.SchemaVersion,
+		Environment:   cli.CurrentEnvironment(),
+		StartTime:     startTime,
+		Targets:       targets,
 		Summary: Summary{
-			TotalTargets:      len(params.Targets),
-			SuccessfulTargets: successfulTargets,
-			FailedTargets:     failedTargets,
-			OverallAvgLatency: overallAvgLatency,
+			TotalTargets:        len(params.Targets),
+			SuccessfulTargets:   successfulTargets,
+			FailedTargets:       failedTargets,
+			OverallAvgLatency:   overallAvgLatency,
+			AchievedConcurrency: int(atomic.LoadInt64(&maxInFlight)),
+			WarmupIterations:    warmupIterations,
+			TimedOut:            cli.TimedOut(ctx),
 		},
 		EndTime:            endTime,
 		TotalExecutionTime: endTime - startTime,
@@ -261,32 +654,32 @@ func runPingBenchmark(params Parameters) Results {
 }
 
 func main() {
-	if len(os.Args) < 2 {
-		fmt.Fprintf(os.Stderr, "Usage: %s <config_file>\n", os.Args[0])
-		os.Exit(1)
-	}
-
-	configFile := os.Args[1]
-
-	configData, err := os.ReadFile(configFile)
+	opts, err := cli.Parse(filepath.Base(os.Args[0]), os.Args[1:])
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error reading config file: %v\n", err)
-		os.Exit(1)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
 	}
+	opts.IterationsKey = "packet_count"
 
 	var config Config
-	if err := json.Unmarshal(configData, &config); err != nil {
-		fmt.Fprintf(os.Stderr, "Error parsing config JSON: %v\n", err)
+	if err := opts.LoadConfig(&config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	results := runPingBenchmark(config.Parameters)
+	deadline := 0
+	if config.Parameters.DeadlineSeconds != nil {
+		deadline = *config.Parameters.DeadlineSeconds
+	}
+	ctx, cancel := cli.Deadline(deadline)
+	defer cancel()
 
-	output, err := json.MarshalIndent(results, "", "  ")
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error marshaling results: %v\n", err)
+	results := runPingBenchmark(ctx, config.Parameters)
+	results.Name = config.Name
+	results.Tags = config.Parameters.Tags
+
+	if err := opts.WriteResults(results); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
-
-	fmt.Println(string(output))
 }
@@ -1,17 +1,22 @@
 package main
 
 import (
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"runtime"
+	"strings"
 	"time"
+	"unsafe"
+
+	"benchmark_test/internal/cli"
+	"benchmark_test/internal/stats"
 )
 
 type Config struct {
 	Parameters Parameters `json:"parameters"`
+	Name       string     `json:"name"`
 }
 
 type Parameters struct {
@@ -20,10 +25,31 @@ type Parameters struct {
 	AllocationCounts    []int    `json:"allocation_counts"`
 	DataStructures      []string `json:"data_structures"`
 	Iterations          int      `json:"iterations"`
+	Seed                *int64   `json:"seed,omitempty"`
+	Tags                []string `json:"tags"`
+}
+
+// Validate checks the parameters; none of them has a default.
+func (c *Config) Validate() error {
+	p := c.Parameters
+	return cli.FirstError(
+		cli.Required("allocation_sizes", len(p.AllocationSizes)),
+		cli.EachPositive("allocation_sizes", p.AllocationSizes),
+		cli.Required("allocation_counts", len(p.AllocationCounts)),
+		cli.EachPositive("allocation_counts", p.AllocationCounts),
+		cli.Required("data_structures", len(p.DataStructures)),
+		cli.Required("allocation_patterns", len(p.AllocationPatterns)),
+		cli.Positive("iterations", p.Iterations),
+	)
 }
 
 type Results struct {
+	SchemaVersion       string      `json:"schema_version"`
+	Environment         cli.Environment `json:"environment"`
+	Name                string      `json:"name,omitempty"`
+	Tags                []string    `json:"tags,omitempty"`
 	StartTime           float64     `json:"start_time"`
+	Seed                int64       `json:"seed"`
 	TestCases           []TestCase  `json:"test_cases"`
 	Summary             Summary     `json:"summary"`
 	EndTime             float64     `json:"end_time"`
@@ -39,6 +65,7 @@ type TestCase struct {
 	AvgAllocationTime    float64          `json:"avg_allocation_time"`
 	AvgDeallocationTime  float64          `json:"avg_deallocation_time"`
 	AvgMemoryEfficiency  float64          `json:"avg_memory_efficiency"`
+	AvgAllocationsPerSecond float64       `json:"avg_allocations_per_second"`
 }
 
 type IterationResult struct {
@@ -54,7 +81,16 @@ type AllocationResult struct {
 	MemoryUsed       int     `json:"memory_used"`
 	PeakMemory       int     `json:"peak_memory"`
 	MemoryEfficiency float64 `json:"memory_efficiency"`
+	// TheoreticalSize is the footprint of the allocated data with no
+	// allocator or runtime slack, in bytes (see theoreticalFootprint).
+	// MemoryEfficiency is TheoreticalSize as a percentage of MemoryUsed.
+	TheoreticalSize  int     `json:"theoretical_size"`
 	ItemsAllocated   int     `json:"items_allocated"`
+	// TotalMallocs is the number of heap objects the runtime allocated during
+	// the phase (MemStats.Mallocs delta), including backing arrays abandoned
+	// by growth and intermediate strings.
+	TotalMallocs         uint64  `json:"total_mallocs"`
+	AllocationsPerSecond float64 `json:"allocations_per_second"`
 	Error            *string `json:"error,omitempty"`
 }
 
@@ -63,6 +99,9 @@ type DeallocationResult struct {
 	TimeMs       float64 `json:"time_ms"`
 	FinalMemory  int     `json:"final_memory"`
 	MemoryFreed  int     `json:"memory_freed"`
+	GCPauseMs    float64 `json:"gc_pause_ms"`
+	MaxGCPauseMs float64 `json:"max_gc_pause_ms"`
+	NumGC        int     `json:"num_gc"`
 	Error        *string `json:"error,omitempty"`
 }
 
@@ -73,22 +112,54 @@ type Summary struct {
 	AvgAllocationTime      float64 `json:"avg_allocation_time"`
 	AvgDeallocationTime    float64 `json:"avg_deallocation_time"`
 	AvgMemoryEfficiency    float64 `json:"avg_memory_efficiency"`
+	TotalGCPauseMs         float64 `json:"total_gc_pause_ms"`
+	MaxGCPauseMs           float64 `json:"max_gc_pause_ms"`
+	TotalMallocs           uint64  `json:"total_mallocs"`
+	AvgAllocationsPerSecond float64 `json:"avg_allocations_per_second"`
+	// GCPauseMsPerMillionMallocs relates the two: total pause time divided by
+	// the heap objects allocated across all iterations.
+	GCPauseMsPerMillionMallocs float64 `json:"gc_pause_ms_per_million_mallocs"`
 }
 
 // Memory tracking
+//
+// A collection is forced before reading so that HeapAlloc reflects live heap
+// objects only; callers must keep the measured data reachable (see
+// runtime.KeepAlive) for it to be counted.
 func getMemoryUsage() int {
+	runtime.GC()
 	var m runtime.MemStats
 	runtime.ReadMemStats(&m)
-	return int(m.Sys)
+	return int(m.HeapAlloc)
+}
+
+// gcPauses returns the total and longest stop-the-world pause, in
+// milliseconds, and the number of collections that completed between two
+// MemStats readings. PauseNs is a circular buffer of the last 256 pauses, so
+// older pauses are dropped when more collections than that have run.
+func gcPauses(before, after *runtime.MemStats) (float64, float64, int) {
+	numGC := int(after.NumGC - before.NumGC)
+	maxPause := uint64(0)
+	for i := 0; i < numGC && i < len(after.PauseNs); i++ {
+		pause := after.PauseNs[(int(after.NumGC)-1-i+len(after.PauseNs))%len(after.PauseNs)]
+		if pause > maxPause {
+			maxPause = pause
+		}
+	}
+	totalMs := float64(after.PauseTotalNs-before.PauseTotalNs) / 1e6
+	return totalMs, float64(maxPause) / 1e6, numGC
 }
 
+// rng fills the allocated structures; seeded per run from parameters.seed.
+var rng *rand.Rand
+
 func allocateArrays(size, count int) [][]int {
 	arrays := make([][]int, 0, count)
 	
 	for i := 0; i < count; i++ {
 		array := make([]int, size)
 		for j := 0; j < size; j++ {
-			array[j] = rand.Intn(1000)
+			array[j] = rng.Intn(1000)
 		}
 		arrays = append(arrays, array)
 	}
@@ -102,8 +173,8 @@ func allocateHashMaps(size, count int) []map[int]int {
 	for i := 0; i < count; i++ {
 		hashMap := make(map[int]int)
 		for j := 0; j < size; j++ {
-			key := rand.Intn(size * 2)
-			value := rand.Intn(1000)
+			key := rng.Intn(size * 2)
+			value := rng.Intn(1000)
 			hashMap[key] = value
 		}
 		maps = append(maps, hashMap)
@@ -124,7 +195,7 @@ func allocateLinkedLists(size, count int) []*ListNode {
 		var head *ListNode
 		for j := 0; j < size; j++ {
 			newNode := &ListNode{
-				Value: rand.Intn(1000),
+				Value: rng.Intn(1000),
 				Next:  head,
 			}
 			head = newNode
@@ -135,7 +206,147 @@ func allocateLinkedLists(size, count int) []*ListNode {
 	return lists
 }
 
+// allocateGrowingSlices builds each slice by appending without
+// preallocating, so the runtime repeatedly grows and copies the backing array.
+func allocateGrowingSlices(size, count int) [][]int {
+	slices := make([][]int, 0, count)
+	
+	for i := 0; i < count; i++ {
+		var slice []int
+		for j := 0; j < size; j++ {
+			slice = append(slice, rng.Intn(1000))
+		}
+		slices = append(slices, slice)
+	}
+	
+	return slices
+}
+
+// stringChunk is the unit appended at each step by the string benchmarks.
+const stringChunk = "0123456789abcdef"
+
+func buildStrings(size, count int) []string {
+	result := make([]string, 0, count)
+	
+	for i := 0; i < count; i++ {
+		var builder strings.Builder
+		for builder.Len() < size {
+			builder.WriteString(stringChunk)
+		}
+		result = append(result, builder.String()[:size])
+	}
+	
+	return result
+}
+
+// concatStrings produces the same strings as buildStrings with repeated
+// concatenation, which allocates a new string at every step.
+func concatStrings(size, count int) []string {
+	result := make([]string, 0, count)
+	
+	for i := 0; i < count; i++ {
+		str := ""
+		for len(str) < size {
+			str += stringChunk
+		}
+		result = append(result, str[:size])
+	}
+	
+	return result
+}
+
+type Record struct {
+	ID        int64
+	Value     float64
+	Timestamp int64
+	Flags     uint32
+	Active    bool
+}
+
+func allocateRecords(size, count int) [][]Record {
+	records := make([][]Record, 0, count)
+	
+	for i := 0; i < count; i++ {
+		batch := make([]Record, size)
+		for j := 0; j < size; j++ {
+			batch[j] = Record{
+				ID:        int64(j),
+				Value:     rng.Float64(),
+				Timestamp: int64(rng.Intn(1000)),
+				Flags:     uint32(rng.Intn(16)),
+				Active:    j%2 == 0,
+			}
+		}
+		records = append(records, batch)
+	}
+	
+	return records
+}
+
+// mapLoadFactor approximates the fill at which a Go map grows: 6.5 entries
+// per 8-slot bucket for the bucket-based maps, 7/8 for the Swiss tables.
+const mapLoadFactor = 0.8
+
+// mapHeaderSize is the size of the runtime map header on 64-bit platforms,
+// which unsafe.Sizeof cannot see behind the map pointer.
+const mapHeaderSize = 48
+
+// mapFootprint estimates the bytes held by a map of n entries whose key and
+// value together take pairSize bytes: the header plus a power-of-two slot
+// array of at least 8 slots, kept under mapLoadFactor full, with one control
+// (tophash) byte per slot.
+func mapFootprint(n, pairSize int) int {
+	slots := 8
+	for float64(slots)*mapLoadFactor < float64(n) {
+		slots *= 2
+	}
+	return mapHeaderSize + slots*(pairSize+1)
+}
+
+// theoreticalFootprint returns the bytes the data returned by an allocate
+// function needs at minimum: the outer slice's backing array plus, for each
+// element, its payload sized with unsafe.Sizeof. Slices count their length,
+// not spare capacity, and maps their actual entries, since random keys may
+// collide. Anything above this in the measured usage is allocator overhead.
+func theoreticalFootprint(data interface{}) int {
+	intSize := int(unsafe.Sizeof(int(0)))
+	total := 0
+	switch data := data.(type) {
+	case [][]int:
+		total = len(data) * int(unsafe.Sizeof([]int(nil)))
+		for _, array := range data {
+			total += len(array) * intSize
+		}
+	case []map[int]int:
+		total = len(data) * int(unsafe.Sizeof(map[int]int(nil)))
+		for _, hashMap := range data {
+			total += mapFootprint(len(hashMap), 2*intSize)
+		}
+	case []*ListNode:
+		total = len(data) * int(unsafe.Sizeof((*ListNode)(nil)))
+		for _, head := range data {
+			for node := head; node != nil; node = node.Next {
+				total += int(unsafe.Sizeof(*node))
+			}
+		}
+	case []string:
+		total = len(data) * int(unsafe.Sizeof(""))
+		for _, str := range data {
+			total += len(str)
+		}
+	case [][]Record:
+		total = len(data) * int(unsafe.Sizeof([]Record(nil)))
+		for _, batch := range data {
+			total += len(batch) * int(unsafe.Sizeof(Record{}))
+		}
+	}
+	return total
+}
+
 func runMemoryAllocationBenchmark(params Parameters) Results {
+	seed := cli.Seed(params.Seed)
+	rng = rand.New(rand.NewSource(seed))
+	
 	startTime := float64(time.Now().UnixNano()) / 1e9
 	testCases := make([]TestCase, 0)
 	summary := Summary{
@@ -150,6 +361,7 @@ func runMemoryAllocationBenchmark(params Parameters) Results {
 	allAllocationTimes := make([]float64, 0)
 	allDeallocationTimes := make([]float64, 0)
 	allMemoryEfficiencies := make([]float64, 0)
+	allAllocationRates := make([]float64, 0)
 	
 	for _, size := range params.AllocationSizes {
 		for _, count := range params.AllocationCounts {
@@ -172,6 +384,7 @@ func runMemoryAllocationBenchmark(params Parameters) Results {
 					allocationTimes := make([]float64, 0)
 					deallocationTimes := make([]float64, 0)
 					memoryEfficiencies := make([]float64, 0)
+					allocationRates := make([]float64, 0)
 					
 					for i := 0; i < params.Iterations; i++ {
 						fmt.Fprintf(os.Stderr, "  Iteration %d/%d...\n", i+1, params.Iterations)
@@ -179,6 +392,12 @@ func runMemoryAllocationBenchmark(params Parameters) Results {
 						initialMemory := getMemoryUsage()
 						summary.TotalTests++
 						
+						// GC activity is measured across the whole iteration:
+						// collections triggered while allocating, the one forced
+						// for the peak reading, and the deallocation itself.
+						var gcBefore runtime.MemStats
+						runtime.ReadMemStats(&gcBefore)
+						
 						iterationResult := IterationResult{
 							Iteration:    i + 1,
 							InitialMemory: initialMemory,
@@ -199,60 +418,45 @@ func runMemoryAllocationBenchmark(params Parameters) Results {
 						}
 						
 						success := false
+						var allocate func() interface{}
 						switch structure {
 						case "array":
-							start := time.Now()
-							_ = allocateArrays(size, count)
-							allocationTime := float64(time.Since(start).Nanoseconds()) / 1e6
-							
-							peakMemory := getMemoryUsage()
-							memoryUsed := peakMemory - initialMemory
-							theoreticalSize := size * count * 8 // 8 bytes per int
-							memoryEfficiency := 100.0
-							if memoryUsed > 0 {
-								memoryEfficiency = float64(theoreticalSize) / float64(memoryUsed) * 100.0
-							}
-							
-							allocationTimes = append(allocationTimes, allocationTime)
-							allAllocationTimes = append(allAllocationTimes, allocationTime)
-							memoryEfficiencies = append(memoryEfficiencies, memoryEfficiency)
-							allMemoryEfficiencies = append(allMemoryEfficiencies, memoryEfficiency)
-							
-							iterationResult.Allocation = AllocationResult{
-								Success:          true,
-								TimeMs:           allocationTime,
-								MemoryUsed:       memoryUsed,
-								PeakMemory:       peakMemory,
-								MemoryEfficiency: memoryEfficiency,
-								ItemsAllocated:   count,
-							}
-							
-							// Deallocation
-							start = time.Now()
-							runtime.GC()    // Force garbage collection
-							deallocationTime := float64(time.Since(start).Nanoseconds()) / 1e6
-							finalMemory := getMemoryUsage()
-							
-							deallocationTimes = append(deallocationTimes, deallocationTime)
-							allDeallocationTimes = append(allDeallocationTimes, deallocationTime)
-							
-							iterationResult.Deallocation = DeallocationResult{
-								Success:     true,
-								TimeMs:      deallocationTime,
-								FinalMemory: finalMemory,
-								MemoryFreed: peakMemory - finalMemory,
-							}
-							
-							success = true
-							
+							allocate = func() interface{} { return allocateArrays(size, count) }
 						case "hash_map":
+							allocate = func() interface{} { return allocateHashMaps(size, count) }
+						case "linked_list":
+							allocate = func() interface{} { return allocateLinkedLists(size, count) }
+						case "slice_growth":
+							allocate = func() interface{} { return allocateGrowingSlices(size, count) }
+						case "string_builder":
+							allocate = func() interface{} { return buildStrings(size, count) }
+						case "string_concat":
+							allocate = func() interface{} { return concatStrings(size, count) }
+						case "struct":
+							allocate = func() interface{} { return allocateRecords(size, count) }
+						default:
+							errMsg := fmt.Sprintf("Unknown data structure: %s", structure)
+							iterationResult.Allocation.Error = &errMsg
+						}
+						
+						if allocate != nil {
+							var mallocsBefore, mallocsAfter runtime.MemStats
+							runtime.ReadMemStats(&mallocsBefore)
 							start := time.Now()
-							_ = allocateHashMaps(size, count)
-							allocationTime := float64(time.Since(start).Nanoseconds()) / 1e6
+							data := allocate()
+							elapsed := time.Since(start)
+							runtime.ReadMemStats(&mallocsAfter)
+							allocationTime := float64(elapsed.Nanoseconds()) / 1e6
+							totalMallocs := mallocsAfter.Mallocs - mallocsBefore.Mallocs
+							allocationsPerSecond := 0.0
+							if elapsed > 0 {
+								allocationsPerSecond = float64(totalMallocs) / elapsed.Seconds()
+							}
 							
 							peakMemory := getMemoryUsage()
+							theoreticalSize := theoreticalFootprint(data)
+							runtime.KeepAlive(data)
 							memoryUsed := peakMemory - initialMemory
-							theoreticalSize := size * count * 16 // Key-value pairs
 							memoryEfficiency := 100.0
 							if memoryUsed > 0 {
 								memoryEfficiency = float64(theoreticalSize) / float64(memoryUsed) * 100.0
@@ -262,82 +466,51 @@ func runMemoryAllocationBenchmark(params Parameters) Results {
 							allAllocationTimes = append(allAllocationTimes, allocationTime)
 							memoryEfficiencies = append(memoryEfficiencies, memoryEfficiency)
 							allMemoryEfficiencies = append(allMemoryEfficiencies, memoryEfficiency)
+							allocationRates = append(allocationRates, allocationsPerSecond)
+							allAllocationRates = append(allAllocationRates, allocationsPerSecond)
+							summary.TotalMallocs += totalMallocs
 							
 							iterationResult.Allocation = AllocationResult{
-								Success:          true,
-								TimeMs:           allocationTime,
-								MemoryUsed:       memoryUsed,
-								PeakMemory:       peakMemory,
-								MemoryEfficiency: memoryEfficiency,
-								ItemsAllocated:   count,
+								Success:              true,
+								TimeMs:               allocationTime,
+								MemoryUsed:           memoryUsed,
+								PeakMemory:           peakMemory,
+								MemoryEfficiency:     memoryEfficiency,
+								TheoreticalSize:      theoreticalSize,
+								ItemsAllocated:       count,
+								TotalMallocs:         totalMallocs,
+								AllocationsPerSecond: allocationsPerSecond,
 							}
 							
 							// Deallocation
 							start = time.Now()
 							runtime.GC()    // Force garbage collection
 							deallocationTime := float64(time.Since(start).Nanoseconds()) / 1e6
+							var gcAfter runtime.MemStats
+							runtime.ReadMemStats(&gcAfter)
+							gcPauseMs, maxGCPauseMs, numGC := gcPauses(&gcBefore, &gcAfter)
 							finalMemory := getMemoryUsage()
 							
 							deallocationTimes = append(deallocationTimes, deallocationTime)
 							allDeallocationTimes = append(allDeallocationTimes, deallocationTime)
 							
 							iterationResult.Deallocation = DeallocationResult{
-								Success:     true,
-								TimeMs:      deallocationTime,
-								FinalMemory: finalMemory,
-								MemoryFreed: peakMemory - finalMemory,
-							}
-							
-							success = true
-							
-						case "linked_list":
-							start := time.Now()
-							_ = allocateLinkedLists(size, count)
-							allocationTime := float64(time.Since(start).Nanoseconds()) / 1e6
-							
-							peakMemory := getMemoryUsage()
-							memoryUsed := peakMemory - initialMemory
-							theoreticalSize := size * count * 24 // Node overhead
-							memoryEfficiency := 100.0
-							if memoryUsed > 0 {
-								memoryEfficiency = float64(theoreticalSize) / float64(memoryUsed) * 100.0
-							}
-							
-							allocationTimes = append(allocationTimes, allocationTime)
-							allAllocationTimes = append(allAllocationTimes, allocationTime)
-							memoryEfficiencies = append(memoryEfficiencies, memoryEfficiency)
-							allMemoryEfficiencies = append(allMemoryEfficiencies, memoryEfficiency)
-							
-							iterationResult.Allocation = AllocationResult{
-								Success:          true,
-								TimeMs:           allocationTime,
-								MemoryUsed:       memoryUsed,
-								PeakMemory:       peakMemory,
-								MemoryEfficiency: memoryEfficiency,
-								ItemsAllocated:   count,
+								Success:      true,
+								TimeMs:       deallocationTime,
+								FinalMemory:  finalMemory,
+								MemoryFreed:  peakMemory - finalMemory,
+								GCPauseMs:    gcPauseMs,
+								MaxGCPauseMs: maxGCPauseMs,
+								NumGC:        numGC,
 							}
 							
-							// Deallocation
-							start = time.Now()
-							runtime.GC()    // Force garbage collection
-							deallocationTime := float64(time.Since(start).Nanoseconds()) / 1e6
-							finalMemory := getMemoryUsage()
-							
-							deallocationTimes = append(deallocationTimes, deallocationTime)
-							allDeallocationTimes = append(allDeallocationTimes, deallocationTime)
-							
-							iterationResult.Deallocation = DeallocationResult{
-								Success:     true,
-								TimeMs:      deallocationTime,
-								FinalMemory: finalMemory,
-								MemoryFreed: peakMemory - finalMemory,
+							summary.TotalGCPauseMs += gcPauseMs
+							if maxGCPauseMs > summary.MaxGCPauseMs {
+								summary.MaxGCPauseMs = maxGCPauseMs
 							}
 							
 							success = true
 							
-						default:
-							errMsg := fmt.Sprintf("Unknown data structure: %s", structure)
-							iterationResult.Allocation.Error = &errMsg
 						}
 						
 						if success {
@@ -350,29 +523,10 @@ func runMemoryAllocationBenchmark(params Parameters) Results {
 					}
 					
 					// Calculate averages
-					if len(allocationTimes) > 0 {
-						sum := 0.0
-						for _, t := range allocationTimes {
-							sum += t
-						}
-						testCase.AvgAllocationTime = sum / float64(len(allocationTimes))
-					}
-					
-					if len(deallocationTimes) > 0 {
-						sum := 0.0
-						for _, t := range deallocationTimes {
-							sum += t
-						}
-						testCase.AvgDeallocationTime = sum / float64(len(deallocationTimes))
-					}
-					
-					if len(memoryEfficiencies) > 0 {
-						sum := 0.0
-						for _, e := range memoryEfficiencies {
-							sum += e
-						}
-						testCase.AvgMemoryEfficiency = sum / float64(len(memoryEfficiencies))
-					}
+					testCase.AvgAllocationTime = stats.Mean(allocationTimes)
+					testCase.AvgDeallocationTime = stats.Mean(deallocationTimes)
+					testCase.AvgMemoryEfficiency = stats.Mean(memoryEfficiencies)
+					testCase.AvgAllocationsPerSecond = stats.Mean(allocationRates)
 					
 					testCases = append(testCases, testCase)
 				}
@@ -381,34 +535,21 @@ func runMemoryAllocationBenchmark(params Parameters) Results {
 	}
 	
 	// Calculate overall summary
-	if len(allAllocationTimes) > 0 {
-		sum := 0.0
-		for _, t := range allAllocationTimes {
-			sum += t
-		}
-		summary.AvgAllocationTime = sum / float64(len(allAllocationTimes))
-	}
-	
-	if len(allDeallocationTimes) > 0 {
-		sum := 0.0
-		for _, t := range allDeallocationTimes {
-			sum += t
-		}
-		summary.AvgDeallocationTime = sum / float64(len(allDeallocationTimes))
-	}
-	
-	if len(allMemoryEfficiencies) > 0 {
-		sum := 0.0
-		for _, e := range allMemoryEfficiencies {
-			sum += e
-		}
-		summary.AvgMemoryEfficiency = sum / float64(len(allMemoryEfficiencies))
+	summary.AvgAllocationTime = stats.Mean(allAllocationTimes)
+	summary.AvgDeallocationTime = stats.Mean(allDeallocationTimes)
+	summary.AvgMemoryEfficiency = stats.Mean(allMemoryEfficiencies)
+	summary.AvgAllocationsPerSecond = stats.Mean(allAllocationRates)
+	if summary.TotalMallocs > 0 {
+		summary.GCPauseMsPerMillionMallocs = summary.TotalGCPauseMs / (float64(summary.TotalMallocs) / 1e6)
 	}
 	
 	endTime := float64(time.Now().UnixNano()) / 1e9
 	
 	return Results{
+		SchemaVersion:       cli.SchemaVersion,
+		Environment:         cli.CurrentEnvironment(),
 		StartTime:           startTime,
+		Seed:                seed,
 		TestCases:           testCases,
 		Summary:             summary,
 		EndTime:             endTime,
@@ -417,36 +558,24 @@ func runMemoryAllocationBenchmark(params Parameters) Results {
 }
 
 func main() {
-	if len(os.Args) < 2 {
-		fmt.Fprintln(os.Stderr, "Usage: go run memory_allocation.go <config_file>")
-		os.Exit(1)
-	}
-	
-	configFile := os.Args[1]
-	
-	data, err := ioutil.ReadFile(configFile)
+	opts, err := cli.Parse(filepath.Base(os.Args[0]), os.Args[1:])
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: Config file '%s' not found\n", configFile)
-		os.Exit(1)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
 	}
-	
+
 	var config Config
-	err = json.Unmarshal(data, &config)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: Invalid JSON in config file: %v\n", err)
+	if err := opts.LoadConfig(&config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
-	
-	// Seed random number generator
-	rand.Seed(time.Now().UnixNano())
-	
+
 	results := runMemoryAllocationBenchmark(config.Parameters)
-	
-	output, err := json.MarshalIndent(results, "", "  ")
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: Failed to serialize results: %v\n", err)
+	results.Name = config.Name
+	results.Tags = config.Parameters.Tags
+
+	if err := opts.WriteResults(results); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
-	
-	fmt.Println(string(output))
-}
\ No newline at end of file
+}